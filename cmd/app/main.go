@@ -9,7 +9,10 @@ import (
 	"li-acc/internal/handler"
 	"li-acc/internal/model"
 	"li-acc/internal/service"
+	"li-acc/pkg/converter"
 	"li-acc/pkg/logger"
+	"li-acc/pkg/pdf"
+	"li-acc/pkg/qr"
 	"log"
 	"net/http"
 	"os"
@@ -70,10 +73,49 @@ func main() {
 		Password: cfg.SMTP.Password,
 		UseTLS:   true,
 	}
-	serviceManager, err := service.NewManager(dsn, cfg.ConvertAPI.PublicKey, smtp)
+	templateSource, err := service.ParseTemplateSource(cfg.Receipt.TemplateSource)
+	if err != nil {
+		logger.Fatal("invalid receipt template config", zap.Error(err))
+	}
+	var docConverter converter.DocumentConverter
+	if templateSource == service.TemplateSourceConverter {
+		docConverter, err = newDocumentConverter(cfg)
+		if err != nil {
+			logger.Fatal("invalid converter config", zap.Error(err))
+		}
+	}
+
+	serviceManager, err := service.NewManager(dsn, docConverter, smtp)
 	if err != nil {
 		logger.Fatal("failed to create service manager", zap.Error(err))
 	}
+	serviceManager.SetTemplateSource(templateSource)
+	if cfg.Receipt.LayoutPath != "" {
+		layout, err := pdf.LoadLayout(cfg.Receipt.LayoutPath)
+		if err != nil {
+			logger.Fatal("invalid receipt layout", zap.Error(err))
+		}
+		serviceManager.SetReceiptLayout(layout)
+	}
+	serviceManager.SetReceiptWorkers(cfg.Receipt.Workers)
+
+	serviceManager.SetVerifyQrCodes(cfg.QR.Verify)
+	serviceManager.SetSaveQrFiles(cfg.QR.SaveFiles)
+
+	qrLevel, err := qr.ParseErrorCorrection(cfg.QR.ErrorCorrection)
+	if err != nil {
+		logger.Fatal("invalid QR code config", zap.Error(err))
+	}
+	qrOptions := qr.RenderOptions{
+		Format:          qr.FormatPNG,
+		ErrorCorrection: qrLevel,
+		ModuleSize:      cfg.QR.ModuleSize,
+		QuietZone:       cfg.QR.QuietZone,
+	}
+	if err := qrOptions.Validate(); err != nil {
+		logger.Fatal("invalid QR code config", zap.Error(err))
+	}
+	serviceManager.SetQrRenderOptions(qrOptions)
 
 	defer serviceManager.Close()
 
@@ -153,3 +195,27 @@ func main() {
 
 	logger.Info("Servers stopped gracefully")
 }
+
+// newDocumentConverter creates the converter of the backend set in config
+func newDocumentConverter(cfg *config.Config) (converter.DocumentConverter, error) {
+	backend, err := converter.ParseBackend(cfg.Converter.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	switch backend {
+	case converter.BackendSoffice:
+		return converter.NewOfficeConverter(cfg.Converter.SofficePath, cfg.Converter.SofficeTimeout, cfg.Converter.SofficeProcesses), nil
+	default:
+		if cfg.ConvertAPI.PublicKey == "" {
+			return nil, errors.New("converter API key is not set")
+		}
+		return converter.NewConverterWithConfig(converter.ApiUrl, cfg.ConvertAPI.PublicKey, cfg.ConvertAPI.PrivateKey, converter.ClientConfig{
+			Timeout:      cfg.ConvertAPI.Timeout,
+			MaxRetries:   cfg.ConvertAPI.MaxRetries,
+			RetryBackoff: cfg.ConvertAPI.RetryBackoff,
+			PollInterval: cfg.ConvertAPI.PollInterval,
+			PollTimeout:  cfg.ConvertAPI.PollTimeout,
+		}), nil
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/gabrielsoaressantos/env/v8"
 	"github.com/joho/godotenv"
@@ -31,9 +32,30 @@ type Config struct {
 		DbName   string `env:"POSTGRES_DB,notEmpty"`
 	}
 
+	// Converter converts the Excel receipt pattern to PDF, if the template source is `converter`
+	Converter struct {
+		Backend          string        `env:"CONVERTER_BACKEND" envDefault:"compdf"` // compdf or soffice
+		SofficePath      string        `env:"SOFFICE_PATH" envDefault:"soffice"`     // LibreOffice binary
+		SofficeTimeout   time.Duration `env:"SOFFICE_TIMEOUT" envDefault:"1m"`       // limit of one conversion
+		SofficeProcesses int           `env:"SOFFICE_MAX_PROCESSES" envDefault:"2"`  // soffice processes at once
+	}
+
+	// ConvertAPI keys are required only by the compdf converter backend.
+	// With the secret key the asynchronous task workflow is used instead of the one-shot conversion
 	ConvertAPI struct {
-		PublicKey  string `env:"CONVERT_API_PUBLIC_KEY,notEmpty"`
-		PrivateKey string `env:"CONVERT_API_SECRET_KEY,notEmpty"`
+		PublicKey    string        `env:"CONVERT_API_PUBLIC_KEY"`
+		PrivateKey   string        `env:"CONVERT_API_SECRET_KEY"`
+		Timeout      time.Duration `env:"CONVERT_API_TIMEOUT" envDefault:"30s"`         // limit of one API request
+		MaxRetries   int           `env:"CONVERT_API_MAX_RETRIES" envDefault:"3"`       // retries of idempotent requests
+		RetryBackoff time.Duration `env:"CONVERT_API_RETRY_BACKOFF" envDefault:"500ms"` // first delay, doubled every retry
+		PollInterval time.Duration `env:"CONVERT_API_POLL_INTERVAL" envDefault:"1s"`    // task status requests interval
+		PollTimeout  time.Duration `env:"CONVERT_API_POLL_TIMEOUT" envDefault:"2m"`     // limit of waiting for the task
+	}
+
+	Receipt struct {
+		TemplateSource string `env:"RECEIPT_TEMPLATE_SOURCE" envDefault:"native"` // native or converter
+		LayoutPath     string `env:"RECEIPT_LAYOUT_PATH"`                         // JSON layout of the frames, built-in if empty
+		Workers        int    `env:"RECEIPT_WORKERS" envDefault:"0"`              // receipts generated at once, number of CPUs if 0
 	}
 
 	SMTP struct {
@@ -42,6 +64,14 @@ type Config struct {
 		Email    string `env:"SMTP_EMAIL,notEmpty"`
 		Password string `env:"SMTP_PASSWORD,notEmpty"`
 	}
+
+	QR struct {
+		Verify          bool   `env:"QR_VERIFY" envDefault:"false"`       // scan every generated QR code before sending the receipt
+		ErrorCorrection string `env:"QR_ERROR_CORRECTION" envDefault:"L"` // L, M, Q or H
+		ModuleSize      int    `env:"QR_MODULE_SIZE" envDefault:"8"`      // pixels per module
+		QuietZone       int    `env:"QR_QUIET_ZONE" envDefault:"0"`       // white margin in modules
+		SaveFiles       bool   `env:"QR_SAVE_FILES" envDefault:"false"`   // write QR images to tmp/qr for debugging
+	}
 }
 
 const ProdFilePath = "./.env"
@@ -25,27 +25,37 @@ func NewAPIClient(baseURL string) *APIClient {
 
 // Загрузка файла с плательщиками
 func (c *APIClient) UploadPayers(filename string, fileData io.Reader) (*PayersFileUploadResponse, error) {
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	part, err := writer.CreateFormFile("file", filename)
-	if err != nil {
+	var result PayersFileUploadResponse
+	if err := c.postFile(ApiEndpointUploadPayers, filename, fileData, &result); err != nil {
 		return nil, err
 	}
+	return &result, nil
+}
 
-	if _, err := io.Copy(part, fileData); err != nil {
+// Предпросмотр файла с плательщиками без отправки квитанций
+func (c *APIClient) PreviewPayers(filename string, fileData io.Reader) (*PayersFilePreviewResponse, error) {
+	var result PayersFilePreviewResponse
+	if err := c.postFile(ApiEndpointPreviewPayers, filename, fileData, &result); err != nil {
 		return nil, err
 	}
+	return &result, nil
+}
 
-	if err := writer.Close(); err != nil {
+// Загрузка файла с почтами
+func (c *APIClient) UploadEmails(filename string, fileData io.Reader) (*EmailsFileUploadResponseSuccess, error) {
+	var result EmailsFileUploadResponseSuccess
+	if err := c.postFile(ApiEndpointUploadEmails, filename, fileData, &result); err != nil {
 		return nil, err
 	}
+	return &result, nil
+}
 
-	req, err := http.NewRequest("POST", c.baseURL+ApiEndpointUploadPayers, body)
+// Сброс кэша шаблонов квитанций
+func (c *APIClient) InvalidateTemplateCache() (*TemplateCacheInvalidateResponse, error) {
+	req, err := http.NewRequest(http.MethodDelete, c.baseURL+ApiEndpointTemplateCache, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -54,7 +64,6 @@ func (c *APIClient) UploadPayers(filename string, fileData io.Reader) (*PayersFi
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		// Читаем error response
 		var errResp map[string]string
 		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
 			return nil, fmt.Errorf("%d", resp.StatusCode)
@@ -62,58 +71,54 @@ func (c *APIClient) UploadPayers(filename string, fileData io.Reader) (*PayersFi
 		return nil, fmt.Errorf("%s", errResp["error"])
 	}
 
-	var result PayersFileUploadResponse
+	var result TemplateCacheInvalidateResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, err
 	}
-
 	return &result, nil
 }
 
-// Загрузка файла с почтами
-func (c *APIClient) UploadEmails(filename string, fileData io.Reader) (*EmailsFileUploadResponseSuccess, error) {
+// postFile отправляет файл в multipart форме на endpoint и декодирует JSON ответ в result.
+// Если статус ответа не 200, возвращает текст ошибки из ответа.
+func (c *APIClient) postFile(endpoint, filename string, fileData io.Reader, result any) error {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
 	part, err := writer.CreateFormFile("file", filename)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if _, err := io.Copy(part, fileData); err != nil {
-		return nil, err
+		return err
 	}
 
 	if err := writer.Close(); err != nil {
-		return nil, err
+		return err
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL+ApiEndpointUploadEmails, body)
+	req, err := http.NewRequest("POST", c.baseURL+endpoint, body)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		// Читаем error response
 		var errResp map[string]string
 		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
-			return nil, fmt.Errorf("%d", resp.StatusCode)
+			return fmt.Errorf("%d", resp.StatusCode)
 		}
-		return nil, fmt.Errorf("%s", errResp["error"])
+		return fmt.Errorf("%s", errResp["error"])
 	}
 
-	var result EmailsFileUploadResponseSuccess
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	return &result, nil
+	return json.NewDecoder(resp.Body).Decode(result)
 }
 
 // Получение истории
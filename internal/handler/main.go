@@ -2,9 +2,13 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"li-acc/internal/metrics"
+	"li-acc/internal/model"
 	"li-acc/internal/service"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,7 +25,7 @@ func NewMainHandler(s service.ManagerIface) *MainHandler {
 // UploadPayersFile godoc
 //
 // @Summary      Upload and process an Excel file containing payer emails
-// @Description  Accepts a multipart/form-data POST request with an Excel file (.xls, .xlsx, .xlsm).
+// @Description  Accepts a multipart/form-data POST request with a table file (.xls, .xlsx, .xlsm, .ods, .csv).
 //
 //	Parses payers, generates receipts, and sends emails.
 //	Supports partial success: includes counts of sent emails and lists of failed emails or missing payers.
@@ -31,7 +35,7 @@ func NewMainHandler(s service.ManagerIface) *MainHandler {
 // @Accept       multipart/form-data
 // @Produce      json
 //
-// @Param        file  formData  file  true  "Excel file to upload. Allowed extensions: .xls, .xlsx, .xlsm"
+// @Param        file  formData  file  true  "Table file to upload. Allowed extensions: .xls, .xlsx, .xlsm, .ods, .csv"
 //
 // @Success      200  {object}  PayersFileUploadResponse  "File processed successfully with optional partial failure details"
 // @Failure      400  {object}  map[string]string        "Bad request errors (file missing, invalid file type, too large)"
@@ -46,7 +50,7 @@ func (h *MainHandler) UploadPayersFile(c *gin.Context) {
 
 	// Call service ProcessPayersFile with context, filename, and file data
 	start := time.Now()
-	_, sentCount, err := h.service.ProcessPayersFile(c.Request.Context(), filename, fileData)
+	batch, err := h.service.ProcessPayersFile(c.Request.Context(), filename, fileData)
 
 	// update file processing latency metric
 	duration := time.Since(start).Seconds()
@@ -57,8 +61,18 @@ func (h *MainHandler) UploadPayersFile(c *gin.Context) {
 	}
 
 	response := PayersFileUploadResponse{
-		Message:    "file processed successfully",
-		SentAmount: sentCount,
+		Message: "file processed successfully",
+	}
+	// the batch is returned with partial errors too
+	if batch != nil {
+		response.SentAmount = batch.SentCount
+		response.Batch = batch.ID
+		for _, scope := range batch.PrintScopes {
+			if response.PrintFiles == nil {
+				response.PrintFiles = make(map[model.PrintScope]string)
+			}
+			response.PrintFiles[scope] = receiptsPrintURL(batch.ID, scope)
+		}
 	}
 
 	if err != nil {
@@ -68,7 +82,7 @@ func (h *MainHandler) UploadPayersFile(c *gin.Context) {
 		if errors.As(err, &compositeErr) {
 			response.PartialSuccess = true
 
-			// For partial failures, error_stage is "send_mails" or "email_mapping"
+			// For partial failures, error_stage is "send_mails", "email_mapping", "qr_verification" or "receipt_generation"
 			for _, e := range compositeErr.Errors {
 				switch typedErr := e.(type) {
 				case *service.EmailSendingError:
@@ -85,6 +99,20 @@ func (h *MainHandler) UploadPayersFile(c *gin.Context) {
 						missed = append(missed, email)
 					}
 					response.MissingPayers = missed
+				case *service.QrVerificationError:
+					errorStage = "qr_verification"
+					var unscannable []string
+					for payer := range typedErr.MapPayerCause {
+						unscannable = append(unscannable, payer)
+					}
+					response.UnscannablePayers = unscannable
+				case *service.ReceiptGenerationError:
+					errorStage = "receipt_generation"
+					var failed []string
+					for payer := range typedErr.MapPayerCause {
+						failed = append(failed, payer)
+					}
+					response.FailedPayers = failed
 				}
 			}
 
@@ -100,3 +128,111 @@ func (h *MainHandler) UploadPayersFile(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// PreviewPayersFile godoc
+//
+// @Summary      Preview payers file without sending receipts
+// @Description  Accepts a multipart/form-data POST request with a table file (.xls, .xlsx, .xlsm, .ods, .csv).
+//
+//	Dry run of /upload-payers: parses payers and organization settings from the file and returns them
+//	together with QR payload and matched email of each payer, and the list of payers without email.
+//	Nothing is generated, sent or saved in history.
+//
+// @Tags         payers
+// @Accept       multipart/form-data
+// @Produce      json
+//
+// @Param        file  formData  file  true  "Table file to upload. Allowed extensions: .xls, .xlsx, .xlsm, .ods, .csv"
+//
+// @Success      200  {object}  PayersFilePreviewResponse  "File parsed successfully"
+// @Failure      400  {object}  map[string]string          "Bad request errors (file missing, invalid file type, too large, invalid data)"
+// @Failure      500  {object}  map[string]string          "Internal server errors"
+//
+// @Router       /preview-payers [post]
+func (h *MainHandler) PreviewPayersFile(c *gin.Context) {
+	filename, fileData := getExcelFileFromMultipart(c)
+	if filename == "" || fileData == nil {
+		return // error response already sent inside the function
+	}
+
+	preview, err := h.service.PreviewPayersFile(c.Request.Context(), filename, fileData)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, PayersFilePreviewResponse{
+		Message:       "file parsed successfully",
+		PayersPreview: *preview,
+	})
+}
+
+// DownloadReceiptsPrintFile godoc
+//
+// @Summary      Download receipts of the batch merged for print
+// @Description  Returns the PDF file with the receipts of the batch made by /upload-payers, two receipts per A4 page
+//
+//	with cut marks between them. The scope "unmapped" selects only the receipts of payers with no email.
+//	URLs of the print files of the batch are returned in "print_files" of /upload-payers response.
+//
+// @Tags         payers
+// @Produce      application/pdf
+//
+// @Param        batch  path   string  true   "Batch id from /upload-payers response"
+// @Param        scope  query  string  false  "Receipts to print: all (default) or unmapped"
+//
+// @Success      200  {file}    file               "Print file"
+// @Failure      400  {object}  map[string]string  "Batch or its print file not found"
+// @Failure      500  {object}  map[string]string  "Internal server errors"
+//
+// @Router       /receipts/{batch}/print [get]
+func (h *MainHandler) DownloadReceiptsPrintFile(c *gin.Context) {
+	batch := c.Param("batch")
+	scope, err := model.ParsePrintScope(c.Query("scope"))
+	if err != nil {
+		// unknown scope has no print file
+		c.Error(&service.PrintFileNotFoundError{Batch: batch, Scope: model.PrintScope(c.Query("scope"))})
+		return
+	}
+
+	path, err := h.service.ReceiptsPrintFile(batch, scope)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.FileAttachment(path, fmt.Sprintf("receipts_%s_%s.pdf", batch, scope))
+}
+
+// receiptsPrintURL returns the URL of DownloadReceiptsPrintFile for the print file of the batch
+func receiptsPrintURL(batch string, scope model.PrintScope) string {
+	endpoint := strings.Replace(ApiEndpointPrintReceipts, ":batch", url.PathEscape(batch), 1)
+	return ApiRoutesGroup + endpoint + "?scope=" + url.QueryEscape(string(scope))
+}
+
+// InvalidateTemplateCache godoc
+//
+// @Summary      Invalidate cached receipt templates
+// @Description  Removes receipt templates cached by organization requisites, so the next payers file
+//
+//	makes its template again from the blank pattern.
+//
+// @Tags         settings
+// @Produce      json
+//
+// @Success      200  {object}  TemplateCacheInvalidateResponse  "Cache invalidated"
+// @Failure      500  {object}  map[string]string                "Internal server errors"
+//
+// @Router       /settings/template-cache [delete]
+func (h *MainHandler) InvalidateTemplateCache(c *gin.Context) {
+	removed, err := h.service.InvalidateTemplateCache()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, TemplateCacheInvalidateResponse{
+		Message: "template cache invalidated",
+		Removed: removed,
+	})
+}
@@ -1,9 +1,21 @@
 package handler
 
+import "li-acc/internal/model"
+
 type PayersFileUploadResponse struct {
-	Message        string   `json:"message"`                  // summary message for user
-	SentAmount     int      `json:"sent_amount,omitempty"`    // number of sent emails
-	FailedEmails   []string `json:"failed_emails,omitempty"`  // emails list from EmailSendingError
-	MissingPayers  []string `json:"missing_payers,omitempty"` // payers list from EmailMappingError
-	PartialSuccess bool     `json:"partial_success"`          // indicates partial failure occurred
+	Message           string                      `json:"message"`                      // summary message for user
+	SentAmount        int                         `json:"sent_amount,omitempty"`        // number of sent emails
+	FailedEmails      []string                    `json:"failed_emails,omitempty"`      // emails list from EmailSendingError
+	MissingPayers     []string                    `json:"missing_payers,omitempty"`     // payers list from EmailMappingError
+	UnscannablePayers []string                    `json:"unscannable_payers,omitempty"` // payers list from QrVerificationError
+	FailedPayers      []string                    `json:"failed_payers,omitempty"`      // payers list from ReceiptGenerationError
+	PartialSuccess    bool                        `json:"partial_success"`              // indicates partial failure occurred
+	Batch             string                      `json:"batch,omitempty"`              // id of the generated receipts batch
+	PrintFiles        map[model.PrintScope]string `json:"print_files,omitempty"`        // print scope -> download URL of the print file
+}
+
+// PayersFilePreviewResponse is the result of the payers file dry run
+type PayersFilePreviewResponse struct {
+	Message string `json:"message"` // summary message for user
+	model.PayersPreview
 }
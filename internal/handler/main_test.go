@@ -6,6 +6,8 @@ import (
 	"li-acc/internal/mocks"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -13,14 +15,21 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"li-acc/internal/handler"
+	"li-acc/internal/model"
 	"li-acc/internal/service"
+	pkg "li-acc/pkg/model"
 )
 
 func TestUploadPayersFile_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	svc := new(mocks.Manager)
-	respMap := map[string]string{"a@a.com": "/path/to/a.pdf"}
-	svc.On("ProcessPayersFile", mock.Anything, "test.xlsx", mock.Anything).Return(respMap, 1, nil)
+	batch := &model.ReceiptsBatch{
+		ID:          "2025-09-01_10-00-00",
+		Receipts:    map[string][]string{"a@a.com": {"/path/to/a.pdf"}},
+		SentCount:   1,
+		PrintScopes: []model.PrintScope{model.PrintScopeAll},
+	}
+	svc.On("ProcessPayersFile", mock.Anything, "test.xlsx", mock.Anything).Return(batch, nil)
 
 	h := handler.NewMainHandler(svc)
 
@@ -40,6 +49,10 @@ func TestUploadPayersFile_Success(t *testing.T) {
 	assert.False(t, resp.PartialSuccess)
 	assert.Empty(t, resp.FailedEmails)
 	assert.Empty(t, resp.MissingPayers)
+	assert.Equal(t, "2025-09-01_10-00-00", resp.Batch)
+	assert.Equal(t, map[model.PrintScope]string{
+		model.PrintScopeAll: "/api/receipts/2025-09-01_10-00-00/print?scope=all",
+	}, resp.PrintFiles)
 
 	svc.AssertExpectations(t)
 }
@@ -55,9 +68,13 @@ func TestUploadPayersFile_PartialSuccess_EmailSendingError(t *testing.T) {
 		},
 	}
 
-	respMap := map[string]string{"success@example.com": "/path/to/success.pdf"}
+	batch := &model.ReceiptsBatch{
+		ID:        "2025-09-01_10-00-00",
+		Receipts:  map[string][]string{"success@example.com": {"/path/to/success.pdf"}},
+		SentCount: 1,
+	}
 	svc.On("ProcessPayersFile", mock.Anything, "test.xlsx", mock.Anything).
-		Return(respMap, 1, compositeErr)
+		Return(batch, compositeErr)
 
 	h := handler.NewMainHandler(svc)
 	w := httptest.NewRecorder()
@@ -90,9 +107,13 @@ func TestUploadPayersFile_PartialSuccess_EmailMappingError(t *testing.T) {
 		},
 	}
 
-	respMap := map[string]string{"success@example.com": "/path/to/success.pdf"}
+	batch := &model.ReceiptsBatch{
+		ID:        "2025-09-01_10-00-00",
+		Receipts:  map[string][]string{"success@example.com": {"/path/to/success.pdf"}},
+		SentCount: 1,
+	}
 	svc.On("ProcessPayersFile", mock.Anything, "test.xlsx", mock.Anything).
-		Return(respMap, 1, compositeErr)
+		Return(batch, compositeErr)
 
 	h := handler.NewMainHandler(svc)
 	w := httptest.NewRecorder()
@@ -114,12 +135,89 @@ func TestUploadPayersFile_PartialSuccess_EmailMappingError(t *testing.T) {
 	svc.AssertExpectations(t)
 }
 
+func TestUploadPayersFile_PartialSuccess_QrVerificationError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := new(mocks.Manager)
+
+	compositeErr := &service.CompositeError{
+		Errors: []error{
+			&service.QrVerificationError{MapPayerCause: map[string]string{"payer1": "generated QR code can not be scanned"}},
+		},
+	}
+
+	batch := &model.ReceiptsBatch{
+		ID:        "2025-09-01_10-00-00",
+		Receipts:  map[string][]string{"success@example.com": {"/path/to/success.pdf"}},
+		SentCount: 1,
+	}
+	svc.On("ProcessPayersFile", mock.Anything, "test.xlsx", mock.Anything).
+		Return(batch, compositeErr)
+
+	h := handler.NewMainHandler(svc)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = newMultipartRequest(t)
+
+	h.UploadPayersFile(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp handler.PayersFileUploadResponse
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.True(t, resp.PartialSuccess)
+	assert.Empty(t, resp.MissingPayers)
+	assert.Equal(t, []string{"payer1"}, resp.UnscannablePayers)
+	assert.Equal(t, 1, resp.SentAmount)
+
+	svc.AssertExpectations(t)
+}
+
+func TestUploadPayersFile_PartialSuccess_ReceiptGenerationError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := new(mocks.Manager)
+
+	compositeErr := &service.CompositeError{
+		Errors: []error{
+			&service.ReceiptGenerationError{MapPayerCause: map[string]string{"payer1": "invalid payment payload"}},
+		},
+	}
+
+	batch := &model.ReceiptsBatch{
+		ID:        "2025-09-01_10-00-00",
+		Receipts:  map[string][]string{"success@example.com": {"/path/to/success.pdf"}},
+		SentCount: 1,
+	}
+	svc.On("ProcessPayersFile", mock.Anything, "test.xlsx", mock.Anything).
+		Return(batch, compositeErr)
+
+	h := handler.NewMainHandler(svc)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = newMultipartRequest(t)
+
+	h.UploadPayersFile(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp handler.PayersFileUploadResponse
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.True(t, resp.PartialSuccess)
+	assert.Empty(t, resp.MissingPayers)
+	assert.Empty(t, resp.UnscannablePayers)
+	assert.Equal(t, []string{"payer1"}, resp.FailedPayers)
+	assert.Equal(t, 1, resp.SentAmount)
+
+	svc.AssertExpectations(t)
+}
+
 func TestUploadPayersFile_FullFailure(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	svc := new(mocks.Manager)
 
 	svc.On("ProcessPayersFile", mock.Anything, "test.xlsx", mock.Anything).
-		Return(make(map[string]string), 0, errors.New("unexpected error"))
+		Return(nil, errors.New("unexpected error"))
 
 	h := handler.NewMainHandler(svc)
 	w := httptest.NewRecorder()
@@ -133,3 +231,152 @@ func TestUploadPayersFile_FullFailure(t *testing.T) {
 
 	svc.AssertExpectations(t)
 }
+
+func TestPreviewPayersFile_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := new(mocks.Manager)
+
+	preview := &model.PayersPreview{
+		Organization: pkg.Organization{Name: "Org"},
+		Payers: []model.PayerPreview{
+			{Payer: pkg.Payer{CHILDFIO: "Иванов Иван", Sum: pkg.NewMoney(100, 50)}, QrData: "ST00012|Name=Org", Emails: []string{"ivanov@example.com", "ivanova@example.com"}},
+			{Payer: pkg.Payer{CHILDFIO: "Петров Петр"}, QrData: "ST00012|Name=Org"},
+		},
+		UnmatchedPayers: []string{"Петров Петр"},
+	}
+	svc.On("PreviewPayersFile", mock.Anything, "test.xlsx", mock.Anything).Return(preview, nil)
+
+	h := handler.NewMainHandler(svc)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = newMultipartRequest(t)
+
+	h.PreviewPayersFile(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp handler.PayersFilePreviewResponse
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "file parsed successfully", resp.Message)
+	assert.Equal(t, *preview, resp.PayersPreview)
+
+	svc.AssertNotCalled(t, "ProcessPayersFile", mock.Anything, mock.Anything, mock.Anything)
+	svc.AssertExpectations(t)
+}
+
+func TestPreviewPayersFile_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := new(mocks.Manager)
+
+	svc.On("PreviewPayersFile", mock.Anything, "test.xlsx", mock.Anything).
+		Return(nil, errors.New("parse error"))
+
+	h := handler.NewMainHandler(svc)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = newMultipartRequest(t)
+
+	h.PreviewPayersFile(c)
+
+	// error is attached to context and handled by the ErrorHandler middleware
+	assert.NotEmpty(t, c.Errors)
+
+	svc.AssertExpectations(t)
+}
+
+func TestInvalidateTemplateCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("success", func(t *testing.T) {
+		svc := new(mocks.Manager)
+		svc.On("InvalidateTemplateCache").Return(2, nil)
+
+		h := handler.NewMainHandler(svc)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodDelete, handler.ApiEndpointTemplateCache, nil)
+
+		h.InvalidateTemplateCache(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp handler.TemplateCacheInvalidateResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 2, resp.Removed)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		svc := new(mocks.Manager)
+		svc.On("InvalidateTemplateCache").Return(0, errors.New("permission denied"))
+
+		h := handler.NewMainHandler(svc)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodDelete, handler.ApiEndpointTemplateCache, nil)
+
+		h.InvalidateTemplateCache(c)
+
+		assert.NotEmpty(t, c.Errors)
+		svc.AssertExpectations(t)
+	})
+}
+
+func TestDownloadReceiptsPrintFile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newContext := func(w *httptest.ResponseRecorder, query string) *gin.Context {
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/receipts/2025-09-01_10-00-00/print"+query, nil)
+		c.Params = gin.Params{{Key: "batch", Value: "2025-09-01_10-00-00"}}
+		return c
+	}
+
+	t.Run("success", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "unmapped.pdf")
+		assert.NoError(t, os.WriteFile(path, []byte("%PDF-1.7"), 0o644))
+
+		svc := new(mocks.Manager)
+		svc.On("ReceiptsPrintFile", "2025-09-01_10-00-00", model.PrintScopeUnmapped).Return(path, nil)
+
+		h := handler.NewMainHandler(svc)
+		w := httptest.NewRecorder()
+		c := newContext(w, "?scope=unmapped")
+
+		h.DownloadReceiptsPrintFile(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "%PDF-1.7", w.Body.String())
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "receipts_2025-09-01_10-00-00_unmapped.pdf")
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		svc := new(mocks.Manager)
+		svc.On("ReceiptsPrintFile", "2025-09-01_10-00-00", model.PrintScopeAll).
+			Return("", &service.PrintFileNotFoundError{Batch: "2025-09-01_10-00-00", Scope: model.PrintScopeAll})
+
+		h := handler.NewMainHandler(svc)
+		w := httptest.NewRecorder()
+		c := newContext(w, "")
+
+		h.DownloadReceiptsPrintFile(c)
+
+		assert.NotEmpty(t, c.Errors)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("unknown scope", func(t *testing.T) {
+		svc := new(mocks.Manager)
+
+		h := handler.NewMainHandler(svc)
+		w := httptest.NewRecorder()
+		c := newContext(w, "?scope=paid")
+
+		h.DownloadReceiptsPrintFile(c)
+
+		var notFound *service.PrintFileNotFoundError
+		assert.ErrorAs(t, c.Errors.Last(), &notFound)
+		svc.AssertNotCalled(t, "ReceiptsPrintFile", mock.Anything, mock.Anything)
+	})
+}
@@ -8,10 +8,13 @@ import (
 )
 
 const (
-	ApiRoutesGroup          = "/api"
-	ApiEndpointUploadPayers = "/upload-payers"
-	ApiEndpointUploadEmails = "/settings/upload-emails"
-	ApiEndpointGetHistory   = "/history"
+	ApiRoutesGroup           = "/api"
+	ApiEndpointUploadPayers  = "/upload-payers"
+	ApiEndpointPreviewPayers = "/preview-payers"
+	ApiEndpointUploadEmails  = "/settings/upload-emails"
+	ApiEndpointTemplateCache = "/settings/template-cache"
+	ApiEndpointGetHistory    = "/history"
+	ApiEndpointPrintReceipts = "/receipts/:batch/print"
 )
 
 func SetupRouter(manager service.ManagerIface, uiHandler *UIHandler) *gin.Engine {
@@ -35,9 +38,18 @@ func SetupRouter(manager service.ManagerIface, uiHandler *UIHandler) *gin.Engine
 		// Upload payers Excel file
 		api.POST(ApiEndpointUploadPayers, mainHandler.UploadPayersFile)
 
+		// Preview payers Excel file without sending receipts
+		api.POST(ApiEndpointPreviewPayers, mainHandler.PreviewPayersFile)
+
 		// Upload settings or sender emails file
 		api.POST(ApiEndpointUploadEmails, settingsHandler.UploadEmailsFile)
 
+		// Download receipts of the batch merged for print
+		api.GET(ApiEndpointPrintReceipts, mainHandler.DownloadReceiptsPrintFile)
+
+		// Remove cached receipt templates, so they are made again with the next payers file
+		api.DELETE(ApiEndpointTemplateCache, mainHandler.InvalidateTemplateCache)
+
 		// Get history of uploaded files
 		api.GET(ApiEndpointGetHistory, historyHandler.GetFilesHistory)
 	}
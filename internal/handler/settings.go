@@ -22,7 +22,7 @@ func NewSettingsHandler(s service.SettingsService) *SettingsHandler {
 // UploadEmailsFile godoc
 //
 // @Summary      Upload and process an Excel file containing payer emails
-// @Description  Accepts a multipart/form-data POST request containing an Excel file (.xls, .xlsx, .xlsm).
+// @Description  Accepts a multipart/form-data POST request containing a table file (.xls, .xlsx, .xlsm, .ods, .csv).
 //
 //	Validates file type and size. Parses, validates, and stores payer emails.
 //	Returns success message or relevant error messages in JSON format.
@@ -31,7 +31,7 @@ func NewSettingsHandler(s service.SettingsService) *SettingsHandler {
 // @Accept       multipart/form-data
 // @Produce      json
 //
-// @Param        file formData file true "Excel file to upload. Allowed extensions: .xls, .xlsx, .xlsm"
+// @Param        file formData file true "Table file to upload. Allowed extensions: .xls, .xlsx, .xlsm, .ods, .csv"
 //
 // @Success      200  {object}  EmailsFileUploadResponseSuccess "File processed successfully"
 // @Failure      400  {object}  map[string]string        "Bad request (invalid file, missing, or too large)"
@@ -3,3 +3,8 @@ package handler
 type EmailsFileUploadResponseSuccess struct {
 	Message string `json:"message"`
 }
+
+type TemplateCacheInvalidateResponse struct {
+	Message string `json:"message"`
+	Removed int    `json:"removed"` // number of removed templates
+}
@@ -19,10 +19,15 @@ import (
 
 // helper to create multipart file upload request
 func newMultipartRequest(t *testing.T) *http.Request {
+	return newMultipartRequestWithName(t, "test.xlsx")
+}
+
+// helper to create multipart file upload request with the given file name
+func newMultipartRequestWithName(t *testing.T, filename string) *http.Request {
 	body := new(bytes.Buffer)
 	writer := multipart.NewWriter(body)
 
-	part, err := writer.CreateFormFile("file", "test.xlsx")
+	part, err := writer.CreateFormFile("file", filename)
 	assert.NoError(t, err)
 	_, err = part.Write([]byte("dummy content"))
 	assert.NoError(t, err)
@@ -83,3 +88,40 @@ func TestUploadEmailsFile_Error(t *testing.T) {
 	assert.NotEmpty(t, c.Errors)
 	mockSvc.AssertExpectations(t)
 }
+
+func TestUploadEmailsFile_Extensions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		filename   string
+		wantStatus int
+	}{
+		{"emails.xls", http.StatusOK},
+		{"emails.XLSX", http.StatusOK},
+		{"emails.ods", http.StatusOK},
+		{"emails.csv", http.StatusOK},
+		{"emails.txt", http.StatusBadRequest},
+		{"emails", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			mockSvc := new(mocks.SettingsService)
+			mockSvc.On("ProcessEmailsFile", mock.Anything, tt.filename, mock.Anything).Return(nil).Maybe()
+
+			h := handler.NewSettingsHandler(mockSvc)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = newMultipartRequestWithName(t, tt.filename)
+
+			h.UploadEmailsFile(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusBadRequest {
+				assert.Contains(t, w.Body.String(), ".ods")
+				mockSvc.AssertNotCalled(t, "ProcessEmailsFile", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
 	"html/template"
+	"io"
 	"li-acc/internal/model"
 	"net/http"
 	"path/filepath"
@@ -18,10 +21,27 @@ type MainPageData struct {
 	SuccessMsg     string
 	MissingPayers  []string
 	FailedEmails   []string
+	FailedPayers   []string
 	SentAmount     int
 	PartialSuccess bool
+
+	// links to the receipts of the batch merged for print, empty if there is no such file
+	PrintAllURL      string
+	PrintUnmappedURL string
+
+	// Preview is set after the dry run of the payers file. The file itself is kept in the page
+	// (base64 in PreviewFileData), so it can be sent after confirmation without choosing it again.
+	Preview         *PayersFilePreviewResponse
+	PreviewFilename string
+	PreviewFileData string
 }
 
+// main page form actions, passed in the "action" field
+const (
+	mainActionPreview = "preview" // parse the chosen file and show the preview
+	mainActionConfirm = "confirm" // send receipts for the previewed file
+)
+
 // HistoryPageData represents data for history_page
 type HistoryPageData struct {
 	Files []model.File
@@ -36,11 +56,15 @@ type HistoryPageData struct {
 
 // SettingsPageData represents data for settings_page
 type SettingsPageData struct {
-	Errors           []string
-	ErrorMsg         string
-	SuccessMsgEmails string
+	Errors              []string
+	ErrorMsg            string
+	SuccessMsgEmails    string
+	SuccessMsgTemplates string
 }
 
+// settings page form actions, passed in the "action" field; the emails form has no action
+const settingsActionInvalidateTemplates = "invalidate-templates" // remove cached receipt templates
+
 type UIHandler struct {
 	templates map[string]*template.Template
 	apiClient *APIClient
@@ -74,6 +98,20 @@ func (h *UIHandler) MainPage(c *gin.Context) {
 		return
 	}
 
+	// POST - подтверждение отправки файла после предпросмотра
+	if c.PostForm("action") == mainActionConfirm {
+		fileData, err := base64.StdEncoding.DecodeString(c.PostForm("filedata"))
+		if err != nil || len(fileData) == 0 {
+			data := MainPageData{
+				ErrorMsg: "Не удалось получить файл, выберите его снова",
+			}
+			h.renderTemplate(c.Writer, "main_page", data)
+			return
+		}
+		h.uploadPayers(c, c.PostForm("filename"), bytes.NewReader(fileData))
+		return
+	}
+
 	// POST - обработка загрузки
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -95,8 +133,47 @@ func (h *UIHandler) MainPage(c *gin.Context) {
 	}
 	defer src.Close()
 
+	if c.PostForm("action") == mainActionPreview {
+		h.previewPayers(c, file.Filename, src)
+		return
+	}
+
+	h.uploadPayers(c, file.Filename, src)
+}
+
+// previewPayers показывает разобранных плательщиков без отправки квитанций
+func (h *UIHandler) previewPayers(c *gin.Context, filename string, src io.Reader) {
+	fileData, err := io.ReadAll(src)
+	if err != nil {
+		data := MainPageData{
+			ErrorMsg: "Ошибка чтения файла",
+		}
+		h.renderTemplate(c.Writer, "main_page", data)
+		return
+	}
+
 	// Вызываем API
-	resp, err := h.apiClient.UploadPayers(file.Filename, src)
+	resp, err := h.apiClient.PreviewPayers(filename, bytes.NewReader(fileData))
+	if err != nil {
+		data := MainPageData{
+			ErrorMsg: err.Error(),
+		}
+		h.renderTemplate(c.Writer, "main_page", data)
+		return
+	}
+
+	data := MainPageData{
+		Preview:         resp,
+		PreviewFilename: filename,
+		PreviewFileData: base64.StdEncoding.EncodeToString(fileData),
+	}
+	h.renderTemplate(c.Writer, "main_page", data)
+}
+
+// uploadPayers отправляет квитанции плательщикам из файла
+func (h *UIHandler) uploadPayers(c *gin.Context, filename string, src io.Reader) {
+	// Вызываем API
+	resp, err := h.apiClient.UploadPayers(filename, src)
 	if err != nil {
 		data := MainPageData{
 			ErrorMsg: err.Error(),
@@ -117,6 +194,10 @@ func (h *UIHandler) MainPage(c *gin.Context) {
 			successMsg += fmt.Sprintf(". Не найдены плательщики %d:\n", len(resp.MissingPayers))
 			successMsg += strings.Join(resp.MissingPayers, ", ")
 		}
+		if len(resp.FailedPayers) > 0 {
+			successMsg += fmt.Sprintf(". Не сформированы квитанции %d:\n", len(resp.FailedPayers))
+			successMsg += strings.Join(resp.FailedPayers, ", ")
+		}
 	} else {
 		successMsg = fmt.Sprintf("Файл успешно обработан! Отправлено писем: %d", resp.SentAmount)
 	}
@@ -126,7 +207,11 @@ func (h *UIHandler) MainPage(c *gin.Context) {
 		PartialSuccess: resp.PartialSuccess,
 		MissingPayers:  resp.MissingPayers,
 		FailedEmails:   resp.FailedEmails,
+		FailedPayers:   resp.FailedPayers,
 		SuccessMsg:     successMsg,
+
+		PrintAllURL:      resp.PrintFiles[model.PrintScopeAll],
+		PrintUnmappedURL: resp.PrintFiles[model.PrintScopeUnmapped],
 	}
 	h.renderTemplate(c.Writer, "main_page", data)
 }
@@ -153,6 +238,11 @@ func (h *UIHandler) SettingsPage(c *gin.Context) {
 		return
 	}
 
+	if c.PostForm("action") == settingsActionInvalidateTemplates {
+		h.invalidateTemplateCache(c)
+		return
+	}
+
 	// POST - обработка загрузки
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -189,6 +279,23 @@ func (h *UIHandler) SettingsPage(c *gin.Context) {
 	h.renderTemplate(c.Writer, "settings_page", data)
 }
 
+// invalidateTemplateCache сбрасывает кэш шаблонов квитанций
+func (h *UIHandler) invalidateTemplateCache(c *gin.Context) {
+	resp, err := h.apiClient.InvalidateTemplateCache()
+	if err != nil {
+		data := SettingsPageData{
+			ErrorMsg: fmt.Sprintf("Ошибка сброса кэша: %v", err),
+		}
+		h.renderTemplate(c.Writer, "settings_page", data)
+		return
+	}
+
+	data := SettingsPageData{
+		SuccessMsgTemplates: fmt.Sprintf("Кэш шаблонов квитанций сброшен, удалено шаблонов: %d", resp.Removed),
+	}
+	h.renderTemplate(c.Writer, "settings_page", data)
+}
+
 // Документация
 func (h *UIHandler) DocsPage(c *gin.Context) {
 	h.renderTemplate(c.Writer, "docs_page", nil)
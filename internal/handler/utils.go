@@ -3,11 +3,17 @@ package handler
 import (
 	"io"
 	"net/http"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// allowedTableExtensions are the extensions of the table files accepted for upload.
+// The actual file format is detected by the content during parsing, so the check is only a guard against obvious mistakes.
+var allowedTableExtensions = []string{".xls", ".xlsx", ".xlsm", ".ods", ".csv"}
+
 func getExcelFileFromMultipart(c *gin.Context) (string, []byte) {
 	// Parse multipart form with a reasonable max size (e.g., 10MB)
 	if err := c.Request.ParseMultipartForm(10 << 20); err != nil {
@@ -23,10 +29,9 @@ func getExcelFileFromMultipart(c *gin.Context) (string, []byte) {
 	}
 
 	// Validate file extension
-	if !strings.HasSuffix(fileHeader.Filename, ".xls") &&
-		!strings.HasSuffix(fileHeader.Filename, ".xlsx") &&
-		!strings.HasSuffix(fileHeader.Filename, ".xlsm") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "можно загрузить только файлы Excel (.xls, .xlsx, .xlsm)"})
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	if !slices.Contains(allowedTableExtensions, ext) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "можно загрузить только таблицы Excel (.xls, .xlsx, .xlsm), LibreOffice (.ods) или CSV (.csv)"})
 		return "", nil
 	}
 
@@ -101,6 +101,7 @@ var (
 			Name: "liacc_stage_generate_receipts_total",
 			Help: "Total generate receipts operations",
 		},
+		// `result=("success"|"partial"|"failed")`, partial if the receipts of some payers failed
 		[]string{"result", "error_type"},
 	)
 
@@ -114,6 +115,15 @@ var (
 	)
 )
 
+// TemplateCacheTotal counts lookups of receipt templates in the cache, `result=("hit"|"miss")`
+var TemplateCacheTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "liacc_template_cache_total",
+		Help: "Total lookups of receipt templates in the cache",
+	},
+	[]string{"result"},
+)
+
 // Send mails
 var (
 	SendMailsDuration = promauto.NewHistogramVec(
@@ -5,7 +5,12 @@ import (
 	"fmt"
 	"li-acc/internal/errs"
 	"li-acc/internal/service"
+	"li-acc/pkg/pdf"
+	"li-acc/pkg/qr"
+	"li-acc/pkg/requisites"
 	"li-acc/pkg/xls"
+	"li-acc/pkg/xls/biff"
+	"li-acc/pkg/xls/ods"
 	"strconv"
 	"strings"
 )
@@ -47,17 +52,87 @@ func Localizer(err error) string {
 			return "В Excel таблице отсутствует лист " + ms.Sheet
 		}
 
+		var uw *xls.UnreadableWorkbookError
+		if errors.As(err, &uw) {
+			switch {
+			case errors.Is(uw, biff.ErrEncrypted), errors.Is(uw, ods.ErrEncrypted):
+				return "Файл таблицы защищен паролем. Снимите защиту и загрузите файл снова"
+			case errors.Is(uw, biff.ErrUnsupportedVersion):
+				return "Формат Excel файла устарел. Сохраните его в формате .xlsx и загрузите снова"
+			case uw.Format == xls.FormatCSV:
+				return "Не удалось прочитать CSV файл, проверьте кавычки в значениях ячеек"
+			default:
+				return fmt.Sprintf("Не удалось прочитать файл .%s, возможно он поврежден. Сохраните его в формате .xlsx и загрузите снова",
+					uw.Format)
+			}
+		}
+
+		var uf *xls.UnsupportedFormatError
+		if errors.As(err, &uf) {
+			return "Неподдерживаемый формат файла. Загрузите таблицу Excel (.xlsx, .xlsm, .xls), LibreOffice (.ods) или CSV"
+		}
+
 		var mc *xls.MissingPayersSheetColumns
 		if errors.As(err, &mc) {
 			return fmt.Sprintf("На листе %s неверное число колонок: имеется %d, ожидается %d",
 				xls.PayersSheet, mc.Have, mc.Want)
 		}
 
+		var mh *xls.MissingColumnsError
+		if errors.As(err, &mh) {
+			return fmt.Sprintf("На листе %s не найдены обязательные колонки: %s",
+				mh.Sheet, strings.Join(mh.Missing, ", "))
+		}
+
+		var ip *xls.InvalidParamsError
+		if errors.As(err, &ip) {
+			var problems []string
+			for _, p := range ip.Problems {
+				problems = append(problems, localizeParamProblem(p))
+			}
+			return fmt.Sprintf("На листе %s неверно указаны реквизиты:\n%s",
+				ip.Sheet, strings.Join(problems, "\n"))
+		}
+
+		var ir *xls.InvalidRowsError
+		if errors.As(err, &ir) {
+			var problems []string
+			for _, p := range ir.Problems {
+				problems = append(problems, localizeRowProblem(p))
+			}
+			return fmt.Sprintf("На листе %s найдены ошибки (%d):\n%s",
+				ir.Sheet, len(problems), strings.Join(problems, "\n"))
+		}
+
+		//
+		// ==== qr package errors ===
+		//
+		var pe *qr.PayloadError
+		if errors.As(err, &pe) {
+			var problems []string
+			for _, p := range pe.Problems {
+				problems = append(problems, localizeRequisiteProblem(p))
+			}
+			return "Данные для QR-кода не соответствуют ГОСТ Р 56042-2014, проверьте настройки организации:\n" +
+				strings.Join(problems, "\n")
+		}
+
+		//
+		// ==== pdf package errors ===
+		//
+		var to *pdf.TextOverflowError
+		if errors.As(err, &to) {
+			return fmt.Sprintf("Текст не помещается в квитанцию даже при уменьшенном шрифте, сократите его: «%s»",
+				strings.ReplaceAll(to.Text, "\n", " "))
+		}
+
 		//
 		// ==== service layer errors ===
 		//
 		emailMappingBaseMsg := "Некоторые плательщики не имеют сопоставленных email адресов"
 		emailSendingBaseMsg := "Не удалось отправить квитанции некоторым получателям"
+		qrVerificationBaseMsg := "QR-коды некоторых плательщиков не распознаются при проверке, их квитанции не отправлены"
+		receiptGenerationBaseMsg := "Не удалось сформировать квитанции некоторых плательщиков"
 
 		var c *service.CompositeError
 		if errors.As(err, &c) {
@@ -71,6 +146,14 @@ func Localizer(err error) string {
 				if errors.As(subErr, &em) {
 					return emailMappingBaseMsg
 				}
+				var qv *service.QrVerificationError
+				if errors.As(subErr, &qv) {
+					return qrVerificationBaseMsg
+				}
+				var rg *service.ReceiptGenerationError
+				if errors.As(subErr, &rg) {
+					return receiptGenerationBaseMsg
+				}
 				// handle other subErr types as needed
 			}
 		}
@@ -80,6 +163,20 @@ func Localizer(err error) string {
 			return emailSendingBaseMsg
 		}
 
+		var rg *service.ReceiptGenerationError
+		if errors.As(err, &rg) {
+			var payers []string
+			for p := range rg.MapPayerCause {
+				payers = append(payers, p)
+			}
+			return receiptGenerationBaseMsg + ": " + strings.Join(payers, ", ")
+		}
+
+		var pf *service.PrintFileNotFoundError
+		if errors.As(err, &pf) {
+			return "Файл квитанций для печати не найден"
+		}
+
 		var em *service.EmailMappingError
 		if errors.As(err, &em) {
 			var payers []string
@@ -92,3 +189,97 @@ func Localizer(err error) string {
 	}
 	return "Ошибка сервера, попробуйте позже, или обратитесь к администратору сервиса"
 }
+
+// localizeRowProblem returns the user message for a single invalid value of the sheet,
+// e.g. "строка 8, КБК «111»: должно быть 20 цифр".
+func localizeRowProblem(p xls.RowProblem) string {
+	var reason string
+	switch p.Kind {
+	case xls.ProblemEmpty:
+		reason = "значение не заполнено"
+	case xls.ProblemNotPositive:
+		reason = "должно быть положительным числом"
+	case xls.ProblemDuplicate:
+		reason = fmt.Sprintf("значение повторяется (впервые в строке %d)", p.DuplicateOf)
+	case xls.ProblemFormat:
+		switch p.Column {
+		case xls.HeaderCBC:
+			reason = fmt.Sprintf("должно быть %d цифр", xls.CBCLength)
+		case xls.HeaderOKTMO:
+			reason = fmt.Sprintf("должно быть %d или %d цифр", xls.OKTMOShortLength, xls.OKTMOLongLength)
+		case xls.EmailsColumnEmail:
+			reason = "неверный адрес электронной почты, ожидается вид name@example.com"
+		default:
+			reason = "неверный формат"
+		}
+	default:
+		reason = "неверное значение"
+	}
+
+	return fmt.Sprintf("строка %d, %s «%s»: %s", p.Row, p.Column, p.Value, reason)
+}
+
+// localizeParamProblem returns the user message for an invalid parameter of the settings sheet,
+// e.g. "БИК «04452522»: должно быть 9 цифр".
+func localizeParamProblem(p xls.ParamProblem) string {
+	var reason string
+	switch p.Kind {
+	case xls.ProblemChecksum:
+		switch p.Param {
+		case xls.ParamPersonalAcc, xls.ParamCorrespAcc:
+			reason = "контрольный ключ не совпадает с БИК, проверьте номер счета и БИК"
+		default:
+			reason = "неверное контрольное число, проверьте значение"
+		}
+	case xls.ProblemFormat:
+		switch p.Param {
+		case xls.ParamBIC:
+			reason = fmt.Sprintf("должно быть %d цифр", requisites.BICLength)
+		case xls.ParamPersonalAcc, xls.ParamCorrespAcc:
+			reason = fmt.Sprintf("должно быть %d цифр", requisites.AccountLength)
+		case xls.ParamINN:
+			reason = fmt.Sprintf("должно быть %d или %d цифр", requisites.INNLegalLength, requisites.INNIndividualLength)
+		case xls.ParamKPP:
+			reason = "должно быть 9 символов: 4 цифры, 2 цифры или заглавные латинские буквы, 3 цифры"
+		case xls.ParamExtraParams:
+			reason = "ожидаются параметры вида Category=4|PaymPeriod=09.2025 (латинские имена реквизитов ГОСТ Р 56042-2014), " +
+				"кодировка QR-кода задается как Encoding=1 (WIN1251), 2 (UTF8) или 3 (KOI8-R)"
+		default:
+			reason = "неверный формат"
+		}
+	default:
+		reason = "неверное значение"
+	}
+
+	return fmt.Sprintf("%s «%s»: %s", p.Param, p.Value, reason)
+}
+
+// localizeRequisiteProblem returns the user message for an invalid requisite of the QR code payload,
+// e.g. "BankName «ОТДЕЛЕНИЕ-НБ ...»: должно быть не более 45 символов".
+func localizeRequisiteProblem(p *qr.RequisiteError) string {
+	var reason string
+	switch {
+	case errors.Is(p, qr.ErrRequired):
+		return fmt.Sprintf("%s: обязательный реквизит не заполнен", p.Key)
+	case errors.Is(p, qr.ErrNoSeparator):
+		return "в значениях встречаются все допустимые разделители реквизитов (| ; # ~ ^ `), уберите хотя бы один из них"
+	case errors.Is(p, qr.ErrTooLong):
+		reason = fmt.Sprintf("должно быть не более %d символов", p.MaxLength)
+	case errors.Is(p, qr.ErrNotDigits):
+		reason = "должно состоять только из цифр"
+	case errors.Is(p, qr.ErrForbiddenChars):
+		reason = "содержит перенос строки или другие служебные символы"
+	case errors.Is(p, qr.ErrNotEncodable):
+		reason = "содержит символы, которых нет в выбранной кодировке QR-кода"
+	case errors.Is(p, qr.ErrInvalidKey):
+		reason = "ожидается реквизит вида Имя=Значение, имя латинскими буквами"
+	case errors.Is(p, qr.ErrDuplicateKey):
+		reason = "реквизит указан несколько раз"
+	case errors.Is(p, qr.ErrUnknownEncoding):
+		reason = "код кодировки должен быть 1 (WIN1251), 2 (UTF8) или 3 (KOI8-R)"
+	default:
+		reason = "неверное значение"
+	}
+
+	return fmt.Sprintf("%s «%s»: %s", p.Key, p.Value, reason)
+}
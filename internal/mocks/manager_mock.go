@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"li-acc/internal/model"
 	"li-acc/internal/service"
 
 	"github.com/stretchr/testify/mock"
@@ -27,7 +28,24 @@ func (m *Manager) HistoryService() service.HistoryService {
 	panic("implement me")
 }
 
-func (m *Manager) ProcessPayersFile(ctx context.Context, filename string, data []byte) (map[string]string, int, error) {
+func (m *Manager) ProcessPayersFile(ctx context.Context, filename string, data []byte) (*model.ReceiptsBatch, error) {
 	args := m.Called(ctx, filename, data)
-	return args.Get(0).(map[string]string), args.Int(1), args.Error(2)
+	batch, _ := args.Get(0).(*model.ReceiptsBatch)
+	return batch, args.Error(1)
+}
+
+func (m *Manager) ReceiptsPrintFile(batch string, scope model.PrintScope) (string, error) {
+	args := m.Called(batch, scope)
+	return args.String(0), args.Error(1)
+}
+
+func (m *Manager) PreviewPayersFile(ctx context.Context, filename string, data []byte) (*model.PayersPreview, error) {
+	args := m.Called(ctx, filename, data)
+	preview, _ := args.Get(0).(*model.PayersPreview)
+	return preview, args.Error(1)
+}
+
+func (m *Manager) InvalidateTemplateCache() (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
 }
@@ -21,7 +21,7 @@ func (m *SettingsService) GetSettings(ctx context.Context) (model.Settings, erro
 	return args.Get(0).(model.Settings), args.Error(1)
 }
 
-func (m *SettingsService) UploadEmails(ctx context.Context, emails map[string]string) error {
+func (m *SettingsService) UploadEmails(ctx context.Context, emails map[string][]string) error {
 	args := m.Called(ctx, emails)
 	return args.Error(0)
 }
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReceiptsBatch is the result of processing the payers file: receipts generated in one batch and sent by email.
+type ReceiptsBatch struct {
+	ID          string              // name of the batch directory in SentReceiptsDir
+	Receipts    map[string][]string // receiver email -> pdf receipts paths
+	SentCount   int                 // number of sent emails
+	PrintScopes []PrintScope        // scopes of the print files made for the batch
+}
+
+// PrintScope is the set of the batch receipts merged into one print file, two receipts per A4 page.
+type PrintScope string
+
+const (
+	// PrintScopeAll prints all generated receipts of the batch
+	PrintScopeAll PrintScope = "all"
+	// PrintScopeUnmapped prints only the receipts of payers with no email, they are handed out on paper
+	PrintScopeUnmapped PrintScope = "unmapped"
+)
+
+// ParsePrintScope parses the print scope by its name: "all" or "unmapped" (case-insensitive). Empty name means PrintScopeAll.
+func ParsePrintScope(scope string) (PrintScope, error) {
+	switch s := PrintScope(strings.ToLower(strings.TrimSpace(scope))); s {
+	case "":
+		return PrintScopeAll, nil
+	case PrintScopeAll, PrintScopeUnmapped:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown print scope %q, expected %q or %q", scope, PrintScopeAll, PrintScopeUnmapped)
+}
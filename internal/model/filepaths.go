@@ -10,6 +10,7 @@ const (
 	ReceiptPatternsDir = TmpDir + "/patterns"
 	SentReceiptsDir    = TmpDir + "/sent"
 	QrCodesDir         = TmpDir + "/qr"
+	TemplateCacheDir   = TmpDir + "/templates"
 )
 
 var BlankReceiptPath = "./assets/excel/blank_receipt_pattern.xls"
@@ -26,6 +27,7 @@ func EnsureTmpDirectories() error {
 		ReceiptPatternsDir,
 		SentReceiptsDir,
 		QrCodesDir,
+		TemplateCacheDir,
 	}
 
 	for _, dir := range dirs {
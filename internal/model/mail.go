@@ -3,17 +3,17 @@ package model
 import "fmt"
 
 type Mail struct {
-	Subject         string            // subject of the mail
-	Body            string            // body of the message
-	To              []string          // list of emails of the receivers
-	From            string            // sender email
-	AttachmentPaths map[string]string // receiver email -> attachment file path
+	Subject         string              // subject of the mail
+	Body            string              // body of the message
+	To              []string            // list of emails of the receivers
+	From            string              // sender email
+	AttachmentPaths map[string][]string // receiver email -> attachment file paths, all of them are sent in one message
 }
 
-func (m Mail) GetAttachmentPath(email string) (string, error) {
+func (m Mail) GetAttachmentPaths(email string) ([]string, error) {
 	p, ok := m.AttachmentPaths[email]
-	if !ok {
-		return "", fmt.Errorf("no such email in AttachmentPaths")
+	if !ok || len(p) == 0 {
+		return nil, fmt.Errorf("no such email in AttachmentPaths")
 	}
 	return p, nil
 }
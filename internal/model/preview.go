@@ -0,0 +1,18 @@
+package model
+
+import pkg "li-acc/pkg/model"
+
+// PayersPreview is the result of the payers file dry run: parsed data exactly as it would be used
+// to generate and send receipts, but nothing is generated or sent.
+type PayersPreview struct {
+	Organization    pkg.Organization `json:"organization"`
+	Payers          []PayerPreview   `json:"payers"`
+	UnmatchedPayers []string         `json:"unmatched_payers"` // full names of payers with no emails in the emails mapping
+}
+
+// PayerPreview is a single parsed payer with the data of his receipt
+type PayerPreview struct {
+	Payer  pkg.Payer `json:"payer"`
+	QrData string    `json:"qr_data"`          // payload string of the receipt's QR code
+	Emails []string  `json:"emails,omitempty"` // empty if the payer has no emails in the emails mapping
+}
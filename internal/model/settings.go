@@ -5,9 +5,9 @@ import "encoding/json"
 // Settings struct represents the model used in database, table `settings`.
 // Store all data needed to form and send receipts.
 type Settings struct {
-	Emails      map[string]string `db:"-"`      // map 'Payer's Full Name' -> 'Payer's email'
-	EmailsJSON  string            `db:"Emails"` // emails are stored in DB as json string
-	SenderEmail string            `db:"SenderEmail"`
+	Emails      map[string][]string `db:"-"`      // map 'Payer's Full Name' -> 'Payer's emails'
+	EmailsJSON  string              `db:"Emails"` // emails are stored in DB as json string
+	SenderEmail string              `db:"SenderEmail"`
 }
 
 // BeforeSave fills EmailsJSON field with serialized data to save it in DB
@@ -20,11 +20,27 @@ func (s *Settings) BeforeSave() error {
 	return nil
 }
 
-// AfterLoad decodes json string fetched from DB into Emails map
+// AfterLoad decodes json string fetched from DB into Emails map.
+// Emails saved before several emails per payer were supported are stored as 'Full Name' -> 'email' map,
+// they are loaded as lists of a single email.
 func (s *Settings) AfterLoad() error {
 	if s.EmailsJSON == "" {
-		s.Emails = make(map[string]string)
+		s.Emails = make(map[string][]string)
 		return nil
 	}
-	return json.Unmarshal([]byte(s.EmailsJSON), &s.Emails)
+
+	err := json.Unmarshal([]byte(s.EmailsJSON), &s.Emails)
+	if err == nil {
+		return nil
+	}
+
+	var single map[string]string
+	if json.Unmarshal([]byte(s.EmailsJSON), &single) != nil {
+		return err
+	}
+	s.Emails = make(map[string][]string, len(single))
+	for name, email := range single {
+		s.Emails[name] = []string{email}
+	}
+	return nil
 }
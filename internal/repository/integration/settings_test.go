@@ -17,9 +17,9 @@ func TestSettingsRepository_SetAndGetSettings(t *testing.T) {
 
 	repo := repository.NewSettingsRepository(testRepo)
 
-	emails := map[string]string{
-		"John Doe":   "john@example.com",
-		"Jane Smith": "jane@example.com",
+	emails := map[string][]string{
+		"John Doe":   {"john@example.com", "john.doe@example.com"},
+		"Jane Smith": {"jane@example.com"},
 	}
 
 	set := model.Settings{
@@ -45,8 +45,8 @@ func TestSettingsRepository_SetEmails(t *testing.T) {
 	repo := repository.NewSettingsRepository(testRepo)
 
 	// Initial emails
-	initialEmails := map[string]string{
-		"A": "a@test.com",
+	initialEmails := map[string][]string{
+		"A": {"a@test.com"},
 	}
 	err := repo.SetEmails(context.Background(), initialEmails)
 	require.NoError(t, err)
@@ -56,20 +56,20 @@ func TestSettingsRepository_SetEmails(t *testing.T) {
 	err = testRepo.DB.QueryRow(context.Background(), `SELECT Emails FROM settings WHERE Id=1`).Scan(&gotJSON)
 	require.NoError(t, err)
 
-	var gotMap map[string]string
+	var gotMap map[string][]string
 	err = json.Unmarshal([]byte(gotJSON), &gotMap)
 	require.NoError(t, err)
 	require.Equal(t, initialEmails, gotMap)
 
 	// Update emails
-	newEmails := map[string]string{
-		"B": "b@test.com",
-		"C": "c@test.com",
+	newEmails := map[string][]string{
+		"B": {"b@test.com", "b2@test.com"},
+		"C": {"c@test.com"},
 	}
 	err = repo.SetEmails(context.Background(), newEmails)
 	require.NoError(t, err)
 
-	gotMap = map[string]string{}
+	gotMap = map[string][]string{}
 
 	// Check updated
 	err = testRepo.DB.QueryRow(context.Background(), `SELECT Emails FROM settings WHERE Id=1`).Scan(&gotJSON)
@@ -79,6 +79,22 @@ func TestSettingsRepository_SetEmails(t *testing.T) {
 	require.Equal(t, newEmails, gotMap)
 }
 
+func TestSettingsRepository_GetSettings_SingleEmailFormat(t *testing.T) {
+	ensureDBReady(t)
+
+	repo := repository.NewSettingsRepository(testRepo)
+
+	// emails stored before several emails per payer were supported
+	_, err := testRepo.DB.Exec(context.Background(), `
+		INSERT INTO settings (Id, Emails) VALUES (1, $1)
+		ON CONFLICT (Id) DO UPDATE SET Emails = EXCLUDED.Emails`, `{"A": "a@test.com"}`)
+	require.NoError(t, err)
+
+	got, err := repo.GetSettings(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"A": {"a@test.com"}}, got.Emails)
+}
+
 func TestSettingsRepository_SetSenderEmail(t *testing.T) {
 	ensureDBReady(t)
 
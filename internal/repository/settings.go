@@ -83,7 +83,7 @@ func (r *SettingsRepository) SetSettings(ctx context.Context, settings model.Set
 }
 
 // SetEmails updates the Emails field in the single settings record (Id=1).
-func (r *SettingsRepository) SetEmails(ctx context.Context, emails map[string]string) error {
+func (r *SettingsRepository) SetEmails(ctx context.Context, emails map[string][]string) error {
 	// Convert map to JSON string
 	data, err := json.Marshal(emails)
 	if err != nil {
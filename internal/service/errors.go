@@ -3,6 +3,7 @@ package service
 import (
 	"fmt"
 	"li-acc/internal/errs"
+	"li-acc/internal/model"
 	"strings"
 )
 
@@ -33,8 +34,8 @@ func (c *CompositeError) Unwrap() []error {
 // EmailSendingError error raised when sender.SendMail() returned an error.
 // Implement interface errs.CodedError
 type EmailSendingError struct {
-	MapReceiverCause map[string]string // map receiver's email -> error message (cause)
-	AttachmentPaths  map[string]string
+	MapReceiverCause map[string]string   // map receiver's email -> error message (cause)
+	AttachmentPaths  map[string][]string // map receiver's email -> receipts that were not delivered
 }
 
 func (e *EmailSendingError) Error() string {
@@ -59,7 +60,7 @@ func (e *EmailSendingError) FailedCount() int {
 
 // EmailMappingError error raised when there are no emails mapped for some payers
 type EmailMappingError struct {
-	MapPayerReceipt map[string]string // map payer name -> pdf receipt path
+	MapPayerReceipt map[string]string // map payer receipt name (see receiptFileName) -> pdf receipt path
 }
 
 func (e *EmailMappingError) Error() string {
@@ -81,3 +82,74 @@ func (e *EmailMappingError) Unwrap() error {
 func (e *EmailMappingError) FailedCount() int {
 	return len(e.MapPayerReceipt)
 }
+
+// QrVerificationError error raised when generated QR codes of some payers are not scanned back to their payload,
+// so their receipts are not generated and sent
+type QrVerificationError struct {
+	MapPayerCause map[string]string // map payer receipt name (see receiptFileName) -> verification error message (cause)
+}
+
+func (e *QrVerificationError) Error() string {
+	var msg []string
+	for payer, cause := range e.MapPayerCause {
+		msg = append(msg, fmt.Sprint(payer, ": ", cause))
+	}
+	return fmt.Sprintf("QR codes of some payers failed verification. %v", msg)
+}
+
+func (e *QrVerificationError) Kind() errs.Kind {
+	return errs.User
+}
+
+func (e *QrVerificationError) Unwrap() error {
+	return nil
+}
+
+func (e *QrVerificationError) FailedCount() int {
+	return len(e.MapPayerCause)
+}
+
+// ReceiptGenerationError error raised when the receipts of some payers are not generated, e.g. their data do not fit
+// the QR payload or the receipt can not be saved. The other payers of the batch get their receipts.
+type ReceiptGenerationError struct {
+	MapPayerCause map[string]string // map payer receipt name (see receiptFileName) -> generation error message (cause)
+}
+
+func (e *ReceiptGenerationError) Error() string {
+	var msg []string
+	for payer, cause := range e.MapPayerCause {
+		msg = append(msg, fmt.Sprint(payer, ": ", cause))
+	}
+	return fmt.Sprintf("receipts of some payers are not generated. %v", msg)
+}
+
+func (e *ReceiptGenerationError) Kind() errs.Kind {
+	return errs.User
+}
+
+func (e *ReceiptGenerationError) Unwrap() error {
+	return nil
+}
+
+func (e *ReceiptGenerationError) FailedCount() int {
+	return len(e.MapPayerCause)
+}
+
+// PrintFileNotFoundError error raised when the print file of the receipts batch is requested,
+// but the batch is unknown or has no receipts of the scope
+type PrintFileNotFoundError struct {
+	Batch string
+	Scope model.PrintScope
+}
+
+func (e *PrintFileNotFoundError) Error() string {
+	return fmt.Sprintf("print file `%s` of receipts batch `%s` is not found", e.Scope, e.Batch)
+}
+
+func (e *PrintFileNotFoundError) Kind() errs.Kind {
+	return errs.User
+}
+
+func (e *PrintFileNotFoundError) Unwrap() error {
+	return nil
+}
@@ -38,9 +38,13 @@ func NewMailService(smtp model.SMTP) MailService {
 // The method launches up to [maxParallel] concurrent senders to avoid overloading the SMTP server.
 // Each goroutine reports its result into a channel [sent]. When all emails are processed,
 // it collects all errors (if any) and returns amount of sent mails and a combined error message.
+// Each address gets its own message with all of its attachments, so a payer with several emails receives
+// the receipt on all of them, and a parent of several children gets their receipts in one message.
+// Repeated addresses in [mail.To] are sent only once.
 func (m *mailService) SendMails(ctx context.Context, mail model.Mail) (int, error) {
 	start := time.Now()
 	maxParallel := defaultMaxParallel
+	mail.To = uniqueRecipients(mail.To)
 
 	var errorType string
 	var totalSent int
@@ -117,8 +121,8 @@ func (m *mailService) SendMails(ctx context.Context, mail model.Mail) (int, erro
 			default:
 			}
 
-			attach, err := mail.GetAttachmentPath(recipient)
-			msg, _ := sender.FormMessage(mail.Subject, mail.Body, attach, mail.From, recipient)
+			attach, err := mail.GetAttachmentPaths(recipient)
+			msg, _ := sender.FormMessageWithAttachments(mail.Subject, mail.Body, attach, mail.From, recipient)
 			if err != nil {
 				statusChan <- sender.EmailStatus{
 					Status:    sender.Error,
@@ -142,7 +146,7 @@ func (m *mailService) SendMails(ctx context.Context, mail model.Mail) (int, erro
 
 	// Collect results
 	failedMails := make(map[string]string)
-	failedAttachments := make(map[string]string)
+	failedAttachments := make(map[string][]string)
 
 	for status := range statusChan {
 		if status.Status == sender.Error {
@@ -160,7 +164,7 @@ func (m *mailService) SendMails(ctx context.Context, mail model.Mail) (int, erro
 						continue
 					}
 					failedMails[receiver] = status.Cause.Error()
-					attach, _ := mail.GetAttachmentPath(receiver)
+					attach, _ := mail.GetAttachmentPaths(receiver)
 					failedAttachments[receiver] = attach
 				}
 			}
@@ -187,6 +191,20 @@ func (m *mailService) SendMails(ctx context.Context, mail model.Mail) (int, erro
 	return totalSent, nil
 }
 
+// uniqueRecipients returns recipients without repeats, keeping their order
+func uniqueRecipients(to []string) []string {
+	seen := make(map[string]bool, len(to))
+	unique := make([]string, 0, len(to))
+	for _, r := range to {
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		unique = append(unique, r)
+	}
+	return unique
+}
+
 func (m *mailService) GetSenderEmail() string {
 	return m.sender.GetSenderEmail()
 }
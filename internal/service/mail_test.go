@@ -54,9 +54,9 @@ func newTestMail(recipients ...string) model.Mail {
 		Body:    "Hello there!",
 		From:    "mock@sender.com",
 		To:      recipients,
-		AttachmentPaths: map[string]string{
-			"ok@example.com":   "/tmp/ok.pdf",
-			"fail@example.com": "/tmp/fail.pdf",
+		AttachmentPaths: map[string][]string{
+			"ok@example.com":   {"/tmp/ok.pdf"},
+			"fail@example.com": {"/tmp/fail.pdf"},
 		},
 	}
 }
@@ -78,6 +78,35 @@ func TestSendMails_AllSuccess(t *testing.T) {
 	require.Equal(t, sentCount, 1)
 }
 
+func TestSendMails_DuplicateRecipients(t *testing.T) {
+	ctx := context.Background()
+	mock := &mockSender{results: map[string]error{
+		"ok@example.com": nil,
+	}}
+	s := &mailService{sender: mock}
+
+	// the same address of several payers gets the receipt once
+	mail := newTestMail("ok@example.com", "ok@example.com")
+	sentCount, err := s.SendMails(ctx, mail)
+	require.NoError(t, err)
+	require.Equal(t, 1, sentCount)
+}
+
+func TestSendMails_SeveralAttachments(t *testing.T) {
+	ctx := context.Background()
+	mock := &mockSender{results: map[string]error{
+		"ok@example.com": nil,
+	}}
+	s := &mailService{sender: mock}
+
+	// receipts of siblings go to their parent in one message
+	mail := newTestMail("ok@example.com")
+	mail.AttachmentPaths["ok@example.com"] = []string{"/tmp/first.pdf", "/tmp/second.pdf"}
+	sentCount, err := s.SendMails(ctx, mail)
+	require.NoError(t, err)
+	require.Equal(t, 1, sentCount)
+}
+
 func TestSendMails_SomeFailures(t *testing.T) {
 	ctx := context.Background()
 	mock := &mockSender{results: map[string]error{
@@ -136,7 +165,7 @@ func TestSendMails_AttachmentMissing(t *testing.T) {
 	s := &mailService{sender: mock}
 
 	mail := newTestMail("ok@example.com")
-	// Remove attachment to simulate GetAttachmentPath error
+	// Remove attachment to simulate GetAttachmentPaths error
 	mail.AttachmentPaths = nil
 
 	sentCount, err := s.SendMails(ctx, mail)
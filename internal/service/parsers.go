@@ -0,0 +1,103 @@
+package service
+
+import (
+	pkg "li-acc/pkg/model"
+	"li-acc/pkg/xls"
+)
+
+// ===== CSV implementations of the parsers =====
+
+// csvPayerParser extracts payer data from CSV files laid out as the payers sheet.
+type csvPayerParser struct{}
+
+func (csvPayerParser) ParsePayers(path string) ([]pkg.Payer, error) {
+	return xls.ParsePayersCSV(path)
+}
+
+// csvOrgParser extracts organization data from CSV files laid out as the settings sheet.
+type csvOrgParser struct{}
+
+func (csvOrgParser) ParseSettings(path string) (*pkg.Organization, error) {
+	return xls.ParseSettingsCSV(path)
+}
+
+// csvEmailParser extracts payers emails from CSV files laid out as the emails sheet.
+type csvEmailParser struct{}
+
+func (csvEmailParser) ParseEmail(path string) (map[string][]string, error) {
+	return xls.ParseEmailCSV(path)
+}
+
+// ===== Parsers choosing the implementation by the file format =====
+
+// formatPayerParser parses spreadsheets (xlsx, xlsm, xls, ods) with `spreadsheet` parser and CSV files with `csv` one.
+// The format is detected by the file content, see xls.DetectFormat.
+type formatPayerParser struct {
+	spreadsheet PayerParser
+	csv         PayerParser
+}
+
+func newFormatPayerParser() formatPayerParser {
+	return formatPayerParser{spreadsheet: defaultPayerParser{}, csv: csvPayerParser{}}
+}
+
+func (p formatPayerParser) ParsePayers(path string) ([]pkg.Payer, error) {
+	parser, err := chooseParser(path, p.spreadsheet, p.csv)
+	if err != nil {
+		return nil, err
+	}
+	return parser.ParsePayers(path)
+}
+
+// formatOrgParser parses spreadsheets (xlsx, xlsm, xls, ods) with `spreadsheet` parser and CSV files with `csv` one.
+// The format is detected by the file content, see xls.DetectFormat.
+type formatOrgParser struct {
+	spreadsheet OrgParser
+	csv         OrgParser
+}
+
+func newFormatOrgParser() formatOrgParser {
+	return formatOrgParser{spreadsheet: defaultOrgParser{}, csv: csvOrgParser{}}
+}
+
+func (p formatOrgParser) ParseSettings(path string) (*pkg.Organization, error) {
+	parser, err := chooseParser(path, p.spreadsheet, p.csv)
+	if err != nil {
+		return nil, err
+	}
+	return parser.ParseSettings(path)
+}
+
+// formatEmailParser parses spreadsheets (xlsx, xlsm, xls, ods) with `spreadsheet` parser and CSV files with `csv` one.
+// The format is detected by the file content, see xls.DetectFormat.
+type formatEmailParser struct {
+	spreadsheet EmailParser
+	csv         EmailParser
+}
+
+func newFormatEmailParser() formatEmailParser {
+	return formatEmailParser{spreadsheet: &xlsEmailParser{}, csv: csvEmailParser{}}
+}
+
+func (p formatEmailParser) ParseEmail(path string) (map[string][]string, error) {
+	parser, err := chooseParser(path, p.spreadsheet, p.csv)
+	if err != nil {
+		return nil, err
+	}
+	return parser.ParseEmail(path)
+}
+
+// chooseParser returns `csv` parser for CSV files and `spreadsheet` parser for all other files.
+// Files of unknown format also go to the spreadsheet parser, which reports them as xls.UnsupportedFormatError.
+func chooseParser[T any](path string, spreadsheet, csv T) (T, error) {
+	format, err := xls.DetectFormat(path)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	if format == xls.FormatCSV {
+		return csv, nil
+	}
+	return spreadsheet, nil
+}
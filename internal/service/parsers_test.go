@@ -0,0 +1,73 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	pkg "li-acc/pkg/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatParsers(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "table.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte("ФИО;Почта\nИванов И.И.;ivanov@example.com\n"), 0o644))
+	binPath := filepath.Join(dir, "table.xls")
+	require.NoError(t, os.WriteFile(binPath, []byte("PK\x03\x04\x00\x00"), 0o644))
+
+	spreadsheetPayers := []pkg.Payer{{CHILDFIO: "spreadsheet"}}
+	csvPayers := []pkg.Payer{{CHILDFIO: "csv"}}
+	payers := formatPayerParser{
+		spreadsheet: &mockPayerParser{payers: spreadsheetPayers},
+		csv:         &mockPayerParser{payers: csvPayers},
+	}
+
+	spreadsheetOrg := &pkg.Organization{Name: "spreadsheet"}
+	csvOrg := &pkg.Organization{Name: "csv"}
+	orgs := formatOrgParser{
+		spreadsheet: &mockOrgParser{org: spreadsheetOrg},
+		csv:         &mockOrgParser{org: csvOrg},
+	}
+
+	spreadsheetEmails := map[string][]string{"a": {"spreadsheet"}}
+	csvEmails := map[string][]string{"a": {"csv"}}
+	emails := formatEmailParser{
+		spreadsheet: &mockEmailParser{result: spreadsheetEmails},
+		csv:         &mockEmailParser{result: csvEmails},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		gotPayers, err := payers.ParsePayers(csvPath)
+		require.NoError(t, err)
+		require.Equal(t, csvPayers, gotPayers)
+
+		gotOrg, err := orgs.ParseSettings(csvPath)
+		require.NoError(t, err)
+		require.Equal(t, csvOrg, gotOrg)
+
+		gotEmails, err := emails.ParseEmail(csvPath)
+		require.NoError(t, err)
+		require.Equal(t, csvEmails, gotEmails)
+	})
+
+	t.Run("not csv goes to spreadsheet parser", func(t *testing.T) {
+		gotPayers, err := payers.ParsePayers(binPath)
+		require.NoError(t, err)
+		require.Equal(t, spreadsheetPayers, gotPayers)
+
+		gotOrg, err := orgs.ParseSettings(binPath)
+		require.NoError(t, err)
+		require.Equal(t, spreadsheetOrg, gotOrg)
+
+		gotEmails, err := emails.ParseEmail(binPath)
+		require.NoError(t, err)
+		require.Equal(t, spreadsheetEmails, gotEmails)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := payers.ParsePayers(filepath.Join(dir, "missing.csv"))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"li-acc/internal/errs"
+	"li-acc/internal/model"
+	"li-acc/pkg/logger"
+	"li-acc/pkg/pdf"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"go.uber.org/zap"
+)
+
+// printDirName is the directory of the print files inside the batch directory, apart from the receipts sent by email
+const printDirName = "print"
+
+// batchIDPattern matches the names of the batch directories made by createNowDir
+var batchIDPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}$`)
+
+// printFilePath returns the path of the print file of the [scope] in the batch directory
+func printFilePath(batchDir string, scope model.PrintScope) string {
+	return filepath.Join(batchDir, printDirName, string(scope)+".pdf")
+}
+
+// writePrintFiles merges the receipts of every print scope into its print file, two receipts per A4 page.
+// Scopes with no receipts get no file. The print files are the convenience for the paper receipts, so their failure
+// is logged and does not stop the batch. Returns the scopes of the written files.
+func (m *Manager) writePrintFiles(batchDir string, receipts map[model.PrintScope][]string) []model.PrintScope {
+	var written []model.PrintScope
+	for _, scope := range []model.PrintScope{model.PrintScopeAll, model.PrintScopeUnmapped} {
+		if len(receipts[scope]) == 0 {
+			continue
+		}
+
+		path := printFilePath(batchDir, scope)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			logger.Error("failed to create print files dir", zap.String("dir", filepath.Dir(path)), zap.Error(err))
+			return written
+		}
+		if err := pdf.MergeReceiptsForPrint(path, receipts[scope]); err != nil {
+			logger.Error("failed to merge receipts for print", zap.String("scope", string(scope)), zap.Error(err))
+			continue
+		}
+		written = append(written, scope)
+		logger.Info("receipts merged for print", zap.String("pdf", path), zap.Int("receipts", len(receipts[scope])))
+	}
+	return written
+}
+
+// ReceiptsPrintFile returns the path of the print file of the [batch] receipts, made by ProcessPayersFile.
+// Returns PrintFileNotFoundError if the batch is unknown or has no receipts of the scope.
+func (m *Manager) ReceiptsPrintFile(batch string, scope model.PrintScope) (string, error) {
+	notFound := &PrintFileNotFoundError{Batch: batch, Scope: scope}
+	// the batch is the name of the directory, so paths outside of the receipts directory are rejected here
+	if !batchIDPattern.MatchString(batch) {
+		return "", notFound
+	}
+
+	path := printFilePath(filepath.Join(m.dirs.SentReceiptsDir, batch), scope)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) || (err == nil && info.IsDir()) {
+		return "", notFound
+	}
+	if err != nil {
+		return "", errs.WrapIOError("check receipts print file", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"li-acc/internal/errs"
+	"li-acc/internal/model"
+	pkg "li-acc/pkg/model"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormPersonalReceipts_PrintFiles(t *testing.T) {
+	settings := model.Settings{Emails: map[string][]string{"иванов иван": {"ivanov@example.com"}}, SenderEmail: "c"}
+	m := &Manager{Settings: &mockSettingsService{settings: settings}, pdfFontPath: "./testdata/Arial.ttf"}
+	m.dirs.TemplateCacheDir = t.TempDir()
+	m.dirs.SentReceiptsDir = t.TempDir()
+	org := pkg.Organization{
+		Name: "Org", PersonalAcc: "40702810938000012345", BankName: "Сбер", BIC: "044525225", CorrespAcc: "30101810400000000225",
+	}
+	payers := []pkg.Payer{
+		{CHILDFIO: "Иванов Иван", PersAcc: "1", Purpose: "питание", Sum: pkg.NewMoney(100, 0)},
+		{CHILDFIO: "Петров Петр", PersAcc: "2", Purpose: "питание", Sum: pkg.NewMoney(200, 0)},
+		{CHILDFIO: "Сидоров Сидор", PersAcc: "3", Purpose: "питание", Sum: pkg.NewMoney(300, 0)},
+	}
+
+	batch, err := m.formPersonalReceipts(context.Background(), payers, org)
+	var mappingErr *EmailMappingError
+	require.ErrorAs(t, err, &mappingErr)
+	require.Equal(t, 2, mappingErr.FailedCount())
+
+	require.NotNil(t, batch)
+	require.Equal(t, []string{"ivanov@example.com"}, slices.Collect(maps.Keys(batch.Receipts)))
+	require.Equal(t, []model.PrintScope{model.PrintScopeAll, model.PrintScopeUnmapped}, batch.PrintScopes)
+
+	// three receipts take two pages, two receipts of unmapped payers take one
+	pages := map[model.PrintScope]string{model.PrintScopeAll: "/Count 2", model.PrintScopeUnmapped: "/Count 1"}
+	for _, scope := range batch.PrintScopes {
+		path, err := m.ReceiptsPrintFile(batch.ID, scope)
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(m.dirs.SentReceiptsDir, batch.ID, printDirName), filepath.Dir(path))
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Contains(t, string(data), pages[scope], scope)
+	}
+}
+
+func TestReceiptsPrintFile(t *testing.T) {
+	m := &Manager{}
+	m.dirs.SentReceiptsDir = t.TempDir()
+
+	const batch = "2025-09-01_10-00-00"
+	path := printFilePath(filepath.Join(m.dirs.SentReceiptsDir, batch), model.PrintScopeAll)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte("%PDF-1.7"), 0o644))
+
+	got, err := m.ReceiptsPrintFile(batch, model.PrintScopeAll)
+	require.NoError(t, err)
+	require.Equal(t, path, got)
+
+	tests := map[string]struct {
+		batch string
+		scope model.PrintScope
+	}{
+		"no file of scope": {batch, model.PrintScopeUnmapped},
+		"unknown batch":    {"2025-09-02_10-00-00", model.PrintScopeAll},
+		"path traversal":   {"../" + batch, model.PrintScopeAll},
+		"not a batch":      {printDirName, model.PrintScopeAll},
+	}
+	for name, tt := range tests {
+		_, err := m.ReceiptsPrintFile(tt.batch, tt.scope)
+		var notFound *PrintFileNotFoundError
+		require.True(t, errors.As(err, &notFound), name)
+		require.True(t, errs.IsUserError(err), name)
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"li-acc/internal/errs"
+	"li-acc/pkg/logger"
+	pkg "li-acc/pkg/model"
+	"li-acc/pkg/pdf"
+	"li-acc/pkg/qr"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// defaultReceiptWorkers is the default number of receipts generated at once: every receipt takes one CPU core
+// for QR code rendering and PDF compression, so more workers do not speed the batch up.
+var defaultReceiptWorkers = runtime.NumCPU()
+
+// SetReceiptWorkers sets the number of receipts generated at once. Zero or negative value means the number of CPUs.
+func (m *Manager) SetReceiptWorkers(n int) {
+	m.receiptWorkers = n
+}
+
+// receiptWorkersCount returns the number of receipt generation workers for [jobs] payers
+func (m *Manager) receiptWorkersCount(jobs int) int {
+	workers := m.receiptWorkers
+	if workers <= 0 {
+		workers = defaultReceiptWorkers
+	}
+	return max(min(workers, jobs), 1)
+}
+
+// receiptGenerator makes the receipts of one batch. It is shared by the workers, so it keeps only the data
+// that is safe for concurrent use: every payer gets its own canvas of the template.
+type receiptGenerator struct {
+	template    *pdf.ReceiptTemplate
+	qrCreator   *qr.QrCode
+	qrOptions   qr.RenderOptions
+	receiptsDir string
+	qrDir       string // empty if QR code images are not saved
+	verifyQr    bool
+}
+
+// payerReceipt is the result of the receipt generation of one payer
+type payerReceipt struct {
+	pdfFile    string // path of the saved receipt, empty if it is not generated
+	unverified string // cause of the QR code verification failure, the payer gets no receipt
+	errorType  string // stage of the generation failed with err, for metrics
+	err        error
+}
+
+// generateReceipts generates the receipts of the [payers] by up to [workers] goroutines. The results are in the payers order.
+// A failed payer does not stop the others, its error is in its result. Canceled [ctx] stops all workers,
+// the payers not processed yet get no result, so ctx.Err() must be checked by the caller.
+func (g *receiptGenerator) generateReceipts(ctx context.Context, payers []pkg.Payer, workers int) []payerReceipt {
+	results := make([]payerReceipt, len(payers))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// every worker writes only the results of its payers
+				results[i] = g.generate(ctx, payers[i])
+			}
+		}()
+	}
+
+feed:
+	for i := range payers {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// generate renders the QR code of the [payer], fills the receipt and saves it to the receipts directory
+func (g *receiptGenerator) generate(ctx context.Context, payer pkg.Payer) payerReceipt {
+	if err := ctx.Err(); err != nil {
+		return payerReceipt{errorType: "context_closed", err: err}
+	}
+	payerFileName := receiptFileName(payer)
+	fail := func(errorType string, err error) payerReceipt {
+		return payerReceipt{errorType: errorType, err: err}
+	}
+
+	// create canvas per-payer (pdf object drawn over the template)
+	canvas, err := g.template.NewCanvas()
+	if err != nil {
+		logger.Error("failed to create canvas from template", zap.Error(err))
+		return fail("create_pdf_canvas", err)
+	}
+
+	qrString, err := g.qrCreator.GetPayersQrDataString(payer)
+	if err != nil {
+		logger.Error("failed to build qr data", zap.String("pers_acc", payer.PersAcc), zap.Error(err))
+		return fail("build_qr_data", err) // qr.PayloadError
+	}
+	qrImgBytes, err := g.qrCreator.RenderQRCode(qrString, g.qrOptions)
+	if err != nil {
+		logger.Error("failed to generate qr", zap.String("pers_acc", payer.PersAcc), zap.Error(err))
+		return fail("generate_qr_code", err)
+	}
+
+	if g.qrDir != "" {
+		qrFile := filepath.Join(g.qrDir, payerFileName+".png")
+		if err := os.WriteFile(qrFile, qrImgBytes, 0o644); err != nil {
+			logger.Error("failed to save qr image", zap.String("qrFile", qrFile), zap.Error(err))
+			return fail("save_qr_code", errs.WrapIOError("write QR code file", qrFile, err))
+		}
+	}
+
+	if g.verifyQr {
+		err := g.qrCreator.VerifyQRCode(qrImgBytes, qrString)
+		var verifyErr *qr.VerificationError
+		if errors.As(err, &verifyErr) {
+			// do not send the receipt that bank applications may fail to scan, other payers are processed
+			logger.Warn("generated qr code failed verification", zap.String("pers_acc", payer.PersAcc), zap.Error(err))
+			return payerReceipt{unverified: verifyErr.Error()}
+		} else if err != nil {
+			logger.Error("failed to verify qr code", zap.String("pers_acc", payer.PersAcc), zap.Error(err))
+			return fail("verify_qr_code", err)
+		}
+	}
+
+	if err := canvas.Fill(payer, qrImgBytes); err != nil {
+		logger.Error("canvas.Fill error", zap.String("pers_acc", payer.PersAcc), zap.Error(err))
+		return fail("fill_payer_info", err)
+	}
+	for _, overflow := range canvas.Warnings() {
+		logger.Warn("text does not fit receipt frame", zap.String("pers_acc", payer.PersAcc), zap.Error(overflow))
+	}
+
+	pdfFile := filepath.Join(g.receiptsDir, payerFileName+".pdf")
+	if err := canvas.Save(pdfFile); err != nil {
+		logger.Error("failed to save pdf", zap.String("pdf", pdfFile), zap.Error(err))
+		return fail("save_pdf_receipt", err)
+	}
+	return payerReceipt{pdfFile: pdfFile}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"li-acc/internal/model"
+	pkg "li-acc/pkg/model"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newReceiptsTestManager returns the manager generating receipts into the temporary directories
+func newReceiptsTestManager(t *testing.T, emails map[string][]string) *Manager {
+	t.Helper()
+	settings := model.Settings{Emails: emails, SenderEmail: "c"}
+	m := &Manager{Settings: &mockSettingsService{settings: settings}, pdfFontPath: "./testdata/Arial.ttf"}
+	m.dirs.TemplateCacheDir = t.TempDir()
+	m.dirs.SentReceiptsDir = t.TempDir()
+	return m
+}
+
+var receiptsTestOrg = pkg.Organization{
+	Name: "Org", PersonalAcc: "40702810938000012345", BankName: "Сбер", BIC: "044525225", CorrespAcc: "30101810400000000225",
+}
+
+func TestFormPersonalReceipts_Workers(t *testing.T) {
+	// the siblings share the parent email, so their receipts are in one list
+	var payers []pkg.Payer
+	emails := make(map[string][]string)
+	for i := range 6 {
+		name := fmt.Sprintf("Иванов Ребенок%d", i)
+		payers = append(payers, pkg.Payer{CHILDFIO: name, PersAcc: fmt.Sprint(i + 1), Purpose: "питание", Sum: pkg.NewMoney(100, 0)})
+		emails[strings.ToLower(name)] = []string{"parent@example.com"}
+	}
+	// control characters do not fit the QR payload, so the payer gets no receipt
+	payers[2].Purpose = "питание\t"
+
+	for _, workers := range []int{1, 4, 0} {
+		t.Run(fmt.Sprintf("workers %d", workers), func(t *testing.T) {
+			m := newReceiptsTestManager(t, emails)
+			m.SetReceiptWorkers(workers)
+
+			batch, err := m.formPersonalReceipts(context.Background(), payers, receiptsTestOrg)
+			var partialErr *CompositeError
+			require.ErrorAs(t, err, &partialErr, "one bad payer does not abort the batch")
+			var generationErr *ReceiptGenerationError
+			require.ErrorAs(t, err, &generationErr)
+			require.Equal(t, 1, generationErr.FailedCount())
+			require.Contains(t, generationErr.MapPayerCause["3_Иванов_Ребенок2"], "invalid payment payload")
+
+			require.NotNil(t, batch)
+			var names []string
+			for _, path := range batch.Receipts["parent@example.com"] {
+				names = append(names, filepath.Base(path))
+			}
+			require.Equal(t, []string{
+				"1_Иванов_Ребенок0.pdf", "2_Иванов_Ребенок1.pdf", "4_Иванов_Ребенок3.pdf", "5_Иванов_Ребенок4.pdf", "6_Иванов_Ребенок5.pdf",
+			}, names, "receipts are in the payers order")
+			require.Equal(t, []model.PrintScope{model.PrintScopeAll}, batch.PrintScopes)
+		})
+	}
+}
+
+func TestFormPersonalReceipts_AllPayersFailed(t *testing.T) {
+	m := newReceiptsTestManager(t, map[string][]string{"иванов иван": {"ivanov@example.com"}})
+	org := receiptsTestOrg
+	org.BIC = "04452522a" // the organization settings do not fit the QR payload of any payer
+
+	payers := []pkg.Payer{
+		{CHILDFIO: "Иванов Иван", PersAcc: "1", Purpose: "питание", Sum: pkg.NewMoney(100, 0)},
+		{CHILDFIO: "Петров Петр", PersAcc: "2", Purpose: "питание", Sum: pkg.NewMoney(200, 0)},
+	}
+	batch, err := m.formPersonalReceipts(context.Background(), payers, org)
+	require.Nil(t, batch)
+	var generationErr *ReceiptGenerationError
+	require.ErrorAs(t, err, &generationErr)
+	require.Equal(t, 2, generationErr.FailedCount(), "every payer is reported")
+	require.Contains(t, generationErr.MapPayerCause["1_Иванов_Иван"], "invalid payment payload")
+	require.Contains(t, generationErr.MapPayerCause["2_Петров_Петр"], "invalid payment payload")
+	var partialErr *CompositeError
+	require.False(t, errors.As(err, &partialErr), "the batch with no receipts is not a partial success")
+}
+
+func TestFormPersonalReceipts_SameNames(t *testing.T) {
+	m := newReceiptsTestManager(t, nil)
+
+	// the namesakes with no emails are both reported
+	payers := []pkg.Payer{
+		{CHILDFIO: "Иванов Иван", PersAcc: "1", Purpose: "питание", Sum: pkg.NewMoney(100, 0)},
+		{CHILDFIO: "Иванов Иван", PersAcc: "2", Purpose: "питание", Sum: pkg.NewMoney(200, 0)},
+	}
+	batch, err := m.formPersonalReceipts(context.Background(), payers, receiptsTestOrg)
+	require.NotNil(t, batch)
+	var mappingErr *EmailMappingError
+	require.ErrorAs(t, err, &mappingErr)
+	require.Equal(t, 2, mappingErr.FailedCount())
+	require.Contains(t, mappingErr.MapPayerReceipt, "1_Иванов_Иван")
+	require.Contains(t, mappingErr.MapPayerReceipt, "2_Иванов_Иван")
+}
+
+func TestFormPersonalReceipts_Canceled(t *testing.T) {
+	m := newReceiptsTestManager(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	payers := []pkg.Payer{{CHILDFIO: "Иванов Иван", PersAcc: "1", Purpose: "питание", Sum: pkg.NewMoney(100, 0)}}
+	batch, err := m.formPersonalReceipts(ctx, payers, receiptsTestOrg)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, batch)
+}
+
+func TestReceiptWorkersCount(t *testing.T) {
+	tests := []struct {
+		workers, jobs, want int
+	}{
+		{workers: 4, jobs: 10, want: 4},
+		{workers: 4, jobs: 2, want: 2},
+		{workers: 4, jobs: 0, want: 1},
+		{workers: 0, jobs: 1000, want: defaultReceiptWorkers},
+		{workers: -1, jobs: 1000, want: defaultReceiptWorkers},
+	}
+	for _, tt := range tests {
+		m := &Manager{}
+		m.SetReceiptWorkers(tt.workers)
+		require.Equal(t, tt.want, m.receiptWorkersCount(tt.jobs), "workers %d, jobs %d", tt.workers, tt.jobs)
+	}
+}
@@ -29,12 +29,12 @@ type FileStorage interface {
 	Store(filename, dir string, data []byte) (string, error)
 }
 
-// PayerParser extracts payer data from XLS files.
+// PayerParser extracts payer data from table files (spreadsheets or CSV).
 type PayerParser interface {
 	ParsePayers(path string) ([]pkg.Payer, error)
 }
 
-// OrgParser extracts organization data from XLS files.
+// OrgParser extracts organization data from table files (spreadsheets or CSV).
 type OrgParser interface {
 	ParseSettings(path string) (*pkg.Organization, error)
 }
@@ -58,7 +58,10 @@ func (defaultOrgParser) ParseSettings(path string) (*pkg.Organization, error) {
 }
 
 type ManagerIface interface {
-	ProcessPayersFile(ctx context.Context, filename string, data []byte) (map[string]string, int, error)
+	ProcessPayersFile(ctx context.Context, filename string, data []byte) (*model.ReceiptsBatch, error)
+	ReceiptsPrintFile(batch string, scope model.PrintScope) (string, error)
+	PreviewPayersFile(ctx context.Context, filename string, data []byte) (*model.PayersPreview, error)
+	InvalidateTemplateCache() (int, error)
 	HistoryService() HistoryService
 	SettingsService() SettingsService
 	MailService() MailService
@@ -75,9 +78,23 @@ type Manager struct {
 	payerParser PayerParser
 	orgParser   OrgParser
 
-	converterConfigKey string
+	// docConverter converts the Excel receipt pattern to PDF, used by TemplateSourceConverter
+	docConverter converter.DocumentConverter
+	// templateSource is the way the receipt template is made, TemplateSourceNative if not set
+	templateSource TemplateSource
 
 	pdfFontPath string
+	// receiptLayout describes the frames filled on the receipt template, pdf.DefaultLayout if not set
+	receiptLayout *pdf.Layout
+
+	// verifyQrCodes enables scanning of every generated QR code before it is put into the receipt
+	verifyQrCodes bool
+	// qrOptions are the settings of QR code images, qr.DefaultRenderOptions if not set
+	qrOptions qr.RenderOptions
+	// saveQrFiles enables writing QR code images to QrCodesDir for debugging
+	saveQrFiles bool
+	// receiptWorkers is the number of receipts generated at once, the number of CPUs if not set
+	receiptWorkers int
 
 	dirs struct {
 		BlankReceiptPath   string
@@ -85,6 +102,7 @@ type Manager struct {
 		PayersXlsDir       string
 		SentReceiptsDir    string
 		QrCodesDir         string
+		TemplateCacheDir   string
 	}
 }
 
@@ -92,6 +110,64 @@ func (m *Manager) SetPdfFontPath(path string) {
 	m.pdfFontPath = path
 }
 
+// TemplateSource is the way the PDF receipt template with organization credentials is made.
+type TemplateSource string
+
+const (
+	// TemplateSourceNative draws the template in Go, see pdf.GenerateReceiptTemplate
+	TemplateSourceNative TemplateSource = "native"
+	// TemplateSourceConverter fills the Excel pattern and converts it to PDF with the external converter API
+	TemplateSourceConverter TemplateSource = "converter"
+)
+
+// ParseTemplateSource parses the template source by its name: "native" or "converter" (case-insensitive).
+func ParseTemplateSource(source string) (TemplateSource, error) {
+	switch s := TemplateSource(strings.ToLower(strings.TrimSpace(source))); s {
+	case TemplateSourceNative, TemplateSourceConverter:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown receipt template source %q, expected %q or %q", source, TemplateSourceNative, TemplateSourceConverter)
+}
+
+// SetTemplateSource sets the way receipt templates are made. The converter source needs the converter of NewManager.
+func (m *Manager) SetTemplateSource(source TemplateSource) {
+	m.templateSource = source
+}
+
+// SetReceiptLayout sets the layout of the frames filled with payer's data on the receipt template.
+// The layout must match the template, so it is changed together with the template blank.
+func (m *Manager) SetReceiptLayout(layout *pdf.Layout) {
+	m.receiptLayout = layout
+}
+
+// SetVerifyQrCodes enables or disables the check that every generated QR code is scanned back to its payload.
+// A payer whose QR code fails the check gets no receipt and is reported in QrVerificationError.
+func (m *Manager) SetVerifyQrCodes(enabled bool) {
+	m.verifyQrCodes = enabled
+}
+
+// SetQrRenderOptions sets error correction level, module size and quiet zone of QR codes in the receipts.
+// The receipts always embed PNG images, so the format of the options is ignored.
+func (m *Manager) SetQrRenderOptions(opts qr.RenderOptions) {
+	m.qrOptions = opts
+}
+
+// SetSaveQrFiles enables or disables writing every generated QR code image to the QR codes directory.
+// Receipts do not need the files, they are useful only for debugging.
+func (m *Manager) SetSaveQrFiles(enabled bool) {
+	m.saveQrFiles = enabled
+}
+
+// qrRenderOptions returns the options receipt QR codes are rendered with
+func (m *Manager) qrRenderOptions() qr.RenderOptions {
+	opts := m.qrOptions
+	if opts == (qr.RenderOptions{}) {
+		opts = qr.DefaultRenderOptions()
+	}
+	opts.Format = qr.FormatPNG
+	return opts
+}
+
 func (m *Manager) Close() {
 	m.repo.CloseDB()
 }
@@ -109,7 +185,8 @@ func (m *Manager) HistoryService() HistoryService {
 }
 
 // NewManager constructor
-func NewManager(dsn string, converterConfig string, smtp model.SMTP) (*Manager, error) {
+// [conv] is used only by TemplateSourceConverter and may be nil if the templates are drawn natively.
+func NewManager(dsn string, conv converter.DocumentConverter, smtp model.SMTP) (*Manager, error) {
 	// Ensure directories exist
 	if err := model.EnsureTmpDirectories(); err != nil {
 		return nil, fmt.Errorf("failed to create tmp directories: %w", err)
@@ -121,31 +198,33 @@ func NewManager(dsn string, converterConfig string, smtp model.SMTP) (*Manager,
 	}
 
 	m := &Manager{
-		History:            NewHistoryService(repository.NewHistoryRepository(repo)),
-		Settings:           NewSettingsService(repository.NewSettingsRepository(repo)),
-		Mail:               NewMailService(smtp),
-		repo:               repo,
-		converterConfigKey: converterConfig,
-		pdfFontPath:        pdf.DefaultFontPath,
+		History:      NewHistoryService(repository.NewHistoryRepository(repo)),
+		Settings:     NewSettingsService(repository.NewSettingsRepository(repo)),
+		Mail:         NewMailService(smtp),
+		repo:         repo,
+		docConverter: conv,
+		pdfFontPath:  pdf.DefaultFontPath,
 		dirs: struct {
 			BlankReceiptPath   string
 			ReceiptPatternsDir string
 			PayersXlsDir       string
 			SentReceiptsDir    string
 			QrCodesDir         string
+			TemplateCacheDir   string
 		}{
 			BlankReceiptPath:   model.BlankReceiptPath,
 			ReceiptPatternsDir: model.ReceiptPatternsDir,
 			PayersXlsDir:       model.PayersXlsDir,
 			SentReceiptsDir:    model.SentReceiptsDir,
 			QrCodesDir:         model.QrCodesDir,
+			TemplateCacheDir:   model.TemplateCacheDir,
 		},
 	}
 
 	// inject defaults
 	m.storage = defaultFileStorage{}
-	m.payerParser = defaultPayerParser{}
-	m.orgParser = defaultOrgParser{}
+	m.payerParser = newFormatPayerParser()
+	m.orgParser = newFormatOrgParser()
 
 	// since now SenderEmail passes only in `smtp` in this constructor, it never added to DB
 	// so add it now
@@ -157,18 +236,20 @@ func NewManager(dsn string, converterConfig string, smtp model.SMTP) (*Manager,
 	return m, nil
 }
 
-// ProcessPayersFile handles the uploaded xls/xlsx file bytes: stores the file, parses payers and settings,
-// generates receipts PDF files, sends emails with receipts and returns mapping email->pdfpath.
+// ProcessPayersFile handles the uploaded table file bytes (spreadsheet or CSV): stores the file, parses payers and settings,
+// generates receipts PDF files, sends emails with receipts and returns the batch with mapping email->pdf paths
+// and the print files of the receipts, see ReceiptsPrintFile.
 // It performs validation, logs every stage and preserves error kinds from lower-level packages.
-// Return a non-nil CompositeError containing one or both EmailSendingError and EmailMappingError, or regular error.
-func (m *Manager) ProcessPayersFile(ctx context.Context, filename string, data []byte) (map[string]string, int, error) {
+// Return a non-nil CompositeError containing EmailSendingError, EmailMappingError, QrVerificationError
+// or ReceiptGenerationError, or regular error.
+func (m *Manager) ProcessPayersFile(ctx context.Context, filename string, data []byte) (*model.ReceiptsBatch, error) {
 	start := time.Now()
 	logger.Info("ProcessPayersFile started", zap.String("filename", filename))
 
 	// validate settings exist and are OK
 	if err := m.validateBeforeProcessFile(ctx); err != nil {
 		logger.Warn("validation before processing failed", zap.Error(err))
-		return nil, 0, errs.Wrap(errs.Validation, "validation before processing failed", err)
+		return nil, errs.Wrap(errs.Validation, "validation before processing failed", err)
 	}
 
 	settings := m.Settings.GetCache()
@@ -177,7 +258,7 @@ func (m *Manager) ProcessPayersFile(ctx context.Context, filename string, data [
 	filePath, err := m.storage.Store(filename, m.dirs.PayersXlsDir, data)
 	if err != nil {
 		logger.Error("failed to store uploaded file", zap.String("path", filePath), zap.Error(err))
-		return nil, 0, errs.Wrap(errs.System, "failed to store uploaded file "+filePath, err)
+		return nil, errs.Wrap(errs.System, "failed to store uploaded file "+filePath, err)
 	}
 	logger.Info("stored uploaded file", zap.String("path", filePath))
 
@@ -188,7 +269,7 @@ func (m *Manager) ProcessPayersFile(ctx context.Context, filename string, data [
 	if err != nil {
 		logger.Error("failed to parse payers from xls", zap.String("path", filePath), zap.Error(err))
 		// preserve original error kind if present, that is already errs.System or errs.User
-		return nil, 0, err
+		return nil, err
 	}
 
 	// parse organization settings from the same uploaded file (or separate)
@@ -196,49 +277,61 @@ func (m *Manager) ProcessPayersFile(ctx context.Context, filename string, data [
 	if err != nil {
 		logger.Error("failed to parse settings from xls", zap.String("path", filePath), zap.Error(err))
 		// preserve original error kind if present, that is already errs.System or errs.User
-		return nil, 0, err
+		return nil, err
 	}
 
 	// record file in history
 	if err := m.History.AddRecord(ctx, model.File{FileName: storedFileName, FileData: data}); err != nil {
 		logger.Error("failed to add history record", zap.Error(err))
 		// Wrap system error and return
-		return nil, 0, errs.Wrap(errs.System, "history.AddRecord: %w", err)
+		return nil, errs.Wrap(errs.System, "history.AddRecord: %w", err)
 	}
 
-	// formPersonalReceipts may return EmailMappingError or system error
-	receiptsMap, err := m.formPersonalReceipts(ctx, payers, *org)
-	var missedEmailsErr *EmailMappingError
+	// formPersonalReceipts may return CompositeError of EmailMappingError, QrVerificationError and
+	// ReceiptGenerationError, ReceiptGenerationError of all payers or system error
+	batch, err := m.formPersonalReceipts(ctx, payers, *org)
+	var partialErr *CompositeError
+	var generationErr *ReceiptGenerationError
 	errorsCollected := []error{}
 
 	if err != nil {
-		if errors.As(err, &missedEmailsErr) {
-			errorsCollected = append(errorsCollected, missedEmailsErr)
+		if errors.As(err, &partialErr) {
+			errorsCollected = append(errorsCollected, partialErr.Errors...)
+		} else if errors.As(err, &generationErr) {
+			logger.Warn("no receipt is generated, e.g. settings do not fit the QR payload", zap.Error(err))
+			return nil, generationErr // user error, preserve it
 		} else {
 			logger.Error("failed to form personal receipts", zap.Error(err))
-			return nil, 0, errs.Wrap(errs.System, "formPersonalReceipts: ", err)
+			return nil, errs.Wrap(errs.System, "formPersonalReceipts: ", err)
 		}
 	}
 
-	// exclude payers that mentioned in emails map, but not present in actual payers list
-	emailsMap := settings.Emails
+	// exclude payers that mentioned in emails map, but not present in actual payers list,
+	// and payers left without receipt (see QrVerificationError and ReceiptGenerationError)
 	var emailsList []string
 	for _, p := range payers {
-		if email, ok := emailsMap[strings.ToLower(p.CHILDFIO)]; ok {
-			emailsList = append(emailsList, email)
+		for _, email := range payerEmails(settings.Emails, p) {
+			if len(batch.Receipts[email]) > 0 {
+				emailsList = append(emailsList, email)
+			}
 		}
 	}
 
+	if len(emailsList) == 0 && len(errorsCollected) > 0 {
+		// no receipt to send, all payers are reported in the collected errors
+		return batch, &CompositeError{Errors: errorsCollected}
+	}
+
 	mails := model.Mail{
 		Subject:         model.MailDefaultSubject,
 		Body:            model.MailDefaultBody,
 		To:              emailsList,
 		From:            m.Mail.GetSenderEmail(),
-		AttachmentPaths: receiptsMap,
+		AttachmentPaths: batch.Receipts,
 	}
 
 	// SendMails may return EmailSendingError or system error
-	sentCount, err := m.Mail.SendMails(ctx, mails)
+	batch.SentCount, err = m.Mail.SendMails(ctx, mails)
 	var failedMailsErr *EmailSendingError
 
 	if err != nil {
@@ -246,37 +339,129 @@ func (m *Manager) ProcessPayersFile(ctx context.Context, filename string, data [
 			errorsCollected = append(errorsCollected, failedMailsErr)
 		} else {
 			logger.Error("failed to send some emails", zap.Error(err))
-			return nil, 0, errs.Wrap(errs.System, "MailService.SendMails()", err)
+			return nil, errs.Wrap(errs.System, "MailService.SendMails()", err)
 		}
 	}
 
 	if len(errorsCollected) > 0 {
 		// Return composite error containing all partial errors
-		return receiptsMap, sentCount, &CompositeError{Errors: errorsCollected}
+		return batch, &CompositeError{Errors: errorsCollected}
 	}
 
 	// No errors found, full success
 	logger.Info("ProcessPayersFile completed",
 		zap.String("filename", filename),
 		zap.Int("payers_count", len(payers)),
-		zap.Int("mails_sent", sentCount),
+		zap.Int("mails_sent", batch.SentCount),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+
+	return batch, nil
+
+}
+
+// PreviewPayersFile is the dry run of ProcessPayersFile: it parses payers and settings from the uploaded file
+// and returns them together with QR payload and matched email of each payer, and the list of payers with no email.
+// Nothing is generated, sent or recorded in history; the uploaded file is removed after parsing.
+func (m *Manager) PreviewPayersFile(ctx context.Context, filename string, data []byte) (*model.PayersPreview, error) {
+	start := time.Now()
+	logger.Info("PreviewPayersFile started", zap.String("filename", filename))
+
+	// fetch settings into cache; missing emails are not an error here, such payers are reported as unmatched
+	settings, err := m.Settings.GetSettings(ctx)
+	if err != nil {
+		logger.Warn("Settings.GetSettings failed", zap.Error(err))
+		return nil, errs.Wrap(errs.System, "Settings.GetSettings failed", err)
+	}
+
+	// store uploaded file in a temporary directory, since parsers work with files
+	tmpDir, err := os.MkdirTemp("", "payers-preview-*")
+	if err != nil {
+		logger.Error("failed to create temporary directory", zap.Error(err))
+		return nil, errs.WrapIOError("create temporary directory", os.TempDir(), err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	filePath, err := m.storage.Store(filename, tmpDir, data)
+	if err != nil {
+		logger.Error("failed to store uploaded file", zap.String("path", filePath), zap.Error(err))
+		return nil, errs.Wrap(errs.System, "failed to store uploaded file "+filePath, err)
+	}
+
+	payers, err := m.payerParser.ParsePayers(filePath)
+	if err != nil {
+		logger.Error("failed to parse payers", zap.String("path", filePath), zap.Error(err))
+		return nil, err // preserve original error kind
+	}
+
+	org, err := m.orgParser.ParseSettings(filePath)
+	if err != nil {
+		logger.Error("failed to parse settings", zap.String("path", filePath), zap.Error(err))
+		return nil, err // preserve original error kind
+	}
+
+	preview := &model.PayersPreview{
+		Organization:    *org,
+		Payers:          make([]model.PayerPreview, 0, len(payers)),
+		UnmatchedPayers: []string{},
+	}
+
+	qrCreator := qr.NewQrPattern(*org)
+	for _, payer := range payers {
+		qrData, err := qrCreator.GetPayersQrDataString(payer)
+		if err != nil {
+			logger.Warn("failed to build payer's QR data", zap.String("pers_acc", payer.PersAcc), zap.Error(err))
+			return nil, err // qr.PayloadError, user should fix the settings
+		}
+
+		emails := payerEmails(settings.Emails, payer)
+		if len(emails) == 0 {
+			preview.UnmatchedPayers = append(preview.UnmatchedPayers, payer.CHILDFIO)
+		}
+
+		preview.Payers = append(preview.Payers, model.PayerPreview{
+			Payer:  payer,
+			QrData: qrData,
+			Emails: emails,
+		})
+	}
+
+	logger.Info("PreviewPayersFile completed",
+		zap.String("filename", filename),
+		zap.Int("payers_count", len(payers)),
+		zap.Int("unmatched_count", len(preview.UnmatchedPayers)),
 		zap.Duration("elapsed", time.Since(start)),
 	)
 
-	return receiptsMap, sentCount, nil
+	return preview, nil
+}
 
+// payerEmails returns all emails mapped to the payer's full name, or nil if there is no such mapping.
+// Names in the emails map are lower-cased and trimmed when the emails file is parsed.
+func payerEmails(emails map[string][]string, payer pkg.Payer) []string {
+	return emails[strings.ToLower(strings.TrimSpace(payer.CHILDFIO))]
 }
 
-// formPersonalReceipts generates PDF receipts for each payer and returns map of receiver email -> pdf path.
+// formPersonalReceipts generates PDF receipts for each payer and returns the batch with map of receiver email -> pdf paths.
+// Payer with several emails has all of them in the map with the same receipt,
+// and an email shared by several payers (e.g. a parent of siblings) gets all their receipts.
 // It does NOT send the emails; sending is responsibility of Mail service.
 // If there are missed emails for some payers, they are not included in the result map, but custom EmailMappingError returned also.
-func (m *Manager) formPersonalReceipts(ctx context.Context, payers []pkg.Payer, org pkg.Organization) (map[string]string, error) {
+// If QR codes verification is enabled (see SetVerifyQrCodes), payers whose QR code can not be scanned back
+// get no receipt and are reported in QrVerificationError. Both errors are returned in CompositeError.
+// The receipts are generated by the workers (see SetReceiptWorkers) and are kept in the payers order.
+// A payer whose receipt fails is reported in ReceiptGenerationError of CompositeError, the other payers get their receipts;
+// if no receipt is generated at all, the ReceiptGenerationError of all payers is returned alone.
+// The generated receipts are also merged into the print files of the batch, see writePrintFiles.
+func (m *Manager) formPersonalReceipts(ctx context.Context, payers []pkg.Payer, org pkg.Organization) (*model.ReceiptsBatch, error) {
 	start := time.Now()
 
-	receiptsMap := make(map[string]string) // map to be returned, `payer email` -> `personal pdf receipt path`
+	receiptsMap := make(map[string][]string) // map to be returned, `payer email` -> `personal pdf receipts paths`
 
 	// error type string for metrics
 	var errorType string
+	// the receipts of some payers failed, but the batch is generated
+	var partial bool
 
 	// defer metrics updating, when the occured error's type will be known
 	defer func() {
@@ -284,6 +469,8 @@ func (m *Manager) formPersonalReceipts(ctx context.Context, payers []pkg.Payer,
 		var status string
 		if errorType == "" {
 			status = "success"
+		} else if partial {
+			status = "partial"
 		} else {
 			status = "failed"
 		}
@@ -295,12 +482,19 @@ func (m *Manager) formPersonalReceipts(ctx context.Context, payers []pkg.Payer,
 
 	logger.Info("formPersonalReceipts started", zap.Int("payers_count", len(payers)))
 
-	templatePath, err := m.prepareReceiptTemplate(org)
+	templatePath, err := m.prepareReceiptTemplate(ctx, org)
 	if err != nil {
 		errorType = "prepare_pdf_template"
 		logger.Error("prepareReceiptTemplate failed", zap.Error(err))
 		return nil, err // system error
 	}
+	// the template is parsed once, the canvases of the payers only draw over it
+	template, err := pdf.LoadReceiptTemplate(templatePath, m.pdfFontPath, m.receiptLayout, false) // debugMode true if logger present
+	if err != nil {
+		errorType = "load_pdf_template"
+		logger.Error("failed to load receipt template", zap.String("template", templatePath), zap.Error(err))
+		return nil, err
+	}
 
 	receiptsDir, err := createNowDir(m.dirs.SentReceiptsDir)
 	if err != nil {
@@ -309,114 +503,195 @@ func (m *Manager) formPersonalReceipts(ctx context.Context, payers []pkg.Payer,
 		return nil, err // system error
 	}
 
-	qrDir, err := createNowDir(m.dirs.QrCodesDir)
-	if err != nil {
-		errorType = "create_dir"
-		logger.Error("failed to create qr dir", zap.Error(err))
-		return nil, err // system error
+	var qrDir string
+	if m.saveQrFiles {
+		qrDir, err = createNowDir(m.dirs.QrCodesDir)
+		if err != nil {
+			errorType = "create_dir"
+			logger.Error("failed to create qr dir", zap.Error(err))
+			return nil, err // system error
+		}
 	}
 
-	qrCreator := qr.NewQrPattern(org)
-
-	missedPayers := make(map[string]string)
+	generator := &receiptGenerator{
+		template:    template,
+		qrCreator:   qr.NewQrPattern(org),
+		qrOptions:   m.qrRenderOptions(),
+		receiptsDir: receiptsDir,
+		qrDir:       qrDir,
+		verifyQr:    m.verifyQrCodes,
+	}
 
-	// iterate payers
+	named := make([]pkg.Payer, 0, len(payers))
 	for _, payer := range payers {
-		select {
-		case <-ctx.Done():
-			logger.Warn("formPersonalReceipts aborted: context canceled")
-			errorType = "context_closed"
-			return nil, ctx.Err()
-		default:
-		}
-
-		payerFileName := strings.ReplaceAll(strings.TrimSpace(payer.CHILDFIO), " ", "_")
-		if payerFileName == "" {
+		if strings.TrimSpace(payer.CHILDFIO) == "" {
 			logger.Warn("empty payer name, skipping", zap.Any("payer", payer))
 			continue
 		}
+		named = append(named, payer)
+	}
 
-		// create canvas per-payer (pdf object wraps the template)
-		canvas, err := pdf.NewCanvasFromTemplate(templatePath, m.pdfFontPath, false) // debugMode true if logger present
-		if err != nil {
-			errorType = "create_pdf_canvas"
-			logger.Error("failed to create canvas from template", zap.Error(err))
-			return nil, err
-		}
-
-		// qr file path
-		qrFile := filepath.Join(qrDir, payerFileName+".jpg")
-		qrString := qrCreator.GetPayersQrDataString(payer)
-		if err := qrCreator.GenerateQRCode(qrString, qrFile); err != nil {
-			errorType = "generate_qr_code"
-			logger.Error("failed to generate qr", zap.String("qrFile", qrFile), zap.Error(err))
-			return nil, err
-		}
+	workers := m.receiptWorkersCount(len(named))
+	logger.Info("generating receipts", zap.Int("payers_count", len(named)), zap.Int("workers", workers))
+	results := generator.generateReceipts(ctx, named, workers)
+	if ctx.Err() != nil {
+		logger.Warn("formPersonalReceipts aborted: context canceled")
+		errorType = "context_closed"
+		return nil, ctx.Err()
+	}
 
-		qrImgBytes, err := os.ReadFile(qrFile)
-		if err != nil {
-			errorType = "read_qr_code"
-			logger.Error("failed to read qr image", zap.String("qrFile", qrFile), zap.Error(err))
-			return nil, err
+	// the payers are reported by their receipt names, since payers may have the same names
+	missedPayers := make(map[string]string)
+	unverifiedPayers := make(map[string]string)
+	// payers whose receipts failed, the other payers get their receipts
+	failedPayers := make(map[string]string)
+	// receipts of the print files in the payers order
+	printReceipts := make(map[model.PrintScope][]string)
+
+	for i, result := range results {
+		payer := named[i]
+		switch {
+		case result.err != nil:
+			if errorType == "" {
+				errorType = result.errorType
+			}
+			failedPayers[receiptFileName(payer)] = result.err.Error()
+			continue
+		case result.unverified != "":
+			unverifiedPayers[receiptFileName(payer)] = result.unverified
+			continue
 		}
+		pdfFile := result.pdfFile
 
-		if err := canvas.Fill(payer, qrImgBytes); err != nil {
-			errorType = "fill_payer_info"
-			logger.Error("canvas.Fill error", zap.Error(err))
-			return nil, err
+		// the same receipt is sent to all emails of the payer
+		emails := payerEmails(m.Settings.GetCache().Emails, payer)
+		if len(emails) == 0 {
+			missedPayers[receiptFileName(payer)] = pdfFile
+			printReceipts[model.PrintScopeUnmapped] = append(printReceipts[model.PrintScopeUnmapped], pdfFile)
 		}
-
-		pdfFile := filepath.Join(receiptsDir, payerFileName+".pdf")
-		if err := canvas.Save(pdfFile); err != nil {
-			errorType = "save_pdf_receipt"
-			logger.Error("failed to save pdf", zap.String("pdf", pdfFile), zap.Error(err))
-			return nil, err
+		printReceipts[model.PrintScopeAll] = append(printReceipts[model.PrintScopeAll], pdfFile)
+		for _, email := range emails {
+			receiptsMap[email] = append(receiptsMap[email], pdfFile)
 		}
+	}
 
-		payerEmail := m.Settings.GetCache().Emails[strings.ToLower(strings.TrimSpace(payer.CHILDFIO))]
-		if payerEmail == "" {
-			missedPayers[payer.CHILDFIO] = pdfFile
-		} else {
-			receiptsMap[payerEmail] = pdfFile
-		}
+	if len(failedPayers) > 0 && len(printReceipts[model.PrintScopeAll]) == 0 && len(unverifiedPayers) == 0 {
+		// no receipt is generated, e.g. the organization settings do not fit the QR payload,
+		// so the batch fails with all the payers reported
+		generationErr := &ReceiptGenerationError{MapPayerCause: failedPayers}
+		logger.Error("formPersonalReceipts failed for all payers", zap.Int("failed", len(failedPayers)), zap.Error(generationErr))
+		return nil, generationErr
 	}
 
-	var missedErr error
+	var partialErrs []error
+	if len(failedPayers) > 0 {
+		partial = true
+		generationErr := &ReceiptGenerationError{MapPayerCause: failedPayers}
+		partialErrs = append(partialErrs, generationErr)
+		logger.Warn("formPersonalReceipts failed to generate receipts of some payers", zap.Error(generationErr))
+	}
 	if len(missedPayers) > 0 {
-		missedErr = &EmailMappingError{MapPayerReceipt: missedPayers}
-		logger.Warn("formPersonalReceipts missed emails for some payers", zap.Error(err))
+		missedErr := &EmailMappingError{MapPayerReceipt: missedPayers}
+		partialErrs = append(partialErrs, missedErr)
+		logger.Warn("formPersonalReceipts missed emails for some payers", zap.Error(missedErr))
+	}
+	if len(unverifiedPayers) > 0 {
+		verifyErr := &QrVerificationError{MapPayerCause: unverifiedPayers}
+		partialErrs = append(partialErrs, verifyErr)
+		logger.Warn("formPersonalReceipts skipped payers with unscannable qr codes", zap.Error(verifyErr))
+	}
+
+	batch := &model.ReceiptsBatch{
+		ID:          filepath.Base(receiptsDir),
+		Receipts:    receiptsMap,
+		PrintScopes: m.writePrintFiles(receiptsDir, printReceipts),
 	}
 
 	logger.Info("formPersonalReceipts completed", zap.Int("generated", len(receiptsMap)), zap.Duration("elapsed", time.Since(start)))
-	return receiptsMap, missedErr
+	if len(partialErrs) > 0 {
+		return batch, &CompositeError{Errors: partialErrs}
+	}
+	return batch, nil
 }
 
-// prepareReceiptTemplate creates XLSX based template with organization params and converts it to PDF using converter.
-func (m *Manager) prepareReceiptTemplate(org pkg.Organization) (string, error) {
+// fileNameReplacer replaces characters that are not allowed or inconvenient in file names.
+var fileNameReplacer = strings.NewReplacer(" ", "_", "/", "_", "\\", "_")
+
+// receiptFileName returns the base name (without extension) of the payer's receipt and QR code files, e.g. "12345_Иванов_Иван".
+// Personal account makes it unique, since payers may have the same names, and the name keeps it readable for the receiver.
+func receiptFileName(payer pkg.Payer) string {
+	return fileNameReplacer.Replace(strings.TrimSpace(payer.PersAcc) + "_" + strings.TrimSpace(payer.CHILDFIO))
+}
+
+// prepareReceiptTemplate returns PDF template of the receipt with organization params.
+// The template is taken from the cache, or is made and cached: drawn natively, or XLSX based template
+// is converted to PDF using converter, see TemplateSource.
+func (m *Manager) prepareReceiptTemplate(ctx context.Context, org pkg.Organization) (string, error) {
 	start := time.Now()
-	logger.Info("prepareReceiptTemplate started")
+	logger.Info("prepareReceiptTemplate started", zap.String("source", string(m.templateSource)))
+
+	pdfPath, cached, err := m.cachedReceiptTemplate(org)
+	if err != nil {
+		logger.Error("cachedReceiptTemplate failed", zap.Error(err))
+		return "", err
+	}
+	if cached {
+		metrics.TemplateCacheTotal.WithLabelValues("hit").Inc()
+		logger.Info("prepareReceiptTemplate completed from cache", zap.String("pdf", pdfPath), zap.Duration("elapsed", time.Since(start)))
+		return pdfPath, nil
+	}
+	metrics.TemplateCacheTotal.WithLabelValues("miss").Inc()
+
+	// the template is made under a temporary name, so a concurrent batch never takes a partial file from the cache
+	tmpPath := fmt.Sprintf("%s.%d.tmp", pdfPath, time.Now().UnixNano())
+	if m.templateSource == TemplateSourceConverter {
+		err = m.convertReceiptTemplate(ctx, tmpPath, org)
+	} else {
+		err = m.drawReceiptTemplate(tmpPath, org)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := os.Rename(tmpPath, pdfPath); err != nil {
+		os.Remove(tmpPath)
+		return "", errs.WrapIOError("store receipt template in cache", pdfPath, err)
+	}
+
+	logger.Info("prepareReceiptTemplate completed", zap.String("pdf", pdfPath), zap.Duration("elapsed", time.Since(start)))
+	return pdfPath, nil
+}
+
+// drawReceiptTemplate draws PDF template with organization params without external services.
+func (m *Manager) drawReceiptTemplate(pdfPath string, org pkg.Organization) error {
+	if err := pdf.GenerateReceiptTemplate(pdfPath, m.pdfFontPath, org); err != nil {
+		logger.Error("GenerateReceiptTemplate failed", zap.Error(err))
+		return errs.Wrap(errs.System, "GenerateReceiptTemplate failed", err)
+	}
+	return nil
+}
+
+// convertReceiptTemplate creates XLSX based template with organization params and converts it to PDF using converter.
+func (m *Manager) convertReceiptTemplate(ctx context.Context, pdfPath string, org pkg.Organization) error {
+	if m.docConverter == nil {
+		return errs.New(errs.System, "document converter is not configured")
+	}
 
 	xlsPath, err := xls.FillOrganizationParamsInReceipt(m.dirs.BlankReceiptPath, m.dirs.ReceiptPatternsDir, org)
 	if err != nil {
 		logger.Error("FillOrganizationParamsInReceipt failed", zap.Error(err))
-		return "", errs.Wrap(errs.System, "FillOrganizationParamsInReceipt failed", err)
+		return errs.Wrap(errs.System, "FillOrganizationParamsInReceipt failed", err)
 	}
 
-	_, xlsFilename := filepath.Split(xlsPath)
-	xlsCleanFilename := strings.TrimSuffix(xlsFilename, filepath.Ext(xlsFilename))
-	pdfPath := filepath.Join(m.dirs.ReceiptPatternsDir, xlsCleanFilename+".pdf")
-
 	convStart := time.Now()
 	logger.Info("converting xls to pdf", zap.String("xls", xlsPath), zap.String("pdf", pdfPath))
-	if err := converter.ExcelToPdf(xlsPath, pdfPath, m.converterConfigKey); err != nil {
+	if err := m.docConverter.ExcelToPdf(ctx, xlsPath, pdfPath); err != nil {
 		logger.Error("ExcelToPdf failed", zap.Error(err), zap.Duration("elapsed", time.Since(convStart)))
-		return "", errs.Wrap(errs.System, "ExcelToPdf failed", err)
+		return errs.Wrap(errs.System, "ExcelToPdf failed", err)
 	}
 
 	logger.Info("conversion completed", zap.Duration("elapsed", time.Since(convStart)))
-
-	logger.Info("prepareReceiptTemplate completed", zap.Duration("elapsed", time.Since(start)))
-	return pdfPath, nil
+	return nil
 }
 
 // validateBeforeProcessFile ensures settings exist and are usable prior to processing.
@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"li-acc/internal/model"
+	"li-acc/pkg/converter"
 	"os"
 	"path/filepath"
 	"testing"
@@ -49,9 +50,9 @@ func TestIntegration_ProcessPayersFile_FileOrchestration(t *testing.T) {
 	// MOCK: Settings service with predefined email mappings (already tested at repo layer)
 	mockSettings := &mockSettingsService{
 		settings: model.Settings{
-			Emails: map[string]string{
-				"иванов иван": "john@example.com",
-				"петров петр": "jane@example.com",
+			Emails: map[string][]string{
+				"иванов иван": {"john@example.com"},
+				"петров петр": {"jane@example.com"},
 			},
 			SenderEmail: "sender@example.com",
 		},
@@ -71,33 +72,39 @@ func TestIntegration_ProcessPayersFile_FileOrchestration(t *testing.T) {
 
 	// Create Manager with REAL file operations, MOCK database/email
 	m := &Manager{
-		History:            mockHistory,
-		Settings:           mockSettings,
-		Mail:               mockMail,
-		storage:            defaultFileStorage{}, // REAL file operations
-		payerParser:        defaultPayerParser{}, // REAL XLS parsing
-		orgParser:          defaultOrgParser{},   // REAL XLS parsing
-		converterConfigKey: converterKey,
-		pdfFontPath:        pdfFontPath,
+		History:        mockHistory,
+		Settings:       mockSettings,
+		Mail:           mockMail,
+		storage:        defaultFileStorage{}, // REAL file operations
+		payerParser:    defaultPayerParser{}, // REAL XLS parsing
+		orgParser:      defaultOrgParser{},   // REAL XLS parsing
+		docConverter:   converter.NewConverter(converter.ApiUrl, converterKey),
+		templateSource: TemplateSourceConverter,
+		pdfFontPath:    pdfFontPath,
 		dirs: struct {
 			BlankReceiptPath   string
 			ReceiptPatternsDir string
 			PayersXlsDir       string
 			SentReceiptsDir    string
 			QrCodesDir         string
+			TemplateCacheDir   string
 		}{
 			BlankReceiptPath:   "./testdata/blank_receipt_pattern.xls",
 			ReceiptPatternsDir: outDir,
 			PayersXlsDir:       outDir,
 			SentReceiptsDir:    outDir,
 			QrCodesDir:         outDir,
+			TemplateCacheDir:   outDir,
 		},
 	}
 
 	// === ACT: Execute the orchestration workflow ===
 	startTime := time.Now()
-	receiptsMap, sentCount, err := m.ProcessPayersFile(ctx, "real_case_valid.xlsm", data)
+	batch, err := m.ProcessPayersFile(ctx, "real_case_valid.xlsm", data)
 	elapsed := time.Since(startTime)
+	require.NotNil(t, batch, err)
+	receiptsMap, sentCount := batch.Receipts, batch.SentCount
+	require.Contains(t, batch.PrintScopes, model.PrintScopeAll, "all receipts are merged for print")
 
 	require.Equal(t, sentCount, mockMail.sentCount)
 
@@ -109,24 +116,27 @@ func TestIntegration_ProcessPayersFile_FileOrchestration(t *testing.T) {
 	t.Logf("ProcessPayersFile completed in %v, generated %d receipts", elapsed, len(receiptsMap))
 
 	// 2. Receipt files were actually created with proper structure
-	for email, pdfPath := range receiptsMap {
-		t.Logf("Verifying receipt for %s at %s", email, pdfPath)
-
-		// File exists
-		require.FileExists(t, pdfPath, "PDF should be created at %s", pdfPath)
-
-		// PDF is in output directory structure
-		assert.Contains(t, pdfPath, outDir, "PDF should be in output directory")
-
-		// Path contains timestamp (orchestration creates timestamped subdirs)
-		assert.Regexp(t, `\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}`, pdfPath,
-			"Path should contain timestamp from createNowDir()")
-
-		// PDF has content and valid format
-		pdfData, err := os.ReadFile(pdfPath)
-		require.NoError(t, err)
-		assert.Greater(t, len(pdfData), 1000, "PDF should have substantial content")
-		assert.True(t, startsWithPDFMagicBytes(pdfData), "Should be valid PDF format")
+	for email, pdfPaths := range receiptsMap {
+		require.NotEmpty(t, pdfPaths, "Every email should have at least one receipt")
+		for _, pdfPath := range pdfPaths {
+			t.Logf("Verifying receipt for %s at %s", email, pdfPath)
+
+			// File exists
+			require.FileExists(t, pdfPath, "PDF should be created at %s", pdfPath)
+
+			// PDF is in output directory structure
+			assert.Contains(t, pdfPath, outDir, "PDF should be in output directory")
+
+			// Path contains timestamp (orchestration creates timestamped subdirs)
+			assert.Regexp(t, `\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}`, pdfPath,
+				"Path should contain timestamp from createNowDir()")
+
+			// PDF has content and valid format
+			pdfData, err := os.ReadFile(pdfPath)
+			require.NoError(t, err)
+			assert.Greater(t, len(pdfData), 1000, "PDF should have substantial content")
+			assert.True(t, startsWithPDFMagicBytes(pdfData), "Should be valid PDF format")
+		}
 	}
 
 	// 3. Verify uploaded file was stored with timestamp
@@ -203,17 +213,19 @@ func TestIntegration_ProcessPayersFile_ValidationFailure(t *testing.T) {
 			PayersXlsDir       string
 			SentReceiptsDir    string
 			QrCodesDir         string
+			TemplateCacheDir   string
 		}{
 			BlankReceiptPath:   "./testdata/blank_receipt_pattern.xls",
 			ReceiptPatternsDir: outDir,
 			PayersXlsDir:       outDir,
 			SentReceiptsDir:    outDir,
 			QrCodesDir:         outDir,
+			TemplateCacheDir:   outDir,
 		},
 	}
 
 	// ACT: Should fail at validation
-	_, _, err = m.ProcessPayersFile(ctx, "test.xlsm", data)
+	_, err = m.ProcessPayersFile(ctx, "test.xlsm", data)
 
 	// ASSERT: Error returned, no files created
 	require.Error(t, err)
@@ -239,8 +251,8 @@ func TestIntegration_ProcessPayersFile_HistoryFailure(t *testing.T) {
 
 	mockSettings := &mockSettingsService{
 		settings: model.Settings{
-			Emails: map[string]string{
-				"john doe": "john@example.com",
+			Emails: map[string][]string{
+				"john doe": {"john@example.com"},
 			},
 			SenderEmail: "sender@example.com",
 		},
@@ -264,17 +276,19 @@ func TestIntegration_ProcessPayersFile_HistoryFailure(t *testing.T) {
 			PayersXlsDir       string
 			SentReceiptsDir    string
 			QrCodesDir         string
+			TemplateCacheDir   string
 		}{
 			BlankReceiptPath:   "./testdata/blank_receipt_pattern.xls",
 			ReceiptPatternsDir: outDir,
 			PayersXlsDir:       outDir,
 			SentReceiptsDir:    outDir,
 			QrCodesDir:         outDir,
+			TemplateCacheDir:   outDir,
 		},
 	}
 
 	// ACT: Should fail at history recording
-	_, _, err = m.ProcessPayersFile(ctx, "test.xlsm", data)
+	_, err = m.ProcessPayersFile(ctx, "test.xlsm", data)
 
 	// ASSERT: Error propagated correctly
 	require.Error(t, err)
@@ -317,8 +331,8 @@ func TestIntegration_ProcessPayersFile_EmailMappingError(t *testing.T) {
 	// MOCK settings — missing one email intentionally
 	mockSettings := &mockSettingsService{
 		settings: model.Settings{
-			Emails: map[string]string{
-				"иванов иван": "john@example.com",
+			Emails: map[string][]string{
+				"иванов иван": {"john@example.com"},
 				// "петров петр": missing -> triggers EmailMappingError
 			},
 			SenderEmail: "sender@example.com",
@@ -328,30 +342,35 @@ func TestIntegration_ProcessPayersFile_EmailMappingError(t *testing.T) {
 	mockMail := &mockMailService{sentCount: 1}
 
 	m := &Manager{
-		History:            &mockHistoryService{},
-		Settings:           mockSettings,
-		Mail:               mockMail,
-		storage:            defaultFileStorage{},
-		payerParser:        defaultPayerParser{},
-		orgParser:          defaultOrgParser{},
-		converterConfigKey: converterKey,
-		pdfFontPath:        pdfFontPath,
+		History:        &mockHistoryService{},
+		Settings:       mockSettings,
+		Mail:           mockMail,
+		storage:        defaultFileStorage{},
+		payerParser:    defaultPayerParser{},
+		orgParser:      defaultOrgParser{},
+		docConverter:   converter.NewConverter(converter.ApiUrl, converterKey),
+		templateSource: TemplateSourceConverter,
+		pdfFontPath:    pdfFontPath,
 		dirs: struct {
 			BlankReceiptPath   string
 			ReceiptPatternsDir string
 			PayersXlsDir       string
 			SentReceiptsDir    string
 			QrCodesDir         string
+			TemplateCacheDir   string
 		}{
 			BlankReceiptPath:   "./testdata/blank_receipt_pattern.xls",
 			ReceiptPatternsDir: outDir,
 			PayersXlsDir:       outDir,
 			SentReceiptsDir:    outDir,
 			QrCodesDir:         outDir,
+			TemplateCacheDir:   outDir,
 		},
 	}
 
-	receiptsMap, sentCount, err := m.ProcessPayersFile(ctx, "real_case_valid.xlsm", data)
+	batch, err := m.ProcessPayersFile(ctx, "real_case_valid.xlsm", data)
+	require.NotNil(t, batch, err)
+	receiptsMap, sentCount := batch.Receipts, batch.SentCount
 
 	// Must have partial success
 	require.Error(t, err)
@@ -364,6 +383,7 @@ func TestIntegration_ProcessPayersFile_EmailMappingError(t *testing.T) {
 
 	t.Logf("EmailMappingError: %+v", mappingErr.MapPayerReceipt)
 	assert.Greater(t, mappingErr.FailedCount(), 0, "at least one payer should be missing an email")
+	assert.Contains(t, batch.PrintScopes, model.PrintScopeUnmapped, "receipts of payers without email are merged for print")
 	assert.Equal(t, len(mockSettings.settings.Emails)+mappingErr.FailedCount(), len(receiptsMap)+mappingErr.FailedCount(), "should match total payers in xls")
 }
 
@@ -387,9 +407,9 @@ func TestIntegration_ProcessPayersFile_EmailSendingError(t *testing.T) {
 	// Valid settings — no missing emails
 	mockSettings := &mockSettingsService{
 		settings: model.Settings{
-			Emails: map[string]string{
-				"иванов иван": "john@example.com",
-				"петров петр": "jane@example.com",
+			Emails: map[string][]string{
+				"иванов иван": {"john@example.com"},
+				"петров петр": {"jane@example.com"},
 			},
 			SenderEmail: "sender@example.com",
 		},
@@ -406,30 +426,35 @@ func TestIntegration_ProcessPayersFile_EmailSendingError(t *testing.T) {
 	}
 
 	m := &Manager{
-		History:            &mockHistoryService{},
-		Settings:           mockSettings,
-		Mail:               failingMail,
-		storage:            defaultFileStorage{},
-		payerParser:        defaultPayerParser{},
-		orgParser:          defaultOrgParser{},
-		converterConfigKey: converterKey,
-		pdfFontPath:        pdfFontPath,
+		History:        &mockHistoryService{},
+		Settings:       mockSettings,
+		Mail:           failingMail,
+		storage:        defaultFileStorage{},
+		payerParser:    defaultPayerParser{},
+		orgParser:      defaultOrgParser{},
+		docConverter:   converter.NewConverter(converter.ApiUrl, converterKey),
+		templateSource: TemplateSourceConverter,
+		pdfFontPath:    pdfFontPath,
 		dirs: struct {
 			BlankReceiptPath   string
 			ReceiptPatternsDir string
 			PayersXlsDir       string
 			SentReceiptsDir    string
 			QrCodesDir         string
+			TemplateCacheDir   string
 		}{
 			BlankReceiptPath:   "./testdata/blank_receipt_pattern.xls",
 			ReceiptPatternsDir: outDir,
 			PayersXlsDir:       outDir,
 			SentReceiptsDir:    outDir,
 			QrCodesDir:         outDir,
+			TemplateCacheDir:   outDir,
 		},
 	}
 
-	receiptsMap, sentCount, err := m.ProcessPayersFile(ctx, "real_case_valid.xlsm", data)
+	batch, err := m.ProcessPayersFile(ctx, "real_case_valid.xlsm", data)
+	require.NotNil(t, batch, err)
+	receiptsMap, sentCount := batch.Receipts, batch.SentCount
 
 	// Expect EmailSendingError
 	require.Error(t, err)
@@ -463,8 +488,8 @@ func TestIntegration_ProcessPayersFile_CompositeError(t *testing.T) {
 	// MOCK: Missing emails for some payers and failing mail mock
 	mockSettings := &mockSettingsService{
 		settings: model.Settings{
-			Emails: map[string]string{
-				"иванов иван": "john@example.com",
+			Emails: map[string][]string{
+				"иванов иван": {"john@example.com"},
 				// "петров петр" missing intentionally
 			},
 			SenderEmail: "sender@example.com",
@@ -480,30 +505,35 @@ func TestIntegration_ProcessPayersFile_CompositeError(t *testing.T) {
 	}
 
 	m := &Manager{
-		History:            &mockHistoryService{},
-		Settings:           mockSettings,
-		Mail:               failingMail,
-		storage:            defaultFileStorage{},
-		payerParser:        defaultPayerParser{},
-		orgParser:          defaultOrgParser{},
-		converterConfigKey: converterKey,
-		pdfFontPath:        pdfFontPath,
+		History:        &mockHistoryService{},
+		Settings:       mockSettings,
+		Mail:           failingMail,
+		storage:        defaultFileStorage{},
+		payerParser:    defaultPayerParser{},
+		orgParser:      defaultOrgParser{},
+		docConverter:   converter.NewConverter(converter.ApiUrl, converterKey),
+		templateSource: TemplateSourceConverter,
+		pdfFontPath:    pdfFontPath,
 		dirs: struct {
 			BlankReceiptPath   string
 			ReceiptPatternsDir string
 			PayersXlsDir       string
 			SentReceiptsDir    string
 			QrCodesDir         string
+			TemplateCacheDir   string
 		}{
 			BlankReceiptPath:   "./testdata/blank_receipt_pattern.xls",
 			ReceiptPatternsDir: outDir,
 			PayersXlsDir:       outDir,
 			SentReceiptsDir:    outDir,
 			QrCodesDir:         outDir,
+			TemplateCacheDir:   outDir,
 		},
 	}
 
-	receiptsMap, sentCount, err := m.ProcessPayersFile(ctx, "real_case_valid.xlsm", data)
+	batch, err := m.ProcessPayersFile(ctx, "real_case_valid.xlsm", data)
+	require.NotNil(t, batch, err)
+	receiptsMap, sentCount := batch.Receipts, batch.SentCount
 
 	require.Error(t, err)
 	require.Equal(t, sentCount, failingMail.sentCount)
@@ -5,7 +5,12 @@ import (
 	"errors"
 	"li-acc/internal/errs"
 	"li-acc/internal/model"
+	"li-acc/pkg/converter"
 	pkg "li-acc/pkg/model"
+	"li-acc/pkg/qr"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -24,7 +29,7 @@ func (m *mockSettingsService) UploadSettings(context.Context, model.Settings) er
 func (m *mockSettingsService) GetSettings(context.Context) (model.Settings, error) {
 	return m.settings, m.getErr
 }
-func (m *mockSettingsService) UploadEmails(context.Context, map[string]string) error { return nil }
+func (m *mockSettingsService) UploadEmails(context.Context, map[string][]string) error { return nil }
 func (m *mockSettingsService) ProcessEmailsFile(context.Context, string, []byte) error {
 	return nil
 }
@@ -85,7 +90,7 @@ func (m *mockOrgParser) ParseSettings(_ string) (*pkg.Organization, error) {
 func TestValidateBeforeProcessFile(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		svc := &mockSettingsService{
-			settings: model.Settings{Emails: map[string]string{"A": "a@b.com"}, SenderEmail: "x@y.com"},
+			settings: model.Settings{Emails: map[string][]string{"A": {"a@b.com"}}, SenderEmail: "x@y.com"},
 		}
 		m := &Manager{Settings: svc}
 		require.NoError(t, m.validateBeforeProcessFile(context.Background()))
@@ -112,7 +117,7 @@ func TestValidateBeforeProcessFile(t *testing.T) {
 
 	t.Run("sender email missing", func(t *testing.T) {
 		svc := &mockSettingsService{
-			settings: model.Settings{Emails: map[string]string{"a": "b"}, SenderEmail: ""},
+			settings: model.Settings{Emails: map[string][]string{"a": {"b"}}, SenderEmail: ""},
 		}
 		m := &Manager{Settings: svc}
 		err := m.validateBeforeProcessFile(context.Background())
@@ -132,77 +137,343 @@ func TestProcessPayersFile(t *testing.T) {
 			payerParser: &mockPayerParser{},
 			orgParser:   &mockOrgParser{},
 		}
-		_, sentCount, err := m.ProcessPayersFile(ctx, "file.xlsx", []byte("data"))
+		batch, err := m.ProcessPayersFile(ctx, "file.xlsx", []byte("data"))
 		require.Error(t, err)
 		require.True(t, errs.IsSystemError(err))
-		require.Equal(t, sentCount, 0)
+		require.Nil(t, batch)
 	})
 
 	t.Run("store fail", func(t *testing.T) {
 		m := &Manager{
-			Settings:    &mockSettingsService{settings: model.Settings{Emails: map[string]string{"a": "b"}, SenderEmail: "c"}},
+			Settings:    &mockSettingsService{settings: model.Settings{Emails: map[string][]string{"a": {"b"}}, SenderEmail: "c"}},
 			storage:     &mockFileStorage{err: errors.New("io fail")},
 			payerParser: &mockPayerParser{},
 			orgParser:   &mockOrgParser{},
 		}
-		_, sentCount, err := m.ProcessPayersFile(ctx, "f.xlsx", []byte("x"))
+		batch, err := m.ProcessPayersFile(ctx, "f.xlsx", []byte("x"))
 		require.Error(t, err)
 		require.True(t, errs.IsSystemError(err))
-		require.Equal(t, sentCount, 0)
+		require.Nil(t, batch)
 	})
 
 	t.Run("parse payers fail", func(t *testing.T) {
 		m := &Manager{
-			Settings:    &mockSettingsService{settings: model.Settings{Emails: map[string]string{"a": "b"}, SenderEmail: "c"}},
+			Settings:    &mockSettingsService{settings: model.Settings{Emails: map[string][]string{"a": {"b"}}, SenderEmail: "c"}},
 			storage:     &mockFileStorage{path: "mock.xlsx"},
 			payerParser: &mockPayerParser{err: errors.New("bad format")},
 			orgParser:   &mockOrgParser{},
 		}
-		_, sentCount, err := m.ProcessPayersFile(ctx, "file.xlsx", []byte("data"))
+		batch, err := m.ProcessPayersFile(ctx, "file.xlsx", []byte("data"))
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "bad format")
-		require.Equal(t, sentCount, 0)
+		require.Nil(t, batch)
 	})
 
 	t.Run("parse org fail", func(t *testing.T) {
 		m := &Manager{
-			Settings:    &mockSettingsService{settings: model.Settings{Emails: map[string]string{"a": "b"}, SenderEmail: "c"}},
+			Settings:    &mockSettingsService{settings: model.Settings{Emails: map[string][]string{"a": {"b"}}, SenderEmail: "c"}},
 			storage:     &mockFileStorage{path: "mock.xlsx"},
 			payerParser: &mockPayerParser{payers: []pkg.Payer{{CHILDFIO: "Jane"}}},
 			orgParser:   &mockOrgParser{err: errors.New("org fail")},
 		}
-		_, sentCount, err := m.ProcessPayersFile(ctx, "file.xlsx", []byte("data"))
+		batch, err := m.ProcessPayersFile(ctx, "file.xlsx", []byte("data"))
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "org fail")
-		require.Equal(t, sentCount, 0)
+		require.Nil(t, batch)
 	})
 
 	t.Run("history fail", func(t *testing.T) {
 		m := &Manager{
 			History:     &mockHistoryService{addErr: errors.New("db write fail")},
-			Settings:    &mockSettingsService{settings: model.Settings{Emails: map[string]string{"a": "b"}, SenderEmail: "c"}},
+			Settings:    &mockSettingsService{settings: model.Settings{Emails: map[string][]string{"a": {"b"}}, SenderEmail: "c"}},
 			storage:     &mockFileStorage{path: "mock.xlsx"},
 			payerParser: &mockPayerParser{payers: []pkg.Payer{{CHILDFIO: "Jane"}}},
 			orgParser:   &mockOrgParser{org: &pkg.Organization{Name: "Org"}},
 		}
-		_, sentCount, err := m.ProcessPayersFile(ctx, "f.xlsx", []byte("x"))
+		batch, err := m.ProcessPayersFile(ctx, "f.xlsx", []byte("x"))
 		require.Error(t, err)
 		require.True(t, errs.IsSystemError(err))
-		require.Equal(t, sentCount, 0)
+		require.Nil(t, batch)
 	})
 
 	t.Run("mail fail", func(t *testing.T) {
 		m := &Manager{
 			History:     &mockHistoryService{},
-			Settings:    &mockSettingsService{settings: model.Settings{Emails: map[string]string{"a": "b"}, SenderEmail: "c"}},
+			Settings:    &mockSettingsService{settings: model.Settings{Emails: map[string][]string{"a": {"b"}}, SenderEmail: "c"}},
 			Mail:        &mockMailService{sendErr: errors.New("smtp fail")},
 			storage:     &mockFileStorage{path: "mock.xlsx"},
 			payerParser: &mockPayerParser{payers: []pkg.Payer{{CHILDFIO: "Jane"}}},
 			orgParser:   &mockOrgParser{org: &pkg.Organization{Name: "Org"}},
 		}
-		_, sentCount, err := m.ProcessPayersFile(ctx, "f.xlsx", []byte("x"))
+		batch, err := m.ProcessPayersFile(ctx, "f.xlsx", []byte("x"))
 		require.Error(t, err)
 		require.True(t, errs.IsSystemError(err))
-		require.Equal(t, sentCount, 0)
+		require.Nil(t, batch)
 	})
 }
+
+func TestPreviewPayersFile(t *testing.T) {
+	ctx := context.Background()
+	settings := model.Settings{Emails: map[string][]string{"иванов иван": {"ivanov@example.com", "ivanova@example.com"}}, SenderEmail: "c"}
+	org := &pkg.Organization{
+		Name: "Org", PersonalAcc: "40702810938000012345", BankName: "Сбер", BIC: "044525225", CorrespAcc: "30101810400000000225",
+	}
+
+	t.Run("settings fail", func(t *testing.T) {
+		m := &Manager{
+			Settings:    &mockSettingsService{getErr: errors.New("db fail")},
+			storage:     &mockFileStorage{path: "mock.xlsx"},
+			payerParser: &mockPayerParser{},
+			orgParser:   &mockOrgParser{},
+		}
+		preview, err := m.PreviewPayersFile(ctx, "file.xlsx", []byte("data"))
+		require.Error(t, err)
+		require.True(t, errs.IsSystemError(err))
+		require.Nil(t, preview)
+	})
+
+	t.Run("parse payers fail", func(t *testing.T) {
+		m := &Manager{
+			Settings:    &mockSettingsService{settings: settings},
+			storage:     &mockFileStorage{path: "mock.xlsx"},
+			payerParser: &mockPayerParser{err: errs.New(errs.User, "bad format")},
+			orgParser:   &mockOrgParser{},
+		}
+		preview, err := m.PreviewPayersFile(ctx, "file.xlsx", []byte("data"))
+		require.Error(t, err)
+		require.True(t, errs.IsUserError(err))
+		require.Nil(t, preview)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		payers := []pkg.Payer{
+			{CHILDFIO: " Иванов Иван ", Sum: pkg.NewMoney(100, 0)},
+			{CHILDFIO: "Петров Петр", Sum: pkg.NewMoney(200, 0)},
+		}
+		m := &Manager{
+			Settings:    &mockSettingsService{settings: settings},
+			storage:     &mockFileStorage{path: "mock.xlsx"},
+			payerParser: &mockPayerParser{payers: payers},
+			orgParser:   &mockOrgParser{org: org},
+		}
+
+		preview, err := m.PreviewPayersFile(ctx, "file.xlsx", []byte("data"))
+		require.NoError(t, err)
+		require.Equal(t, *org, preview.Organization)
+		require.Len(t, preview.Payers, 2)
+
+		require.Equal(t, payers[0], preview.Payers[0].Payer)
+		require.Equal(t, []string{"ivanov@example.com", "ivanova@example.com"}, preview.Payers[0].Emails)
+		require.Contains(t, preview.Payers[0].QrData, "Sum=10000")
+		require.True(t, strings.HasPrefix(preview.Payers[0].QrData, "ST00012|Name=Org|"))
+
+		require.Empty(t, preview.Payers[1].Emails)
+		require.Equal(t, []string{"Петров Петр"}, preview.UnmatchedPayers)
+	})
+
+	t.Run("no emails uploaded", func(t *testing.T) {
+		m := &Manager{
+			Settings:    &mockSettingsService{},
+			storage:     &mockFileStorage{path: "mock.xlsx"},
+			payerParser: &mockPayerParser{payers: []pkg.Payer{{CHILDFIO: "Jane"}}},
+			orgParser:   &mockOrgParser{org: org},
+		}
+
+		preview, err := m.PreviewPayersFile(ctx, "file.xlsx", []byte("data"))
+		require.NoError(t, err)
+		require.Equal(t, []string{"Jane"}, preview.UnmatchedPayers)
+	})
+
+	t.Run("settings do not fit QR payload", func(t *testing.T) {
+		m := &Manager{
+			Settings:    &mockSettingsService{settings: settings},
+			storage:     &mockFileStorage{path: "mock.xlsx"},
+			payerParser: &mockPayerParser{payers: []pkg.Payer{{CHILDFIO: "Jane"}}},
+			orgParser:   &mockOrgParser{org: &pkg.Organization{Name: "Org"}},
+		}
+
+		preview, err := m.PreviewPayersFile(ctx, "file.xlsx", []byte("data"))
+		require.Nil(t, preview)
+		require.True(t, errs.IsUserError(err))
+
+		var pe *qr.PayloadError
+		require.ErrorAs(t, err, &pe)
+	})
+}
+
+func TestReceiptFileName(t *testing.T) {
+	tests := []struct {
+		payer pkg.Payer
+		want  string
+	}{
+		{payer: pkg.Payer{PersAcc: "12345", CHILDFIO: " Иванов Иван "}, want: "12345_Иванов_Иван"},
+		{payer: pkg.Payer{PersAcc: "12/3", CHILDFIO: "Петров\\Петр"}, want: "12_3_Петров_Петр"},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, receiptFileName(tt.payer))
+	}
+
+	// namesakes get different files
+	require.NotEqual(t,
+		receiptFileName(pkg.Payer{PersAcc: "1", CHILDFIO: "Иванов Иван"}),
+		receiptFileName(pkg.Payer{PersAcc: "2", CHILDFIO: "Иванов Иван"}),
+	)
+}
+
+func TestQrRenderOptions(t *testing.T) {
+	m := &Manager{}
+	require.Equal(t, qr.DefaultRenderOptions(), m.qrRenderOptions())
+
+	// receipts embed raster images whatever format is configured
+	m.SetQrRenderOptions(qr.RenderOptions{Format: qr.FormatSVG, ErrorCorrection: qr.ErrorCorrectionM, ModuleSize: 4, QuietZone: 4})
+	require.Equal(t, qr.RenderOptions{Format: qr.FormatPNG, ErrorCorrection: qr.ErrorCorrectionM, ModuleSize: 4, QuietZone: 4}, m.qrRenderOptions())
+}
+
+func TestParseTemplateSource(t *testing.T) {
+	for source, want := range map[string]TemplateSource{"native": TemplateSourceNative, " Converter ": TemplateSourceConverter} {
+		got, err := ParseTemplateSource(source)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+
+	for _, source := range []string{"", "excel", "pdf"} {
+		_, err := ParseTemplateSource(source)
+		require.Error(t, err, source)
+	}
+}
+
+func TestPrepareReceiptTemplate_Native(t *testing.T) {
+	// zero template source draws the template without the converter
+	m := &Manager{pdfFontPath: "./testdata/Arial.ttf"}
+	m.dirs.ReceiptPatternsDir = t.TempDir()
+	m.dirs.TemplateCacheDir = t.TempDir()
+
+	path, err := m.prepareReceiptTemplate(context.Background(), pkg.Organization{Name: "МАОУ Тест", BIC: "999999999", BankName: "Банк"})
+	require.NoError(t, err)
+	require.Equal(t, m.dirs.TemplateCacheDir, filepath.Dir(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(data), "%PDF-"))
+}
+
+func TestPrepareReceiptTemplate_Converter(t *testing.T) {
+	conv := &converter.FakeConverter{PDF: []byte("%PDF-converted")}
+	m := &Manager{docConverter: conv, templateSource: TemplateSourceConverter}
+	m.dirs.BlankReceiptPath = "./testdata/blank_receipt_pattern.xls"
+	m.dirs.ReceiptPatternsDir = t.TempDir()
+	m.dirs.TemplateCacheDir = t.TempDir()
+
+	path, err := m.prepareReceiptTemplate(context.Background(), pkg.Organization{Name: "МАОУ Тест"})
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "%PDF-converted", string(data))
+
+	// the filled Excel pattern is converted
+	inputs := conv.Inputs()
+	require.Len(t, inputs, 1)
+	require.Equal(t, m.dirs.ReceiptPatternsDir, filepath.Dir(inputs[0]))
+	require.Equal(t, ".xlsx", filepath.Ext(inputs[0]))
+
+	// failed conversion leaves nothing in the cache
+	conv.Err = errors.New("converter is down")
+	_, err = m.prepareReceiptTemplate(context.Background(), pkg.Organization{Name: "МАОУ Другая"})
+	require.ErrorContains(t, err, "converter is down")
+	cached, err := os.ReadDir(m.dirs.TemplateCacheDir)
+	require.NoError(t, err)
+	require.Len(t, cached, 1)
+
+	// the converter source is not usable without converter
+	m.docConverter = nil
+	_, err = m.prepareReceiptTemplate(context.Background(), pkg.Organization{Name: "МАОУ Другая"})
+	require.Error(t, err)
+}
+
+func TestPrepareReceiptTemplate_Cache(t *testing.T) {
+	conv := &converter.FakeConverter{}
+	m := &Manager{docConverter: conv, templateSource: TemplateSourceConverter, pdfFontPath: "./testdata/Arial.ttf"}
+	m.dirs.BlankReceiptPath = "./testdata/blank_receipt_pattern.xls"
+	m.dirs.ReceiptPatternsDir = t.TempDir()
+	m.dirs.TemplateCacheDir = t.TempDir()
+	ctx := context.Background()
+	org := pkg.Organization{Name: "МАОУ Тест", PayeeINN: "1657032924", BIC: "019205400"}
+
+	first, err := m.prepareReceiptTemplate(ctx, org)
+	require.NoError(t, err)
+
+	// the same organization reuses the converted template
+	second, err := m.prepareReceiptTemplate(ctx, org)
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+	require.Len(t, conv.Inputs(), 1)
+
+	// any changed requisite makes another template
+	changed := org
+	changed.BIC = "019205401"
+	other, err := m.prepareReceiptTemplate(ctx, changed)
+	require.NoError(t, err)
+	require.NotEqual(t, first, other)
+	require.Len(t, conv.Inputs(), 2)
+
+	// the native template of the same organization is another template too
+	m.templateSource = TemplateSourceNative
+	native, err := m.prepareReceiptTemplate(ctx, org)
+	require.NoError(t, err)
+	require.NotEqual(t, first, native)
+	require.Len(t, conv.Inputs(), 2)
+
+	// after invalidation the template is converted again
+	removed, err := m.InvalidateTemplateCache()
+	require.NoError(t, err)
+	require.Equal(t, 3, removed)
+	require.NoFileExists(t, first)
+
+	m.templateSource = TemplateSourceConverter
+	again, err := m.prepareReceiptTemplate(ctx, org)
+	require.NoError(t, err)
+	require.Equal(t, first, again)
+	require.Len(t, conv.Inputs(), 3)
+}
+
+func TestTemplateCacheKey_BlankPattern(t *testing.T) {
+	blank := filepath.Join(t.TempDir(), "blank.xlsx")
+	require.NoError(t, os.WriteFile(blank, []byte("pattern v1"), 0o644))
+	m := &Manager{templateSource: TemplateSourceConverter}
+	m.dirs.BlankReceiptPath = blank
+	org := pkg.Organization{Name: "МАОУ Тест"}
+
+	before, err := m.templateCacheKey(org)
+	require.NoError(t, err)
+	again, err := m.templateCacheKey(org)
+	require.NoError(t, err)
+	require.Equal(t, before, again)
+
+	// the fixed blank pattern is not taken from the cache
+	require.NoError(t, os.WriteFile(blank, []byte("pattern v2"), 0o644))
+	after, err := m.templateCacheKey(org)
+	require.NoError(t, err)
+	require.NotEqual(t, before, after)
+
+	m.dirs.BlankReceiptPath = filepath.Join(t.TempDir(), "missing.xlsx")
+	_, err = m.templateCacheKey(org)
+	require.Error(t, err)
+}
+
+func TestTemplateCacheKey_ConverterBackend(t *testing.T) {
+	blank := filepath.Join(t.TempDir(), "blank.xlsx")
+	require.NoError(t, os.WriteFile(blank, []byte("pattern"), 0o644))
+	m := &Manager{templateSource: TemplateSourceConverter, docConverter: &converter.FakeConverter{}}
+	m.dirs.BlankReceiptPath = blank
+	org := pkg.Organization{Name: "МАОУ Тест"}
+
+	fake, err := m.templateCacheKey(org)
+	require.NoError(t, err)
+
+	// the template converted by another backend is not taken from the cache
+	m.docConverter = converter.NewOfficeConverter("", 0, 0)
+	soffice, err := m.templateCacheKey(org)
+	require.NoError(t, err)
+	require.NotEqual(t, fake, soffice)
+}
@@ -14,20 +14,21 @@ import (
 	"go.uber.org/zap"
 )
 
+// EmailParser extracts payers emails from table files (spreadsheets or CSV).
 type EmailParser interface {
-	ParseEmail(path string) (map[string]string, error)
+	ParseEmail(path string) (map[string][]string, error)
 }
 
 type xlsEmailParser struct{}
 
-func (p *xlsEmailParser) ParseEmail(path string) (map[string]string, error) {
+func (p *xlsEmailParser) ParseEmail(path string) (map[string][]string, error) {
 	return xls.ParseEmail(path)
 }
 
 type SettingsRepo interface {
 	SetSettings(ctx context.Context, s model.Settings) error
 	GetSettings(ctx context.Context) (model.Settings, error)
-	SetEmails(ctx context.Context, emails map[string]string) error
+	SetEmails(ctx context.Context, emails map[string][]string) error
 	SetSenderEmail(ctx context.Context, email string) error
 }
 
@@ -35,7 +36,7 @@ type SettingsService interface {
 	UploadSettings(ctx context.Context, settings model.Settings) error
 	GetSettings(ctx context.Context) (model.Settings, error)
 
-	UploadEmails(ctx context.Context, emails map[string]string) error
+	UploadEmails(ctx context.Context, emails map[string][]string) error
 	ProcessEmailsFile(ctx context.Context, filename string, fileData []byte) error
 
 	SetSenderEmail(ctx context.Context, email string) error
@@ -50,7 +51,7 @@ type settingsService struct {
 }
 
 func NewSettingsService(repo *repository.SettingsRepository) SettingsService {
-	return &settingsService{repo: repo, parser: &xlsEmailParser{}}
+	return &settingsService{repo: repo, parser: newFormatEmailParser()}
 }
 
 func (s *settingsService) UploadSettings(ctx context.Context, settings model.Settings) error {
@@ -145,7 +146,7 @@ func (s *settingsService) GetSettings(ctx context.Context) (model.Settings, erro
 //
 // Steps:
 //  1. Save uploaded Excel file to a predefined directory.
-//  2. Parse the file to extract payer emails (map[FIO -> email]).
+//  2. Parse the file to extract payer emails (map[FIO -> emails]).
 //  3. Store parsed emails using UploadEmails().
 //  4. Log each stage with timing and context-aware cancellation.
 //
@@ -224,7 +225,7 @@ func (s *settingsService) ProcessEmailsFile(ctx context.Context, filename string
 	return nil
 }
 
-func (s *settingsService) UploadEmails(ctx context.Context, emails map[string]string) error {
+func (s *settingsService) UploadEmails(ctx context.Context, emails map[string][]string) error {
 	start := time.Now()
 
 	// Input validation
@@ -11,11 +11,11 @@ import (
 )
 
 type mockEmailParser struct {
-	result map[string]string
+	result map[string][]string
 	err    error
 }
 
-func (m *mockEmailParser) ParseEmail(_ string) (map[string]string, error) {
+func (m *mockEmailParser) ParseEmail(_ string) (map[string][]string, error) {
 	return m.result, m.err
 }
 
@@ -28,7 +28,7 @@ type mockSettingsRepo struct {
 	getSettingsErr     error
 	getSettingsResult  model.Settings
 	lastSetSettingsArg model.Settings
-	lastSetEmailsArg   map[string]string
+	lastSetEmailsArg   map[string][]string
 	lastSetSenderEmail string
 }
 
@@ -42,7 +42,7 @@ func (m *mockSettingsRepo) GetSettings(ctx context.Context) (model.Settings, err
 	}
 	return m.getSettingsResult, nil
 }
-func (m *mockSettingsRepo) SetEmails(ctx context.Context, emails map[string]string) error {
+func (m *mockSettingsRepo) SetEmails(ctx context.Context, emails map[string][]string) error {
 	m.lastSetEmailsArg = emails
 	return m.setEmailsErr
 }
@@ -60,7 +60,7 @@ func TestUploadSettings(t *testing.T) {
 
 		settings := model.Settings{
 			SenderEmail: "x@y.com",
-			Emails:      map[string]string{"a": "b"},
+			Emails:      map[string][]string{"a": {"b"}},
 		}
 
 		err := svc.UploadSettings(context.Background(), settings)
@@ -80,7 +80,7 @@ func TestUploadSettings(t *testing.T) {
 		repo := &mockSettingsRepo{setSettingsErr: errors.New("db fail")}
 		svc := &settingsService{repo: repo}
 
-		settings := model.Settings{SenderEmail: "x@y.com", Emails: map[string]string{"a": "b"}}
+		settings := model.Settings{SenderEmail: "x@y.com", Emails: map[string][]string{"a": {"b"}}}
 		err := svc.UploadSettings(context.Background(), settings)
 		require.ErrorContains(t, err, "db fail")
 	})
@@ -117,7 +117,7 @@ func TestUploadEmails(t *testing.T) {
 		repo := &mockSettingsRepo{}
 		svc := &settingsService{repo: repo}
 
-		emails := map[string]string{"a": "b"}
+		emails := map[string][]string{"a": {"b"}}
 		err := svc.UploadEmails(context.Background(), emails)
 		require.NoError(t, err)
 		require.Equal(t, emails, svc.cache.Emails)
@@ -133,7 +133,7 @@ func TestUploadEmails(t *testing.T) {
 		repo := &mockSettingsRepo{setEmailsErr: errors.New("insert fail")}
 		svc := &settingsService{repo: repo}
 
-		emails := map[string]string{"a": "b"}
+		emails := map[string][]string{"a": {"b"}}
 		err := svc.UploadEmails(context.Background(), emails)
 		require.ErrorContains(t, err, "insert fail")
 	})
@@ -166,7 +166,7 @@ func TestSetSenderEmail(t *testing.T) {
 func TestProcessEmailsFile(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		repo := &mockSettingsRepo{}
-		parser := &mockEmailParser{result: map[string]string{"John Doe": "john@example.com"}}
+		parser := &mockEmailParser{result: map[string][]string{"John Doe": {"john@example.com"}}}
 		svc := &settingsService{
 			repo:   repo,
 			parser: parser,
@@ -204,7 +204,7 @@ func TestProcessEmailsFile(t *testing.T) {
 
 	t.Run("upload emails fails", func(t *testing.T) {
 		repo := &mockSettingsRepo{setEmailsErr: errors.New("repo fail")}
-		parser := &mockEmailParser{result: map[string]string{"A": "a@example.com"}}
+		parser := &mockEmailParser{result: map[string][]string{"A": {"a@example.com"}}}
 		svc := &settingsService{
 			repo:   repo,
 			parser: parser,
@@ -220,7 +220,7 @@ func TestProcessEmailsFile(t *testing.T) {
 		cancel()
 
 		repo := &mockSettingsRepo{}
-		parser := &mockEmailParser{result: map[string]string{"A": "a@example.com"}}
+		parser := &mockEmailParser{result: map[string][]string{"A": {"a@example.com"}}}
 		svc := &settingsService{
 			repo:   repo,
 			parser: parser,
@@ -233,7 +233,7 @@ func TestProcessEmailsFile(t *testing.T) {
 }
 
 func TestGetCache(t *testing.T) {
-	cache := model.Settings{SenderEmail: "cached@ok", Emails: map[string]string{"x": "y"}}
+	cache := model.Settings{SenderEmail: "cached@ok", Emails: map[string][]string{"x": {"y"}}}
 	svc := &settingsService{cache: cache}
 	got := svc.GetCache()
 	require.Equal(t, cache, got)
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"li-acc/internal/errs"
+	"li-acc/pkg/converter"
+	"li-acc/pkg/logger"
+	pkg "li-acc/pkg/model"
+	"li-acc/pkg/pdf"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// cachedReceiptTemplate returns the path of the receipt template for the organization in the cache directory,
+// and whether the template is already there.
+func (m *Manager) cachedReceiptTemplate(org pkg.Organization) (string, bool, error) {
+	key, err := m.templateCacheKey(org)
+	if err != nil {
+		return "", false, err
+	}
+
+	pdfPath := filepath.Join(m.dirs.TemplateCacheDir, key+".pdf")
+	info, err := os.Stat(pdfPath)
+	if err == nil && info.Size() > 0 {
+		return pdfPath, true, nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", false, errs.WrapIOError("check cached receipt template", pdfPath, err)
+	}
+	return pdfPath, false, nil
+}
+
+// templateCacheKey returns the fingerprint of everything the receipt template is made of: the template source
+// (with the converter backend), organization credentials and the blank pattern (the Excel file for the converter,
+// the font for the native renderer).
+// Requisites of organization almost never change, so the same template serves many batches.
+func (m *Manager) templateCacheKey(org pkg.Organization) (string, error) {
+	h := sha256.New()
+
+	blankPath := m.pdfFontPath
+	if blankPath == "" {
+		blankPath = pdf.DefaultFontPath
+	}
+	if m.templateSource == TemplateSourceConverter {
+		blankPath = m.dirs.BlankReceiptPath
+		var backend converter.Backend
+		if m.docConverter != nil {
+			backend = m.docConverter.Backend()
+		}
+		fmt.Fprintf(h, "%s %s\n", TemplateSourceConverter, backend)
+	} else {
+		fmt.Fprintf(h, "%s %d\n", TemplateSourceNative, pdf.TemplateLayoutVersion)
+	}
+
+	if err := json.NewEncoder(h).Encode(org); err != nil {
+		return "", errs.Wrap(errs.System, "failed to encode organization for template cache key", err)
+	}
+
+	f, err := os.Open(blankPath)
+	if err != nil {
+		return "", errs.WrapIOError("open blank receipt pattern for template cache key", blankPath, err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errs.WrapIOError("read blank receipt pattern for template cache key", blankPath, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// InvalidateTemplateCache removes all cached receipt templates, so the next batch makes its template again,
+// e.g. after the blank pattern or the converter was fixed. Returns the number of removed templates.
+func (m *Manager) InvalidateTemplateCache() (int, error) {
+	paths, err := filepath.Glob(filepath.Join(m.dirs.TemplateCacheDir, "*.pdf"))
+	if err != nil {
+		return 0, errs.Wrap(errs.System, "failed to list cached receipt templates", err)
+	}
+
+	removed := 0
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return removed, errs.WrapIOError("remove cached receipt template", path, err)
+		}
+		removed++
+	}
+
+	logger.Info("receipt template cache invalidated", zap.Int("removed", removed))
+	return removed, nil
+}
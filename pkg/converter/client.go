@@ -1,40 +1,244 @@
 package converter
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"li-acc/internal/errs"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultRequestTimeout = 30 * time.Second
+	DefaultMaxRetries     = 3
+	DefaultRetryBackoff   = 500 * time.Millisecond
+	DefaultMaxBackoff     = 5 * time.Second
+	DefaultPollInterval   = time.Second
+	DefaultPollTimeout    = 2 * time.Minute
+
+	// apiCodeSuccess is the code of the API response body of a successful request
+	apiCodeSuccess = "200"
+	// maxErrorBody limits the response body read into APIError
+	maxErrorBody = 4 << 10
 )
 
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
-type RealClient struct{}
+// ClientConfig configures HTTP requests of the Converter.
+type ClientConfig struct {
+	Timeout      time.Duration // limit of one request, including reading the response body
+	MaxRetries   int           // retries of an idempotent request after network errors and 5xx responses
+	RetryBackoff time.Duration // delay before the first retry, doubled before every next one
+	MaxBackoff   time.Duration // upper limit of the delay between retries
+	PollInterval time.Duration // delay between the requests of the converted file status in the task workflow
+	PollTimeout  time.Duration // limit of waiting for the executed task
+}
 
-func (RealClient) Do(req *http.Request) (*http.Response, error) {
-	return performRequest(req)
+// DefaultClientConfig returns the config used by NewConverter.
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		Timeout:      DefaultRequestTimeout,
+		MaxRetries:   DefaultMaxRetries,
+		RetryBackoff: DefaultRetryBackoff,
+		MaxBackoff:   DefaultMaxBackoff,
+		PollInterval: DefaultPollInterval,
+		PollTimeout:  DefaultPollTimeout,
+	}
 }
 
-// performRequest creates default HTTP Client and performs the request [req]. Returns error if it is unsuccessful,
-// the response object otherwise.
-func performRequest(req *http.Request) (*http.Response, error) {
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to perform request %s: %w", req.URL.String(), err)
+// withDefaults replaces unset durations with the default ones. Zero or negative MaxRetries disables retries.
+func (cfg ClientConfig) withDefaults() ClientConfig {
+	def := DefaultClientConfig()
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
 	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = def.RetryBackoff
+	}
+	if cfg.MaxBackoff < cfg.RetryBackoff {
+		cfg.MaxBackoff = max(def.MaxBackoff, cfg.RetryBackoff)
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = def.PollInterval
+	}
+	if cfg.PollTimeout <= 0 {
+		cfg.PollTimeout = def.PollTimeout
+	}
+	return cfg
+}
 
-	return resp, nil
+// newHTTPClient creates the client shared by all requests of the Converter. Besides the limit of the whole
+// request, connecting and waiting for the response headers are limited, so a hung server is detected early.
+func newHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: min(timeout, 10*time.Second), KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = min(timeout, 10*time.Second)
+	transport.ResponseHeaderTimeout = timeout
+	return &http.Client{Timeout: timeout, Transport: transport}
 }
 
-// checkRequestStatus checks status code of the response
-func checkResponseStatus(resp *http.Response, url string) error {
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("api request failed. URL: <%s>, Code: %d, response: %s",
-			url, resp.StatusCode, string(body))
+// APIError is the error of the converter API: unsuccessful HTTP status or error code in the response body.
+// It has errs.External kind.
+type APIError struct {
+	URL        string
+	StatusCode int    // HTTP status code of the response
+	Code       string // error code of the API, empty if the response has none
+	Message    string // error message of the API or the response body
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("api request failed. URL: <%s>, Status: %d", e.URL, e.StatusCode)
+	if e.Code != "" {
+		msg += ", Code: " + e.Code
+	}
+	if e.Message != "" {
+		msg += ", response: " + e.Message
 	}
+	return msg
+}
+
+func (e *APIError) Kind() errs.Kind {
+	return errs.External
+}
+
+func (e *APIError) Unwrap() error {
+	return nil
+}
+
+// Temporary reports whether the request may succeed if it is repeated: the server failed or limits requests.
+func (e *APIError) Temporary() bool {
+	return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests
+}
+
+// apiCode is the error code of the API. The API sends it as string, but numbers are accepted too.
+type apiCode string
 
+func (c *apiCode) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*c = apiCode(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("api code must be string or number, got %s", data)
+	}
+	*c = apiCode(n.String())
 	return nil
 }
+
+// apiStatus is the common part of the API response bodies
+type apiStatus struct {
+	Code    apiCode `json:"code"`
+	Message string  `json:"msg"`
+}
+
+// err returns APIError if the response body reports an error.
+func (s apiStatus) err(url string, statusCode int) error {
+	if s.Code == "" || s.Code == apiCodeSuccess {
+		return nil
+	}
+	return &APIError{URL: url, StatusCode: statusCode, Code: string(s.Code), Message: s.Message}
+}
+
+// checkResponseStatus checks status code of the response and returns APIError if it is not successful.
+// The error code and message are taken from the body, if it is the API error response.
+func checkResponseStatus(resp *http.Response, url string) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	apiErr := &APIError{URL: url, StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+
+	var status apiStatus
+	if json.Unmarshal(body, &status) == nil && status.Code != "" {
+		apiErr.Code = string(status.Code)
+		if status.Message != "" {
+			apiErr.Message = status.Message
+		}
+	}
+	return apiErr
+}
+
+// doRequest performs the request created by [newRequest], checks the response status and passes the response
+// to [read], which reads the body. The request is created anew for every attempt, so its body is read again.
+// If the request is [idempotent], it is retried with exponential backoff after network errors, temporary API errors
+// and broken connections while the body is read, all of them counting against one budget of retries,
+// until the retries or the context end. Other errors of [read], e.g. of a malformed body, are returned at once.
+func (c *Converter) doRequest(
+	ctx context.Context,
+	idempotent bool,
+	newRequest func(ctx context.Context) (*http.Request, error),
+	read func(resp *http.Response) error,
+) error {
+	attempts := 1
+	if idempotent {
+		attempts += c.config.MaxRetries
+	}
+	backoff := c.config.RetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest(ctx)
+		if err != nil {
+			return errs.Wrap(errs.System, "create request", err)
+		}
+
+		resp, err := c.client.Do(req)
+		var retryable bool
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return errs.Wrap(errs.External, fmt.Sprintf("request to %s canceled", req.URL.Redacted()), ctx.Err())
+		case err != nil:
+			err = errs.Wrap(errs.External, fmt.Sprintf("request to %s", req.URL.Redacted()), err)
+			retryable = true
+		default:
+			if err = checkResponseStatus(resp, req.URL.Redacted()); err != nil {
+				retryable = err.(*APIError).Temporary()
+			} else if err = read(resp); err != nil {
+				retryable = isBrokenConnection(err)
+			}
+			resp.Body.Close()
+			if err == nil {
+				return nil
+			}
+			if ctx.Err() != nil {
+				return errs.Wrap(errs.External, fmt.Sprintf("request to %s canceled", req.URL.Redacted()), ctx.Err())
+			}
+		}
+
+		if !retryable || attempt >= attempts {
+			if attempts > 1 && retryable {
+				return errs.Wrap(errs.External, "failed after "+strconv.Itoa(attempts)+" attempts", err)
+			}
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return errs.Wrap(errs.External, fmt.Sprintf("request to %s canceled, last error: %v", req.URL.Redacted(), err), ctx.Err())
+		}
+		backoff = min(2*backoff, c.config.MaxBackoff)
+	}
+}
+
+// isBrokenConnection reports whether reading the response failed because the connection broke,
+// so the request may succeed if it is repeated
+func isBrokenConnection(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
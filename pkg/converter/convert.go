@@ -2,6 +2,7 @@ package converter
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -16,17 +17,30 @@ import (
 // Converter struct manages API operations of the converter.
 // The methods are general for all kinds of conversion, except ExcelToPdf.
 type Converter struct {
-	apiKey  string // auth token of API
-	baseUrl string
-	client  HTTPClient
+	apiKey    string // public key of API
+	secretKey string // secret key of API, the task workflow is used if it is set
+	baseUrl   string
+	client    HTTPClient // shared by all requests
+	config    ClientConfig
+	token     tokenCache // access token of the task workflow
 }
 
-// NewConverter is initializer of the Converter object, generates auth token and stores in field `token`
+// NewConverter is initializer of the Converter object with DefaultClientConfig.
+// It converts files with one request authorized by [publicKey], see Convert.
 func NewConverter(baseUrl, publicKey string) *Converter {
+	return NewConverterWithConfig(baseUrl, publicKey, "", DefaultClientConfig())
+}
+
+// NewConverterWithConfig is initializer of the Converter object. Unset durations of [cfg] are taken from DefaultClientConfig.
+// If [secretKey] is set, files are converted by the asynchronous task workflow authorized by the access token.
+func NewConverterWithConfig(baseUrl, publicKey, secretKey string, cfg ClientConfig) *Converter {
+	cfg = cfg.withDefaults()
 	c := Converter{
-		baseUrl: strings.TrimRight(baseUrl, "/"),
-		client:  RealClient{},
-		apiKey:  publicKey,
+		baseUrl:   strings.TrimRight(baseUrl, "/"),
+		client:    newHTTPClient(cfg.Timeout),
+		apiKey:    publicKey,
+		secretKey: secretKey,
+		config:    cfg,
 	}
 	return &c
 }
@@ -38,31 +52,42 @@ func (c *Converter) endpoint(path string) (string, error) {
 
 // Convert converts given file to another format. Formats are given in [conversionType] argument.
 // Source file's path is passed as [inFilepath], result file is stored as [outFilepath].
-// Used here ComPDFKit API requires to perform following steps to convert the file:
-// Create Task, Upload File, Execute the conversion of the uploaded file, Get the download link of the converted file.
-func (c *Converter) Convert(inFilepath, outFilepath string, conversionType Conversion) error {
-	fileInfo, err := c.processConversion(inFilepath, conversionType)
-	if err != nil {
-		return errs.Wrap(errs.System, "failed to upload file", err)
-	}
+// Without the secret key the file is converted by one request to ComPDFKit API, which returns the download link.
+// With the secret key the asynchronous task workflow is used (see convertTask): Create Task, Upload File,
+// Execute the conversion of the uploaded file, Get the download link of the converted file when it is ready.
+// Every request is limited by the client timeout, and all of them are interrupted when [ctx] is done.
+// Errors of the API have errs.External kind, see APIError.
+func (c *Converter) Convert(ctx context.Context, inFilepath, outFilepath string, conversionType Conversion) error {
+	var fileUrl string
+	if c.secretKey != "" {
+		var err error
+		if fileUrl, err = c.convertTask(ctx, inFilepath, conversionType); err != nil {
+			return err
+		}
+	} else {
+		fileInfo, err := c.processConversion(ctx, inFilepath, conversionType)
+		if err != nil {
+			return errs.Wrap(errs.External, "failed to upload file", err)
+		}
 
-	fileUrl, err := getFileUrl(fileInfo)
-	if err != nil {
-		return errs.Wrap(errs.System, "failed to get file download url", err)
+		fileUrl, err = getFileUrl(fileInfo)
+		if err != nil {
+			return errs.Wrap(errs.External, "failed to get file download url", err)
+		}
 	}
 
 	// download file from the obtained link and store to [outFilepath]
-	err = downloadFile(fileUrl, outFilepath)
+	err := c.downloadFile(ctx, fileUrl, outFilepath)
 	if err != nil {
-		return errs.Wrap(errs.System, "failed to download file", err)
+		return errs.Wrap(errs.External, "failed to download file", err)
 	}
 	return nil
 }
 
 // processConversion perform file conversion using API. It uploads file to the server, sets api key in headers
-// and performs request.
-func (c *Converter) processConversion(filepath string, conversion Conversion) (ProcessConversionResponse, error) {
-	body, formDataType, err := buildMultipartBody(filepath)
+// and performs request. The request starts a new conversion, so it is not retried.
+func (c *Converter) processConversion(ctx context.Context, filepath string, conversion Conversion) (ProcessConversionResponse, error) {
+	body, formDataType, err := buildMultipartBody(filepath, nil)
 	if err != nil {
 		return ProcessConversionResponse{}, err
 	}
@@ -76,7 +101,7 @@ func (c *Converter) processConversion(filepath string, conversion Conversion) (P
 	var resp ProcessConversionResponse
 
 	// Send the multipart request and decode JSON response into `resp`
-	err = c.doJSONRequest(http.MethodPost, conversion.ConversionFormatEndpoint(), body, headers, nil, &resp)
+	err = c.doJSONRequest(ctx, http.MethodPost, conversion.ConversionFormatEndpoint(), false, body, headers, nil, &resp)
 	if err != nil {
 		return ProcessConversionResponse{}, err
 	}
@@ -87,9 +112,9 @@ func (c *Converter) processConversion(filepath string, conversion Conversion) (P
 
 // buildMultipartBody creates a multipart/form-data request body for file upload.
 // The function opens the file at the specified `filepath`, reads its contents, and forms
-// a multipart body with a "file" field.
+// a multipart body with a "file" field and the text [fields].
 // Returns the finished request body, the corresponding 'Content-Type' header and an error if the operation fails.
-func buildMultipartBody(filepath string) (io.Reader, string, error) {
+func buildMultipartBody(filepath string, fields map[string]string) ([]byte, string, error) {
 	// Open the file that will be uploaded to the converter API
 	file, err := os.Open(filepath)
 	if err != nil {
@@ -112,57 +137,58 @@ func buildMultipartBody(filepath string) (io.Reader, string, error) {
 		return nil, "", fmt.Errorf("copy file contents into the multipart form: %w", err)
 	}
 
+	for name, value := range fields {
+		if err = writer.WriteField(name, value); err != nil {
+			return nil, "", fmt.Errorf("write multipart form field %s: %w", name, err)
+		}
+	}
+
 	// Finalize the multipart body before sending
 	if err = writer.Close(); err != nil {
 		return nil, "", err
 	}
 
-	return body, writer.FormDataContentType(), nil
+	return body.Bytes(), writer.FormDataContentType(), nil
 }
 
+// getFileUrl returns the download URL of the converted file from the API response.
 func getFileUrl(fileInfo ProcessConversionResponse) (string, error) {
+	if len(fileInfo.Data.FileInfo) == 0 {
+		return "", errs.New(errs.External, "API response has no converted files")
+	}
 	fileUrl := fileInfo.Data.FileInfo[0].DownloadUrl
 	status := fileInfo.Data.FileInfo[0].Status
 
 	if status != "success" {
-		return "", fmt.Errorf("file conversion status is not 'success'")
+		return "", errs.New(errs.External, fmt.Sprintf("file conversion status is '%s', not 'success'", status))
 	} else if fileUrl == "" {
-		return "", fmt.Errorf("file download url from API response is empty")
+		return "", errs.New(errs.External, "file download url from API response is empty")
 	}
 
 	return fileUrl, nil
 }
 
 // downloadFile gets file download URL as [url] parameter, gets its binary data and writes to the file [filename].
-func downloadFile(url string, filename string) error {
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf("cannot dowload file from %s: %w", url, err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("converted file downloading failed failed. URL: %s, Code: %d",
-			url, resp.StatusCode)
-	}
-
-	content, err := io.ReadAll(resp.Body)
+// Downloading is idempotent, so it is retried after network errors, 5xx responses and broken downloads.
+func (c *Converter) downloadFile(ctx context.Context, url string, filename string) error {
+	var content []byte
+	err := c.doRequest(ctx, true, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	}, func(resp *http.Response) error {
+		var err error
+		if content, err = io.ReadAll(resp.Body); err != nil {
+			return errs.Wrap(errs.External, fmt.Sprintf("cannot read file downloaded from %s", url), err)
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	file, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
-	if err != nil {
-		return fmt.Errorf("failed to open the file '%s': %w", filename, err)
-	}
-	defer file.Close()
 
-	_, err = file.Write(content)
-	if err != nil {
-		return fmt.Errorf("failed to write file data to the file '%s': %w", filename, err)
+	if err := os.WriteFile(filename, content, 0o644); err != nil {
+		return errs.WrapIOError("save converted file", filename, err)
 	}
-
 	return nil
-
 }
 
 // doJSONRequest is a helper function for methods of Converter.
@@ -170,19 +196,25 @@ func downloadFile(url string, filename string) error {
 // decodes json response into `out` (expected that it is XxxxResponse structure from models.go)
 // `method`: HTTP method;
 // `endpoint`: an endpoint for the current API call (without base URL);
+// `idempotent`: whether the request may be repeated after failures, i.e. it does not change the server state;
 // `body`: a body of the request;
 // `headers`: a map of HTTP request headers;
 // `params`: map of query params.
 func (c *Converter) doJSONRequest(
+	ctx context.Context,
 	method, endpoint string,
-	body io.Reader,
+	idempotent bool,
+	body []byte,
 	headers map[string]string,
 	params map[string]string,
 	out any,
 ) error {
 
 	// generate the complete API url
-	fullURL, _ := c.endpoint(endpoint)
+	fullURL, err := c.endpoint(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
 
 	// Build the base URL
 	reqURL, err := url.Parse(fullURL)
@@ -199,30 +231,34 @@ func (c *Converter) doJSONRequest(
 		reqURL.RawQuery = q.Encode()
 	}
 
-	// Build the HTTP request
-	req, err := http.NewRequest(method, fullURL, body)
-	if err != nil {
-		return fmt.Errorf("create request: %w", err)
-	}
-
-	// Add headers
-	for k, v := range headers {
-		req.Header.Add(k, v)
-	}
-
-	// Send and process response
-	resp, err := c.client.Do(req)
+	// Send and process response. The request is built for every attempt, so its body is read from the start
+	var statusCode int
+	err = c.doRequest(ctx, idempotent, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, reqURL.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range headers {
+			req.Header.Add(k, v)
+		}
+		return req, nil
+	}, func(resp *http.Response) error {
+		statusCode = resp.StatusCode
+		if out == nil {
+			return nil
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return errs.Wrap(errs.External, fmt.Sprintf("decode response of %s", endpoint), err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("request to %s: %w", endpoint, err)
-	}
-	defer resp.Body.Close()
-
-	if err := checkResponseStatus(resp, fullURL); err != nil {
 		return err
 	}
 
-	if out != nil {
-		return json.NewDecoder(resp.Body).Decode(out)
+	// the API reports some errors with the code in the body of a successful response
+	if status, ok := out.(interface{ err(string, int) error }); ok {
+		return status.err(reqURL.Redacted(), statusCode)
 	}
 	return nil
 }
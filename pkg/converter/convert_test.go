@@ -2,13 +2,18 @@ package converter
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"li-acc/internal/errs"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -30,6 +35,7 @@ func TestProcessConversion(t *testing.T) {
 		name        string
 		doFunc      func(*http.Request) (*http.Response, error)
 		expectError bool
+		wantCode    string // code of the APIError
 		wantResp    ProcessConversionResponse
 	}{
 		{
@@ -93,6 +99,28 @@ func TestProcessConversion(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "api error in body",
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: 200,
+					Body:       io.NopCloser(bytes.NewBufferString(`{"code":"06001","msg":"Insufficient assets"}`)),
+				}, nil
+			},
+			expectError: true,
+			wantCode:    "06001",
+		},
+		{
+			name: "api error status",
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: 401,
+					Body:       io.NopCloser(bytes.NewBufferString(`{"code":401,"msg":"invalid public key"}`)),
+				}, nil
+			},
+			expectError: true,
+			wantCode:    "401",
+		},
 		{
 			name: "http client error",
 			doFunc: func(req *http.Request) (*http.Response, error) {
@@ -104,19 +132,32 @@ func TestProcessConversion(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			var calls int
 			c := &Converter{
-				client: &FakeClient{DoFunc: tt.doFunc},
+				client: &FakeClient{DoFunc: func(req *http.Request) (*http.Response, error) {
+					calls++
+					return tt.doFunc(req)
+				}},
 				apiKey: "dummy-key",
+				config: ClientConfig{MaxRetries: 3, RetryBackoff: time.Millisecond}.withDefaults(),
 			}
 
 			conversion := Conversion{
 				From: "docx", To: "pdf",
 			}
 
-			resp, err := c.processConversion(tmpFile.Name(), conversion)
+			resp, err := c.processConversion(context.Background(), tmpFile.Name(), conversion)
 
+			// the conversion is started by POST, it is never repeated
+			require.Equal(t, 1, calls)
 			if tt.expectError {
 				require.Error(t, err)
+				require.True(t, hasKind(err, errs.External), err)
+				if tt.wantCode != "" {
+					var apiErr *APIError
+					require.ErrorAs(t, err, &apiErr)
+					require.Equal(t, tt.wantCode, apiErr.Code)
+				}
 			} else {
 				require.NoError(t, err)
 				require.Equal(t, tt.wantResp, resp)
@@ -125,47 +166,131 @@ func TestProcessConversion(t *testing.T) {
 	}
 }
 
+// hasKind reports whether [err] or an error wrapped by it has the errs.Kind [kind].
+func hasKind(err error, kind errs.Kind) bool {
+	var coded errs.CodedError
+	return errors.As(err, &coded) && coded.Kind() == kind
+}
+
 func TestDownloadFile(t *testing.T) {
 	tests := []struct {
 		name        string
-		handler     http.HandlerFunc
+		failures    int // responses with [failStatus] before the file is returned
+		failStatus  int
+		truncated   bool // the file is never returned whole after the failures
+		wantCalls   int32
 		expectError bool
 	}{
-		{
-			name: "success",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte("hello world"))
-			},
-			expectError: false,
-		},
-		{
-			name: "bad status code",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				http.Error(w, "internal error", http.StatusInternalServerError)
-			},
-			expectError: true,
-		},
+		{name: "success", wantCalls: 1},
+		{name: "retried after server errors", failures: 2, failStatus: http.StatusBadGateway, wantCalls: 3},
+		{name: "retries exhausted", failures: 10, failStatus: http.StatusInternalServerError, wantCalls: 4, expectError: true},
+		{name: "not found is not retried", failures: 10, failStatus: http.StatusNotFound, wantCalls: 1, expectError: true},
+		{name: "broken downloads share the retries", failures: 2, failStatus: http.StatusBadGateway, truncated: true, wantCalls: 4, expectError: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			server := httptest.NewServer(tt.handler)
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(calls.Add(1)) <= tt.failures {
+					http.Error(w, "internal error", tt.failStatus)
+					return
+				}
+				if tt.truncated {
+					// the connection is closed before the declared length is sent
+					w.Header().Set("Content-Length", "100")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte("hello"))
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("hello world"))
+			}))
 			defer server.Close()
 
-			tmpFile, _ := os.CreateTemp("", "download-test-*.txt")
-			defer os.Remove(tmpFile.Name())
+			c := NewConverterWithConfig(server.URL, "public", "", ClientConfig{MaxRetries: 3, RetryBackoff: time.Millisecond})
+			filename := t.TempDir() + "/download.txt"
 
-			err := downloadFile(server.URL, tmpFile.Name())
+			err := c.downloadFile(context.Background(), server.URL, filename)
+			require.Equal(t, tt.wantCalls, calls.Load())
 
-			if tt.expectError {
+			if tt.truncated {
+				require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+				require.True(t, hasKind(err, errs.External), err)
+				require.NoFileExists(t, filename)
+			} else if tt.expectError {
 				require.Error(t, err)
+				var apiErr *APIError
+				require.ErrorAs(t, err, &apiErr)
+				require.Equal(t, tt.failStatus, apiErr.StatusCode)
+				require.True(t, hasKind(err, errs.External), err)
+				require.NoFileExists(t, filename)
 			} else {
 				require.NoError(t, err)
 
-				content, _ := os.ReadFile(tmpFile.Name())
+				content, _ := os.ReadFile(filename)
 				require.Equal(t, "hello world", string(content))
 			}
 		})
 	}
 }
+
+func TestDownloadFile_Hung(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	t.Run("request timeout", func(t *testing.T) {
+		c := NewConverterWithConfig(server.URL, "public", "", ClientConfig{Timeout: 50 * time.Millisecond, MaxRetries: 1, RetryBackoff: time.Millisecond})
+		start := time.Now()
+		err := c.downloadFile(context.Background(), server.URL, t.TempDir()+"/download.txt")
+		require.Error(t, err)
+		require.True(t, hasKind(err, errs.External), err)
+		require.Less(t, time.Since(start), 2*time.Second)
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		c := NewConverterWithConfig(server.URL, "public", "", ClientConfig{Timeout: time.Minute})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := c.downloadFile(ctx, server.URL, t.TempDir()+"/download.txt")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, time.Since(start), 2*time.Second)
+	})
+}
+
+func TestDoJSONRequest_MalformedResponse(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("<html>not json</html>"))
+	}))
+	defer server.Close()
+
+	// the complete body of another format is not fixed by repeating the request
+	c := NewConverterWithConfig(server.URL, "public", "", ClientConfig{MaxRetries: 3, RetryBackoff: time.Millisecond})
+	var out FileInfoResponse
+	err := c.doJSONRequest(context.Background(), http.MethodGet, EndpointGetConverted, true, nil, nil, nil, &out)
+	require.ErrorContains(t, err, "decode response")
+	require.NotContains(t, err.Error(), "attempts")
+	require.True(t, hasKind(err, errs.External), err)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestGetFileUrl(t *testing.T) {
+	_, err := getFileUrl(ProcessConversionResponse{})
+	require.ErrorContains(t, err, "no converted files")
+
+	var resp ProcessConversionResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"data":{"fileInfoDTOList":[{"downloadUrl":"","status":"failed"}]}}`), &resp))
+	_, err = getFileUrl(resp)
+	require.ErrorContains(t, err, "'failed'")
+}
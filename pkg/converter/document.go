@@ -0,0 +1,44 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DocumentConverter converts office documents to PDF. The receipt pattern filled with organization
+// credentials is converted by it, unless the template is drawn natively.
+type DocumentConverter interface {
+	// ExcelToPdf converts the spreadsheet [inFilepath] to PDF and stores it as [outFilepath].
+	ExcelToPdf(ctx context.Context, inFilepath, outFilepath string) error
+	// Backend returns the name of the implementation. The backends render the same document differently.
+	Backend() Backend
+}
+
+// Backend is the name of the DocumentConverter implementation, as it is set in config.
+type Backend string
+
+const (
+	BackendComPDF  Backend = "compdf"  // ComPDFKit cloud API, see Converter
+	BackendSoffice Backend = "soffice" // local headless LibreOffice, see OfficeConverter
+	BackendFake    Backend = "fake"    // FakeConverter for tests, not accepted by ParseBackend
+)
+
+// ParseBackend parses the converter backend by its name: "compdf" or "soffice" (case-insensitive).
+func ParseBackend(backend string) (Backend, error) {
+	switch b := Backend(strings.ToLower(strings.TrimSpace(backend))); b {
+	case BackendComPDF, BackendSoffice:
+		return b, nil
+	}
+	return "", fmt.Errorf("unknown converter backend %q, expected %q or %q", backend, BackendComPDF, BackendSoffice)
+}
+
+// ExcelToPdf converts the spreadsheet using ComPDFKit API. The requests to API are interrupted when the context is done.
+func (c *Converter) ExcelToPdf(ctx context.Context, inFilepath, outFilepath string) error {
+	return c.Convert(ctx, inFilepath, outFilepath, XLSXToPDF)
+}
+
+// Backend returns BackendComPDF
+func (c *Converter) Backend() Backend {
+	return BackendComPDF
+}
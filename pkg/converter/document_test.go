@@ -0,0 +1,35 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeConverter(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "out.pdf")
+
+	var conv DocumentConverter = &FakeConverter{PDF: []byte("%PDF-test")}
+	require.NoError(t, conv.ExcelToPdf(context.Background(), "in.xlsx", dst))
+	data, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, "%PDF-test", string(data))
+	require.Equal(t, []string{"in.xlsx"}, conv.(*FakeConverter).Inputs())
+
+	failing := &FakeConverter{Err: fmt.Errorf("boom")}
+	require.EqualError(t, failing.ExcelToPdf(context.Background(), "in.xlsx", dst), "boom")
+}
+
+func TestParseBackend(t *testing.T) {
+	for name, want := range map[string]Backend{"compdf": BackendComPDF, " SOFFICE ": BackendSoffice} {
+		got, err := ParseBackend(name)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+	_, err := ParseBackend("cloud")
+	require.Error(t, err)
+}
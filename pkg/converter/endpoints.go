@@ -23,3 +23,8 @@ type Conversion struct {
 func (c Conversion) ConversionFormatEndpoint() string {
 	return fmt.Sprintf("/process/%s/%s", strings.ToLower(c.From), strings.ToLower(c.To))
 }
+
+// TaskEndpoint returns the endpoint creating the task of the conversion
+func (c Conversion) TaskEndpoint() string {
+	return fmt.Sprintf("/task/%s/%s", strings.ToLower(c.From), strings.ToLower(c.To))
+}
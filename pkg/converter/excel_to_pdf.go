@@ -1,10 +1,12 @@
 package converter
 
+import "context"
+
 var XLSXToPDF = Conversion{"xlsx", "pdf"}
 
-func ExcelToPdf(inFilepath, outFilepath, publicKey string) error {
+func ExcelToPdf(ctx context.Context, inFilepath, outFilepath, publicKey string) error {
 	conv := NewConverter(ApiUrl, publicKey)
 
-	err := conv.Convert(inFilepath, outFilepath, XLSXToPDF)
+	err := conv.Convert(ctx, inFilepath, outFilepath, XLSXToPDF)
 	return err
 }
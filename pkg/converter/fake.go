@@ -0,0 +1,55 @@
+package converter
+
+import (
+	"context"
+	"li-acc/internal/errs"
+	"os"
+	"sync"
+)
+
+// FakeConverter is DocumentConverter for tests. It does not convert anything:
+// it writes PDF to the output file, or fails with Err. Safe for concurrent use.
+type FakeConverter struct {
+	PDF []byte // content of every output file, a stub if empty
+	Err error  // returned by every conversion if not nil
+
+	mu     sync.Mutex
+	inputs []string
+}
+
+// fakePDF is written if FakeConverter.PDF is empty
+var fakePDF = []byte("%PDF-1.4\n% converted by FakeConverter\n%%EOF\n")
+
+func (f *FakeConverter) ExcelToPdf(ctx context.Context, inFilepath, outFilepath string) error {
+	f.mu.Lock()
+	f.inputs = append(f.inputs, inFilepath)
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return errs.Wrap(errs.System, "conversion canceled", err)
+	}
+	if f.Err != nil {
+		return f.Err
+	}
+
+	data := f.PDF
+	if len(data) == 0 {
+		data = fakePDF
+	}
+	if err := os.WriteFile(outFilepath, data, 0o644); err != nil {
+		return errs.WrapIOError("save converted file", outFilepath, err)
+	}
+	return nil
+}
+
+// Backend returns BackendFake
+func (f *FakeConverter) Backend() Backend {
+	return BackendFake
+}
+
+// Inputs returns the files passed to the converter, in the order of calls.
+func (f *FakeConverter) Inputs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.inputs...)
+}
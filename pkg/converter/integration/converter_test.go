@@ -3,6 +3,7 @@
 package integration
 
 import (
+	"context"
 	"encoding/json"
 	"li-acc/pkg/converter"
 	"net/http"
@@ -72,7 +73,7 @@ func TestConvert_E2E(t *testing.T) {
 	outFile := inFile.Name() + ".out"
 
 	// Вызываем сам метод
-	err := c.Convert(inFile.Name(), outFile, conversion)
+	err := c.Convert(context.Background(), inFile.Name(), outFile, conversion)
 	require.NoError(t, err)
 
 	// Проверяем, что на диске действительно то, что отдал сервер
@@ -100,7 +101,34 @@ func TestConvert_RealCase(t *testing.T) {
 
 	dst := path.Join(outDir, "converted.pdf")
 
-	err := converter.ExcelToPdf(xlsFilePath, dst, pubKey)
+	err := converter.ExcelToPdf(context.Background(), xlsFilePath, dst, pubKey)
+	require.NoError(t, err)
+
+	_, err = os.Stat(dst)
+	require.NoError(t, err)
+}
+
+func TestConvert_RealCaseTask(t *testing.T) {
+	xlsFilePath := "./testdata/receipt_pattern.xlsx"
+
+	if err := godotenv.Load(".env.local"); err != nil {
+		t.Skip("no .env.local found, skipping real data test")
+	}
+
+	pubKey, secKey := os.Getenv("PUBLIC"), os.Getenv("SECRET")
+	if pubKey == "" || secKey == "" {
+		t.Skip("environment variables are empty")
+	}
+
+	outDir := "./testdata/out"
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Skipf("failed to create directory %s: %v", outDir, err)
+	}
+
+	dst := path.Join(outDir, "converted_task.pdf")
+
+	c := converter.NewConverterWithConfig(converter.ApiUrl, pubKey, secKey, converter.DefaultClientConfig())
+	err := c.ExcelToPdf(context.Background(), xlsFilePath, dst)
 	require.NoError(t, err)
 
 	_, err = os.Stat(dst)
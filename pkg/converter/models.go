@@ -1,6 +1,9 @@
 package converter
 
+import "encoding/json"
+
 type ProcessConversionResponse struct {
+	apiStatus
 	Data struct {
 		FileInfo []struct {
 			DownloadUrl string `json:"downloadUrl"`
@@ -8,3 +11,57 @@ type ProcessConversionResponse struct {
 		} `json:"fileInfoDTOList"`
 	} `json:"data"`
 }
+
+// TokenRequest is the body of EndpointToken request
+type TokenRequest struct {
+	PublicKey string `json:"publicKey"`
+	SecretKey string `json:"secretKey"`
+}
+
+// TokenResponse is the response of EndpointToken. ExpiresIn is the lifetime of the token in seconds.
+type TokenResponse struct {
+	apiStatus
+	Data struct {
+		AccessToken string      `json:"accessToken"`
+		ExpiresIn   json.Number `json:"expiresIn"`
+	} `json:"data"`
+}
+
+// CreateTaskResponse is the response of Conversion.TaskEndpoint
+type CreateTaskResponse struct {
+	apiStatus
+	Data struct {
+		TaskId string `json:"taskId"`
+	} `json:"data"`
+}
+
+// UploadFileResponse is the response of EndpointUploadFile
+type UploadFileResponse struct {
+	apiStatus
+	Data struct {
+		FileKey  string `json:"fileKey"`
+		TaskId   string `json:"taskId"`
+		FileName string `json:"fileName"`
+	} `json:"data"`
+}
+
+// ExecuteTaskResponse is the response of EndpointConvert
+type ExecuteTaskResponse struct {
+	apiStatus
+	Data struct {
+		TaskId string `json:"taskId"`
+	} `json:"data"`
+}
+
+// FileInfoResponse is the response of EndpointGetConverted
+type FileInfoResponse struct {
+	apiStatus
+	Data struct {
+		FileKey       string `json:"fileKey"`
+		TaskId        string `json:"taskId"`
+		DownloadUrl   string `json:"downloadUrl"`
+		Status        string `json:"status"`
+		FailureCode   string `json:"failureCode"`
+		FailureReason string `json:"failureReason"`
+	} `json:"data"`
+}
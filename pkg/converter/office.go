@@ -0,0 +1,110 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"li-acc/internal/errs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultSofficeBinary    = "soffice"
+	DefaultSofficeTimeout   = time.Minute
+	DefaultSofficeProcesses = 2
+)
+
+// OfficeConverter converts documents with the local LibreOffice in headless mode (`soffice --convert-to pdf`),
+// so the documents never leave the server. Every conversion runs a separate soffice process;
+// their number is limited, and a process running longer than the timeout is killed.
+type OfficeConverter struct {
+	binary  string
+	timeout time.Duration
+	slots   chan struct{} // one element per running process
+}
+
+// NewOfficeConverter creates converter running [binary] (path or name in PATH) at most [maxProcesses] at once.
+// Zero values are replaced with DefaultSofficeBinary, DefaultSofficeTimeout and DefaultSofficeProcesses.
+func NewOfficeConverter(binary string, timeout time.Duration, maxProcesses int) *OfficeConverter {
+	if binary == "" {
+		binary = DefaultSofficeBinary
+	}
+	if timeout <= 0 {
+		timeout = DefaultSofficeTimeout
+	}
+	if maxProcesses <= 0 {
+		maxProcesses = DefaultSofficeProcesses
+	}
+	return &OfficeConverter{
+		binary:  binary,
+		timeout: timeout,
+		slots:   make(chan struct{}, maxProcesses),
+	}
+}
+
+// Backend returns BackendSoffice
+func (o *OfficeConverter) Backend() Backend {
+	return BackendSoffice
+}
+
+// ExcelToPdf converts the spreadsheet. It waits for a free process slot, unless the context is done.
+func (o *OfficeConverter) ExcelToPdf(ctx context.Context, inFilepath, outFilepath string) error {
+	select {
+	case o.slots <- struct{}{}:
+		defer func() { <-o.slots }()
+	case <-ctx.Done():
+		return errs.Wrap(errs.System, "conversion canceled while waiting for soffice", ctx.Err())
+	}
+
+	// soffice writes the result under the name of the source file, so it works in its own directory
+	workDir, err := os.MkdirTemp("", "soffice-*")
+	if err != nil {
+		return errs.Wrap(errs.System, "failed to create soffice working directory", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	// soffice processes sharing one user profile wait for each other or fail, so every process gets its own
+	profile := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(workDir, "profile"))}).String()
+	cmd := exec.CommandContext(ctx, o.binary,
+		"-env:UserInstallation="+profile,
+		"--headless", "--norestore", "--nologo",
+		"--convert-to", "pdf",
+		"--outdir", workDir,
+		inFilepath,
+	)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.WaitDelay = time.Second // do not wait for children holding the output after the process is killed
+	killProcessGroupOnCancel(cmd)
+
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if errors.Is(ctxErr, context.DeadlineExceeded) {
+				return errs.Wrap(errs.System, fmt.Sprintf("soffice did not convert `%s` in %s", inFilepath, o.timeout), ctxErr)
+			}
+			return errs.Wrap(errs.System, "conversion canceled", ctxErr)
+		}
+		return errs.Wrap(errs.System, fmt.Sprintf("soffice failed to convert `%s`: %s", inFilepath, strings.TrimSpace(output.String())), err)
+	}
+
+	// soffice exits successfully even if it could not read the file, then there is no result
+	converted := filepath.Join(workDir, strings.TrimSuffix(filepath.Base(inFilepath), filepath.Ext(inFilepath))+".pdf")
+	data, err := os.ReadFile(converted)
+	if err != nil {
+		return errs.Wrap(errs.System, fmt.Sprintf("soffice did not convert `%s`: %s", inFilepath, strings.TrimSpace(output.String())), err)
+	}
+	if err := os.WriteFile(outFilepath, data, 0o644); err != nil {
+		return errs.WrapIOError("save converted file", outFilepath, err)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package converter
+
+import "os/exec"
+
+// killProcessGroupOnCancel keeps the default cancellation: only the started process is killed.
+func killProcessGroupOnCancel(*exec.Cmd) {}
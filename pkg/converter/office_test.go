@@ -0,0 +1,113 @@
+//go:build unix
+
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSoffice writes shell script accepting soffice arguments: it runs [body] with $src (the converted file)
+// and $outdir (the directory of the result) set, and returns the path of the script.
+func fakeSoffice(t *testing.T, body string) string {
+	t.Helper()
+	script := `#!/bin/sh
+while [ $# -gt 0 ]; do
+	case "$1" in
+		--outdir) outdir="$2"; shift ;;
+		*) src="$1" ;;
+	esac
+	shift
+done
+name=$(basename "$src")
+result="$outdir/${name%.*}.pdf"
+` + body
+	path := filepath.Join(t.TempDir(), "soffice")
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	return path
+}
+
+func TestOfficeConverter_ExcelToPdf(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "pattern.xlsx")
+	require.NoError(t, os.WriteFile(src, []byte("xlsx"), 0o644))
+
+	tests := []struct {
+		name    string
+		body    string
+		timeout time.Duration
+		wantErr string
+	}{
+		{name: "success", body: `printf '%%PDF converted %s' "$(cat "$src")" > "$result"`},
+		{name: "failure", body: `echo "source file could not be loaded" >&2; exit 1`, wantErr: "source file could not be loaded"},
+		{name: "no result", body: `echo "Error: no export filter"`, wantErr: "no export filter"},
+		{name: "timeout", body: `sleep 5`, timeout: 200 * time.Millisecond, wantErr: "did not convert"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv := NewOfficeConverter(fakeSoffice(t, tt.body), tt.timeout, 1)
+			dst := filepath.Join(t.TempDir(), "pattern.pdf")
+
+			start := time.Now()
+			err := conv.ExcelToPdf(context.Background(), src, dst)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				require.NoFileExists(t, dst)
+				require.Less(t, time.Since(start), 3*time.Second)
+				return
+			}
+			require.NoError(t, err)
+			data, err := os.ReadFile(dst)
+			require.NoError(t, err)
+			require.Equal(t, "%PDF converted xlsx", string(data))
+		})
+	}
+}
+
+func TestOfficeConverter_ProcessLimit(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "pattern.xlsx")
+	require.NoError(t, os.WriteFile(src, []byte("xlsx"), 0o644))
+
+	// every process marks itself running, and reports if it sees more processes than allowed
+	running, overlap := t.TempDir(), filepath.Join(t.TempDir(), "overlap")
+	const limit = 2
+	body := fmt.Sprintf(`mkdir "%[1]s/$$"
+if [ $(ls "%[1]s" | wc -l) -gt %[3]d ]; then touch "%[2]s"; fi
+sleep 0.2
+rmdir "%[1]s/$$"
+echo pdf > "$result"`, running, overlap, limit)
+	conv := NewOfficeConverter(fakeSoffice(t, body), time.Minute, limit)
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, 5)
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errCh <- conv.ExcelToPdf(context.Background(), src, filepath.Join(t.TempDir(), fmt.Sprintf("%d.pdf", i)))
+		}()
+	}
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		require.NoError(t, err)
+	}
+	require.NoFileExists(t, overlap)
+}
+
+func TestOfficeConverter_CanceledWhileWaiting(t *testing.T) {
+	conv := NewOfficeConverter(fakeSoffice(t, `sleep 5`), time.Minute, 1)
+	conv.slots <- struct{}{} // the only process is busy
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := conv.ExcelToPdf(ctx, "pattern.xlsx", filepath.Join(t.TempDir(), "pattern.pdf"))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package converter
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroupOnCancel runs the command in its own process group and kills the whole group when the context
+// is done: soffice starts the office process as its child, which would survive otherwise.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
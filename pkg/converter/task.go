@@ -0,0 +1,192 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"li-acc/internal/errs"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenExpiryMargin is the time before the expiry when the access token is already requested anew,
+// so it does not expire while the conversion goes. Short-lived tokens are renewed after half of their lifetime.
+const tokenExpiryMargin = time.Minute
+
+// file statuses of FileInfoResponse
+const (
+	fileStatusSuccess = "success"
+	fileStatusFailed  = "failed"
+)
+
+// tokenCache keeps the access token of API until it expires. It is shared by concurrent conversions.
+type tokenCache struct {
+	mu      sync.Mutex
+	value   string
+	expires time.Time
+}
+
+// accessToken returns the cached access token, or gets new one with the public and secret keys if it expires.
+func (c *Converter) accessToken(ctx context.Context) (string, error) {
+	c.token.mu.Lock()
+	defer c.token.mu.Unlock()
+
+	if c.token.value != "" && time.Now().Before(c.token.expires) {
+		return c.token.value, nil
+	}
+
+	body, err := json.Marshal(TokenRequest{PublicKey: c.apiKey, SecretKey: c.secretKey})
+	if err != nil {
+		return "", errs.Wrap(errs.System, "encode access token request", err)
+	}
+	headers := map[string]string{"Content-Type": "application/json"}
+
+	var resp TokenResponse
+	if err := c.doJSONRequest(ctx, http.MethodPost, EndpointToken, false, body, headers, nil, &resp); err != nil {
+		return "", errs.Wrap(errs.External, "failed to get access token", err)
+	}
+	if resp.Data.AccessToken == "" {
+		return "", errs.New(errs.External, "access token from API response is empty")
+	}
+	expiresIn, err := resp.Data.ExpiresIn.Int64()
+	if err != nil {
+		return "", errs.Wrap(errs.External, "invalid access token lifetime in API response", err)
+	}
+
+	c.token.value = resp.Data.AccessToken
+	lifetime := time.Duration(expiresIn) * time.Second
+	c.token.expires = time.Now().Add(lifetime - min(tokenExpiryMargin, lifetime/2))
+	return c.token.value, nil
+}
+
+// dropToken removes [token] from the cache, if it is still cached, so the next request gets new one.
+func (c *Converter) dropToken(token string) {
+	c.token.mu.Lock()
+	defer c.token.mu.Unlock()
+	if c.token.value == token {
+		c.token.value = ""
+	}
+}
+
+// doAuthorizedRequest performs doJSONRequest authorized by the access token. If API rejects the token
+// (e.g. it is revoked before the expiry), the request is repeated once with new token: the rejected request
+// changes nothing, so it is repeated even if it is not [idempotent].
+func (c *Converter) doAuthorizedRequest(
+	ctx context.Context,
+	method, endpoint string,
+	idempotent bool,
+	body []byte,
+	headers map[string]string,
+	params map[string]string,
+	out any,
+) error {
+	for attempt := 1; ; attempt++ {
+		token, err := c.accessToken(ctx)
+		if err != nil {
+			return err
+		}
+
+		authHeaders := map[string]string{"Authorization": "Bearer " + token}
+		for k, v := range headers {
+			authHeaders[k] = v
+		}
+
+		err = c.doJSONRequest(ctx, method, endpoint, idempotent, body, authHeaders, params, out)
+		var apiErr *APIError
+		if attempt == 1 && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			c.dropToken(token)
+			continue
+		}
+		return err
+	}
+}
+
+// convertTask converts the file with the asynchronous task workflow of ComPDFKit API: it creates the task,
+// uploads the file to it, executes the task and polls the file status until the conversion is done.
+// Returns the download URL of the converted file.
+func (c *Converter) convertTask(ctx context.Context, inFilepath string, conversion Conversion) (string, error) {
+	// creating and executing the task are GET requests, but they are not repeated: every one of them
+	// creates a new task or starts the conversion again
+	var task CreateTaskResponse
+	if err := c.doAuthorizedRequest(ctx, http.MethodGet, conversion.TaskEndpoint(), false, nil, nil, nil, &task); err != nil {
+		return "", errs.Wrap(errs.External, "failed to create conversion task", err)
+	}
+	taskId := task.Data.TaskId
+	if taskId == "" {
+		return "", errs.New(errs.External, "task id from API response is empty")
+	}
+
+	body, formDataType, err := buildMultipartBody(inFilepath, map[string]string{"taskId": taskId})
+	if err != nil {
+		return "", err
+	}
+	var upload UploadFileResponse
+	err = c.doAuthorizedRequest(ctx, http.MethodPost, EndpointUploadFile, false, body,
+		map[string]string{"Content-Type": formDataType}, nil, &upload)
+	if err != nil {
+		return "", errs.Wrap(errs.External, fmt.Sprintf("failed to upload `%s` to task %s", filepath.Base(inFilepath), taskId), err)
+	}
+	fileKey := upload.Data.FileKey
+	if fileKey == "" {
+		return "", errs.New(errs.External, "file key from API response is empty")
+	}
+
+	var execute ExecuteTaskResponse
+	err = c.doAuthorizedRequest(ctx, http.MethodGet, EndpointConvert, false, nil, nil, map[string]string{"taskId": taskId}, &execute)
+	if err != nil {
+		return "", errs.Wrap(errs.External, fmt.Sprintf("failed to execute task %s", taskId), err)
+	}
+
+	return c.waitConverted(ctx, taskId, fileKey)
+}
+
+// waitConverted polls the status of the file [fileKey] until it is converted, and returns its download URL.
+// Waiting is limited by the poll timeout of the client config.
+func (c *Converter) waitConverted(ctx context.Context, taskId, fileKey string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.PollTimeout)
+	defer cancel()
+
+	var status string // the last status of the file
+	notConverted := func() error {
+		return errs.Wrap(errs.External, fmt.Sprintf("file %s of task %s is not converted, last status `%s`",
+			fileKey, taskId, status), ctx.Err())
+	}
+
+	for {
+		var info FileInfoResponse
+		err := c.doAuthorizedRequest(ctx, http.MethodGet, EndpointGetConverted, true, nil, nil, map[string]string{"fileKey": fileKey}, &info)
+		if err != nil && ctx.Err() != nil {
+			return "", notConverted()
+		} else if err != nil {
+			return "", errs.Wrap(errs.External, fmt.Sprintf("failed to get status of file %s", fileKey), err)
+		}
+		status = info.Data.Status
+
+		switch strings.ToLower(status) {
+		case fileStatusSuccess:
+			if info.Data.DownloadUrl == "" {
+				return "", errs.New(errs.External, "file download url from API response is empty")
+			}
+			return info.Data.DownloadUrl, nil
+		case fileStatusFailed:
+			return "", errs.Wrap(errs.External, fmt.Sprintf("task %s failed to convert file %s", taskId, fileKey), &APIError{
+				URL:        EndpointGetConverted,
+				StatusCode: http.StatusOK,
+				Code:       info.Data.FailureCode,
+				Message:    info.Data.FailureReason,
+			})
+		}
+
+		timer := time.NewTimer(c.config.PollInterval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return "", notConverted()
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"li-acc/internal/errs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTaskAPI emulates the task workflow of ComPDFKit API
+type fakeTaskAPI struct {
+	t         *testing.T
+	expiresIn string
+	statuses  []string // file statuses returned by the polls, the last one is repeated
+	failFirst bool     // reject the first authorized request with 401
+	// endpoints answering the first request with 503, the next ones succeed
+	unavailable map[string]bool
+
+	mu       sync.Mutex
+	requests map[string]int // authorized requests per endpoint
+	tokens   int            // issued tokens
+	polls    int
+	executed bool
+	rejected bool
+	uploaded string
+}
+
+func (f *fakeTaskAPI) server() *httptest.Server {
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	writeJSON := func(w http.ResponseWriter, data any) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": "200", "msg": "success", "data": data})
+	}
+
+	authorized := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			f.mu.Lock()
+			token := "token-" + string(rune('0'+f.tokens))
+			reject := f.failFirst && !f.rejected
+			f.rejected = f.rejected || reject
+			f.mu.Unlock()
+
+			if reject || r.Header.Get("Authorization") != "Bearer "+token {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"code":"01005","msg":"token expired"}`))
+				return
+			}
+
+			f.mu.Lock()
+			if f.requests == nil {
+				f.requests = make(map[string]int)
+			}
+			f.requests[r.URL.Path]++
+			unavailable := f.unavailable[r.URL.Path] && f.requests[r.URL.Path] == 1
+			f.mu.Unlock()
+			if unavailable {
+				http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			next(w, r)
+		}
+	}
+
+	mux.HandleFunc(EndpointToken, func(w http.ResponseWriter, r *http.Request) {
+		var req TokenRequest
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(f.t, TokenRequest{PublicKey: "public", SecretKey: "secret"}, req)
+
+		f.mu.Lock()
+		f.tokens++
+		token := "token-" + string(rune('0'+f.tokens))
+		f.mu.Unlock()
+		writeJSON(w, map[string]string{"accessToken": token, "expiresIn": f.expiresIn})
+	})
+	mux.HandleFunc(XLSXToPDF.TaskEndpoint(), authorized(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"taskId": "task-1"})
+	}))
+	mux.HandleFunc(EndpointUploadFile, authorized(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(f.t, "task-1", r.FormValue("taskId"))
+		file, _, err := r.FormFile("file")
+		require.NoError(f.t, err)
+		data, _ := io.ReadAll(file)
+
+		f.mu.Lock()
+		f.uploaded = string(data)
+		f.mu.Unlock()
+		writeJSON(w, map[string]string{"taskId": "task-1", "fileKey": "file-1"})
+	}))
+	mux.HandleFunc(EndpointConvert, authorized(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(f.t, "task-1", r.URL.Query().Get("taskId"))
+		f.mu.Lock()
+		f.executed = true
+		f.mu.Unlock()
+		writeJSON(w, map[string]string{"taskId": "task-1"})
+	}))
+	mux.HandleFunc(EndpointGetConverted, authorized(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(f.t, "file-1", r.URL.Query().Get("fileKey"))
+		f.mu.Lock()
+		require.True(f.t, f.executed, "file status is requested before the task is executed")
+		status := f.statuses[min(f.polls, len(f.statuses)-1)]
+		f.polls++
+		f.mu.Unlock()
+
+		info := map[string]string{"fileKey": "file-1", "taskId": "task-1", "status": status}
+		if status == fileStatusSuccess {
+			info["downloadUrl"] = ts.URL + "/download/file-1"
+		} else if status == fileStatusFailed {
+			info["failureCode"] = "03002"
+			info["failureReason"] = "file is damaged"
+		}
+		writeJSON(w, info)
+	}))
+	mux.HandleFunc("/download/file-1", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("%PDF converted"))
+	})
+	return ts
+}
+
+func TestConvert_TaskWorkflow(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "pattern.xlsx")
+	require.NoError(t, os.WriteFile(src, []byte("xlsx"), 0o644))
+	cfg := ClientConfig{PollInterval: time.Millisecond, RetryBackoff: time.Millisecond}
+
+	tests := []struct {
+		name       string
+		api        *fakeTaskAPI
+		runs       int
+		wantTokens int
+		wantErr    string
+		wantCode   string
+	}{
+		{name: "converted after polls", api: &fakeTaskAPI{expiresIn: "3600", statuses: []string{"waiting", "converting", "success"}}, runs: 1, wantTokens: 1},
+		{name: "token cached", api: &fakeTaskAPI{expiresIn: "3600", statuses: []string{"success"}}, runs: 3, wantTokens: 1},
+		{name: "short-lived token cached", api: &fakeTaskAPI{expiresIn: "30", statuses: []string{"success"}}, runs: 2, wantTokens: 1},
+		// expired token is renewed before each of 4 authorized requests of the conversion
+		{name: "expired token renewed", api: &fakeTaskAPI{expiresIn: "0", statuses: []string{"success"}}, runs: 2, wantTokens: 8},
+		{name: "rejected token renewed", api: &fakeTaskAPI{expiresIn: "3600", statuses: []string{"success"}, failFirst: true}, runs: 1, wantTokens: 2},
+		{name: "conversion failed", api: &fakeTaskAPI{expiresIn: "3600", statuses: []string{"waiting", "failed"}}, runs: 1, wantTokens: 1,
+			wantErr: "file is damaged", wantCode: "03002"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.api.t = t
+			server := tt.api.server()
+			defer server.Close()
+			c := NewConverterWithConfig(server.URL, "public", "secret", cfg)
+
+			for range tt.runs {
+				dst := filepath.Join(t.TempDir(), "pattern.pdf")
+				err := c.ExcelToPdf(context.Background(), src, dst)
+				if tt.wantErr != "" {
+					require.ErrorContains(t, err, tt.wantErr)
+					require.True(t, hasKind(err, errs.External), err)
+					var apiErr *APIError
+					require.ErrorAs(t, err, &apiErr)
+					require.Equal(t, tt.wantCode, apiErr.Code)
+					require.NoFileExists(t, dst)
+					continue
+				}
+				require.NoError(t, err)
+				data, err := os.ReadFile(dst)
+				require.NoError(t, err)
+				require.Equal(t, "%PDF converted", string(data))
+			}
+			tt.api.mu.Lock()
+			defer tt.api.mu.Unlock()
+			require.Equal(t, "xlsx", tt.api.uploaded)
+			require.Equal(t, tt.wantTokens, tt.api.tokens)
+		})
+	}
+}
+
+func TestConvert_TaskNotRetried(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "pattern.xlsx")
+	require.NoError(t, os.WriteFile(src, []byte("xlsx"), 0o644))
+	cfg := ClientConfig{PollInterval: time.Millisecond, MaxRetries: 3, RetryBackoff: time.Millisecond}
+
+	// repeated requests would create another task or start the conversion again
+	for _, endpoint := range []string{XLSXToPDF.TaskEndpoint(), EndpointConvert} {
+		t.Run(endpoint, func(t *testing.T) {
+			api := &fakeTaskAPI{t: t, expiresIn: "3600", statuses: []string{"success"}, unavailable: map[string]bool{endpoint: true}}
+			server := api.server()
+			defer server.Close()
+			c := NewConverterWithConfig(server.URL, "public", "secret", cfg)
+
+			err := c.ExcelToPdf(context.Background(), src, filepath.Join(t.TempDir(), "pattern.pdf"))
+			var apiErr *APIError
+			require.ErrorAs(t, err, &apiErr)
+			require.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+			api.mu.Lock()
+			defer api.mu.Unlock()
+			require.Equal(t, 1, api.requests[endpoint])
+		})
+	}
+
+	t.Run("file status polled after failure", func(t *testing.T) {
+		api := &fakeTaskAPI{t: t, expiresIn: "3600", statuses: []string{"success"}, unavailable: map[string]bool{EndpointGetConverted: true}}
+		server := api.server()
+		defer server.Close()
+		c := NewConverterWithConfig(server.URL, "public", "secret", cfg)
+
+		require.NoError(t, c.ExcelToPdf(context.Background(), src, filepath.Join(t.TempDir(), "pattern.pdf")))
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		require.Equal(t, 2, api.requests[EndpointGetConverted])
+	})
+}
+
+func TestConvert_TaskPollTimeout(t *testing.T) {
+	api := &fakeTaskAPI{t: t, expiresIn: "3600", statuses: []string{"waiting"}}
+	server := api.server()
+	defer server.Close()
+
+	src := filepath.Join(t.TempDir(), "pattern.xlsx")
+	require.NoError(t, os.WriteFile(src, []byte("xlsx"), 0o644))
+	c := NewConverterWithConfig(server.URL, "public", "secret", ClientConfig{PollInterval: 10 * time.Millisecond, PollTimeout: 100 * time.Millisecond})
+
+	start := time.Now()
+	err := c.ExcelToPdf(context.Background(), src, filepath.Join(t.TempDir(), "pattern.pdf"))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.True(t, strings.Contains(err.Error(), "last status `waiting`"), err)
+	require.Less(t, time.Since(start), 2*time.Second)
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	require.Greater(t, api.polls, 1)
+}
@@ -0,0 +1,189 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Money is an exact amount of money in kopeks. Using integer kopeks instead of float or string values
+// guarantees that the same amount is printed identically in the receipt text and in the QR code.
+type Money int64
+
+// KopeksInRuble is the number of kopeks in one ruble
+const KopeksInRuble = 100
+
+var (
+	// ErrInvalidMoney is returned when the amount string is not a number
+	ErrInvalidMoney = errors.New("invalid money amount")
+
+	// ErrNegativeMoney is returned when the amount is negative
+	ErrNegativeMoney = errors.New("negative money amount")
+)
+
+// NewMoney creates Money from the rubles and kopeks parts, e.g. NewMoney(1200, 50) is 1200.50.
+func NewMoney(rubles, kopeks int64) Money {
+	return Money(rubles*KopeksInRuble + kopeks)
+}
+
+// ParseMoney parses the amount written in any of the common formats:
+// "1200", "1200.5", "1200,50", "1 200,50" (spaces, including non-breaking, separate thousands),
+// "1,200.50" or "1.200,50" (the last separator is the decimal one if both are used).
+// Fractions longer than kopeks are rounded half up, so Excel float artifacts such as "1199.9999999" become 1200.00.
+// Returns ErrNegativeMoney for negative amounts and ErrInvalidMoney for anything that is not a number,
+// including the ambiguous amounts such as "1,200" or "1.200", where the only separator may separate thousands.
+func ParseMoney(s string) (Money, error) {
+	// remove all spaces: they can only separate groups of digits
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+
+	if s == "" {
+		return 0, fmt.Errorf("%w: empty string", ErrInvalidMoney)
+	}
+	if strings.HasPrefix(s, "-") {
+		return 0, fmt.Errorf("%w: %s", ErrNegativeMoney, s)
+	}
+	s = strings.TrimPrefix(s, "+")
+
+	intPart, fracPart, err := splitDecimal(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", err, s)
+	}
+	if intPart == "" && fracPart == "" {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidMoney, s)
+	}
+
+	var kopeks int64
+	for _, d := range intPart {
+		kopeks = kopeks*10 + int64(d-'0')
+		if kopeks > maxRubles {
+			return 0, fmt.Errorf("%w: amount is too large: %s", ErrInvalidMoney, s)
+		}
+	}
+	kopeks *= KopeksInRuble
+
+	// take two digits of kopeks, the third one decides the rounding
+	frac := fracPart + "000"
+	kopeks += int64(frac[0]-'0')*10 + int64(frac[1]-'0')
+	if frac[2] >= '5' {
+		kopeks++
+	}
+
+	return Money(kopeks), nil
+}
+
+// maxRubles limits the amount, so the value in kopeks never overflows int64
+const maxRubles = 1<<62/KopeksInRuble - 1
+
+// splitDecimal splits the amount without spaces and sign into integer and fractional digits.
+// The last '.' or ',' is the decimal separator, unless it is the only kind of separator used several times
+// (then all of them separate thousands, e.g. "1,200,000").
+func splitDecimal(s string) (string, string, error) {
+	lastSep := strings.LastIndexAny(s, ".,")
+	if lastSep != -1 && strings.Count(s, string(s[lastSep])) > 1 && !strings.ContainsAny(s, otherSeparator(s[lastSep])) {
+		lastSep = -1 // all separators are thousands separators
+	}
+
+	intPart, fracPart := s, ""
+	if lastSep != -1 {
+		intPart, fracPart = s[:lastSep], s[lastSep+1:]
+	}
+
+	// the only separator followed by 3 digits may be either decimal or thousands one, e.g. "1,200",
+	// unless the integer part can not be the first group of thousands, e.g. "1200,555" or "0,125"
+	if lastSep != -1 && len(fracPart) == 3 && !strings.ContainsAny(intPart, ".,") &&
+		len(intPart) >= 1 && len(intPart) <= 3 && intPart[0] != '0' {
+		return "", "", ErrInvalidMoney
+	}
+
+	// in the integer part separators may be used only between groups of 3 digits
+	if strings.ContainsAny(intPart, ".,") {
+		groups := strings.FieldsFunc(intPart, func(r rune) bool { return r == '.' || r == ',' })
+		if len(groups) != strings.Count(intPart, ".")+strings.Count(intPart, ",")+1 {
+			return "", "", ErrInvalidMoney
+		}
+		for i, g := range groups {
+			if (i == 0 && len(g) > 3) || (i > 0 && len(g) != 3) {
+				return "", "", ErrInvalidMoney
+			}
+		}
+		intPart = strings.Join(groups, "")
+	}
+
+	if !onlyDigits(intPart) || !onlyDigits(fracPart) {
+		return "", "", ErrInvalidMoney
+	}
+	return intPart, fracPart, nil
+}
+
+func otherSeparator(sep byte) string {
+	if sep == '.' {
+		return ","
+	}
+	return "."
+}
+
+func onlyDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Rubles returns the whole rubles part of the amount.
+func (m Money) Rubles() int64 {
+	return int64(m) / KopeksInRuble
+}
+
+// Kopeks returns the kopeks part of the amount (0-99).
+func (m Money) Kopeks() int64 {
+	return int64(m) % KopeksInRuble
+}
+
+// IsPositive reports whether the amount is greater than zero.
+func (m Money) IsPositive() bool {
+	return m > 0
+}
+
+// String returns the amount with dot and two decimals, e.g. "1200.50".
+func (m Money) String() string {
+	return fmt.Sprintf("%d.%02d", m.Rubles(), m.Kopeks())
+}
+
+// QRString returns the amount in kopeks without separators, as required in the `Sum` field of the payment QR code,
+// e.g. "120050" for 1200.50.
+func (m Money) QRString() string {
+	return fmt.Sprintf("%d", int64(m))
+}
+
+// RubKopString returns the amount in the receipt text format, e.g. "1200 руб. 50 коп.".
+func (m Money) RubKopString() string {
+	return fmt.Sprintf("%d руб. %02d коп.", m.Rubles(), m.Kopeks())
+}
+
+// MarshalJSON encodes the amount as a string with two decimals, e.g. "1200.50".
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON decodes the amount from a string or a number using ParseMoney. JSON null leaves the amount unchanged.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	s := strings.Trim(string(data), `"`)
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+	}{
+		{"1200", NewMoney(1200, 0)},
+		{"1200.5", NewMoney(1200, 50)},
+		{"1200,50", NewMoney(1200, 50)},
+		{"1 200,5", NewMoney(1200, 50)},
+		{"1 200,50", NewMoney(1200, 50)},
+		{"1,200.50", NewMoney(1200, 50)},
+		{"1.200,50", NewMoney(1200, 50)},
+		{"1,200,000", NewMoney(1200000, 0)},
+		{"1200.555", NewMoney(1200, 56)},
+		{"1200.554", NewMoney(1200, 55)},
+		{"0,125", NewMoney(0, 13)},
+		{"1200,125", NewMoney(1200, 13)},
+		{"1.200,500", NewMoney(1200, 50)},
+		{"1199.9999999", NewMoney(1200, 0)},
+		{"0.01", NewMoney(0, 1)},
+		{".5", NewMoney(0, 50)},
+		{"+15", NewMoney(15, 0)},
+		{"0", 0},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		require.NoError(t, err, tt.in)
+		require.Equal(t, tt.want, got, tt.in)
+	}
+}
+
+func TestParseMoney_Invalid(t *testing.T) {
+	tests := map[string]error{
+		"-1":                   ErrNegativeMoney,
+		"-0,50":                ErrNegativeMoney,
+		"":                     ErrInvalidMoney,
+		" ":                    ErrInvalidMoney,
+		"сто":                  ErrInvalidMoney,
+		"12a":                  ErrInvalidMoney,
+		"1e3":                  ErrInvalidMoney,
+		"Inf":                  ErrInvalidMoney,
+		".":                    ErrInvalidMoney,
+		"12,34,56":             ErrInvalidMoney,
+		"1.2,3.4":              ErrInvalidMoney,
+		"1200.50.1":            ErrInvalidMoney,
+		"1,200":                ErrInvalidMoney,
+		"1.200":                ErrInvalidMoney,
+		"12,345":               ErrInvalidMoney,
+		"99999999999999999999": ErrInvalidMoney,
+	}
+
+	for in, want := range tests {
+		_, err := ParseMoney(in)
+		require.ErrorIs(t, err, want, in)
+	}
+}
+
+func TestMoneyFormatting(t *testing.T) {
+	m := NewMoney(1200, 5)
+
+	require.Equal(t, int64(1200), m.Rubles())
+	require.Equal(t, int64(5), m.Kopeks())
+	require.Equal(t, "1200.05", m.String())
+	require.Equal(t, "120005", m.QRString())
+	require.Equal(t, "1200 руб. 05 коп.", m.RubKopString())
+
+	require.Equal(t, "0.00", Money(0).String())
+	require.Equal(t, "0", Money(0).QRString())
+	require.Equal(t, "0 руб. 00 коп.", Money(0).RubKopString())
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(Payer{Sum: NewMoney(100, 50)})
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"Сумма":"100.50"`)
+
+	var p Payer
+	require.NoError(t, json.Unmarshal([]byte(`{"Сумма":"1 200,5"}`), &p))
+	require.Equal(t, NewMoney(1200, 50), p.Sum)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"Сумма":300.1}`), &p))
+	require.Equal(t, NewMoney(300, 10), p.Sum)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"Сумма":null}`), &p))
+	require.Equal(t, NewMoney(300, 10), p.Sum, "null leaves the amount unchanged")
+
+	require.Error(t, json.Unmarshal([]byte(`{"Сумма":"-1"}`), &p))
+}
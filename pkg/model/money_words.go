@@ -0,0 +1,132 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Gender is the grammatical gender of the noun counted by a number: Russian numerals 1 and 2 agree with it
+// ("один рубль", "одна копейка", "две тысячи").
+type Gender int
+
+const (
+	Masculine Gender = iota
+	Feminine
+)
+
+// PluralForms are the noun forms used with numbers: for 1 ("рубль"), for 2-4 ("рубля") and for 5-20 and 0 ("рублей").
+type PluralForms [3]string
+
+var (
+	RubleForms = PluralForms{"рубль", "рубля", "рублей"}
+	KopekForms = PluralForms{"копейка", "копейки", "копеек"}
+)
+
+// Plural returns the noun form for the number [n], e.g. 21 рубль, 22 рубля, 25 рублей, 11 рублей.
+func (f PluralForms) Plural(n int64) string {
+	n %= 100
+	if n >= 11 && n <= 19 {
+		return f[2]
+	}
+	switch n % 10 {
+	case 1:
+		return f[0]
+	case 2, 3, 4:
+		return f[1]
+	default:
+		return f[2]
+	}
+}
+
+var (
+	unitWords = [2][10]string{
+		Masculine: {"", "один", "два", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять"},
+		Feminine:  {"", "одна", "две", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять"},
+	}
+	teenWords = [10]string{"десять", "одиннадцать", "двенадцать", "тринадцать", "четырнадцать",
+		"пятнадцать", "шестнадцать", "семнадцать", "восемнадцать", "девятнадцать"}
+	tensWords = [10]string{"", "", "двадцать", "тридцать", "сорок", "пятьдесят",
+		"шестьдесят", "семьдесят", "восемьдесят", "девяносто"}
+	hundredWords = [10]string{"", "сто", "двести", "триста", "четыреста", "пятьсот",
+		"шестьсот", "семьсот", "восемьсот", "девятьсот"}
+)
+
+// scale is the name of the group of three digits: тысяча, миллион, ...
+type scale struct {
+	forms  PluralForms
+	gender Gender
+}
+
+// scales of the groups, starting from thousands. Money fits int64 kopeks, so quadrillions are enough.
+var scales = []scale{
+	{PluralForms{"тысяча", "тысячи", "тысяч"}, Feminine},
+	{PluralForms{"миллион", "миллиона", "миллионов"}, Masculine},
+	{PluralForms{"миллиард", "миллиарда", "миллиардов"}, Masculine},
+	{PluralForms{"триллион", "триллиона", "триллионов"}, Masculine},
+	{PluralForms{"квадриллион", "квадриллиона", "квадриллионов"}, Masculine},
+}
+
+// NumberToWords writes the non-negative number [n] in Russian words agreeing with the [gender] of the counted noun,
+// e.g. NumberToWords(1201, Feminine) is "одна тысяча двести одна".
+func NumberToWords(n int64, gender Gender) string {
+	if n == 0 {
+		return "ноль"
+	}
+
+	// groups of three digits, from the lowest one
+	var groups []int64
+	for ; n > 0; n /= 1000 {
+		groups = append(groups, n%1000)
+	}
+
+	var words []string
+	for i := len(groups) - 1; i >= 0; i-- {
+		if groups[i] == 0 {
+			continue
+		}
+		if i == 0 {
+			words = append(words, groupWords(groups[i], gender)...)
+			continue
+		}
+		s := scales[i-1]
+		words = append(words, groupWords(groups[i], s.gender)...)
+		words = append(words, s.forms.Plural(groups[i]))
+	}
+	return strings.Join(words, " ")
+}
+
+// groupWords writes the number from 1 to 999
+func groupWords(n int64, gender Gender) []string {
+	var words []string
+	if h := n / 100; h > 0 {
+		words = append(words, hundredWords[h])
+	}
+	switch t := n % 100; {
+	case t >= 10 && t <= 19:
+		words = append(words, teenWords[t-10])
+	default:
+		if t/10 > 0 {
+			words = append(words, tensWords[t/10])
+		}
+		if t%10 > 0 {
+			words = append(words, unitWords[gender][t%10])
+		}
+	}
+	return words
+}
+
+// Words returns the amount as it is written on payment documents: rubles in words and kopeks in digits,
+// e.g. "Одна тысяча двести рублей 50 копеек".
+func (m Money) Words() string {
+	rubles, kopeks := m.Rubles(), m.Kopeks()
+	text := fmt.Sprintf("%s %s %02d %s",
+		NumberToWords(rubles, Masculine), RubleForms.Plural(rubles), kopeks, KopekForms.Plural(kopeks))
+	return capitalize(text)
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
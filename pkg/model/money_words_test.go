@@ -0,0 +1,71 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumberToWords(t *testing.T) {
+	tests := []struct {
+		n      int64
+		gender Gender
+		want   string
+	}{
+		{0, Masculine, "ноль"},
+		{1, Masculine, "один"},
+		{1, Feminine, "одна"},
+		{2, Feminine, "две"},
+		{11, Masculine, "одиннадцать"},
+		{21, Feminine, "двадцать одна"},
+		{40, Masculine, "сорок"},
+		{100, Masculine, "сто"},
+		{512, Masculine, "пятьсот двенадцать"},
+		{1000, Masculine, "одна тысяча"},
+		{2002, Masculine, "две тысячи два"},
+		{5000, Masculine, "пять тысяч"},
+		{11000, Masculine, "одиннадцать тысяч"},
+		{21000, Masculine, "двадцать одна тысяча"},
+		{1201, Feminine, "одна тысяча двести одна"},
+		{1000000, Masculine, "один миллион"},
+		{2000001, Masculine, "два миллиона один"},
+		{1002003004, Masculine, "один миллиард два миллиона три тысячи четыре"},
+		{5_000_000_000_000_000, Masculine, "пять квадриллионов"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			require.Equal(t, tt.want, NumberToWords(tt.n, tt.gender))
+		})
+	}
+}
+
+func TestPluralForms(t *testing.T) {
+	for n, want := range map[int64]string{
+		0: "рублей", 1: "рубль", 2: "рубля", 4: "рубля", 5: "рублей", 11: "рублей", 12: "рублей",
+		14: "рублей", 21: "рубль", 22: "рубля", 111: "рублей", 101: "рубль", 1000: "рублей",
+	} {
+		require.Equal(t, want, RubleForms.Plural(n), n)
+	}
+}
+
+func TestMoneyWords(t *testing.T) {
+	tests := []struct {
+		amount Money
+		want   string
+	}{
+		{NewMoney(1200, 50), "Одна тысяча двести рублей 50 копеек"},
+		{NewMoney(0, 0), "Ноль рублей 00 копеек"},
+		{NewMoney(1, 1), "Один рубль 01 копейка"},
+		{NewMoney(2, 2), "Два рубля 02 копейки"},
+		{NewMoney(21, 21), "Двадцать один рубль 21 копейка"},
+		{NewMoney(10150, 40), "Десять тысяч сто пятьдесят рублей 40 копеек"},
+		{NewMoney(3_000_000, 11), "Три миллиона рублей 11 копеек"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.amount.Words())
+		})
+	}
+}
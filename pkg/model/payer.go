@@ -6,5 +6,5 @@ type Payer struct {
 	Purpose  string `json:"Назначение"`
 	CBC      string `json:"КБК"`
 	OKTMO    string `json:"ОКТМО"`
-	Sum      string `json:"Сумма"`
+	Sum      Money  `json:"Сумма"`
 }
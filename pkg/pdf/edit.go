@@ -16,28 +16,36 @@ const (
 	DefaultFontPath = "static/fonts/Arial.ttf"
 
 	FontStyleRegular = ""
-
-	PaymentAmountFontSize    = 8
-	PayerCredentialsFontSize = 9
 )
 
-// MultilineTextLineSpacing is the space between lines inside the FramePayerCredentialsTop and FramePayerCredentialsBottom frames.
-// It is used since these credentials must be separated on 2 lines, but the pdft package does not split the text that does not fit the frame.
-const MultilineTextLineSpacing = 12
-
-// Canvas object if a wrapper for pdft.PDFt, that refers to one pdf object and provides following functionality:
-// insert payer credentials into pdf receipt, insert payment amount and payment QR Code.
+// Canvas object if a wrapper for pdft.PDFt, that refers to one pdf object and fills the frames of its Layout:
+// payer credentials, payment amount, payment QR Code, etc.
 // DebugMode true, if it is needed to show frames Frame on the PDF receipt
 type Canvas struct {
 	pdf       *pdft.PDFt
+	template  *ReceiptTemplate // template the pdf is put over when saved, nil if the pdf is the template itself
+	layout    *Layout
+	measurer  *textMeasurer
 	debugMode bool
+
+	// warnings are the texts printed over their frames, see OverflowWarn
+	warnings []*TextOverflowError
 }
 
-// NewCanvasFromTemplate is a constructor for Canvas, loading given template.
+// NewCanvasFromTemplate is a constructor for Canvas, loading given template. The template is filled by DefaultLayout.
 func NewCanvasFromTemplate(pdfSrc, fontPath string, debugMode bool) (*Canvas, error) {
+	return NewCanvasWithLayout(pdfSrc, fontPath, DefaultLayout(), debugMode)
+}
+
+// NewCanvasWithLayout is a constructor for Canvas, loading given template filled by [layout].
+// Nil layout means DefaultLayout.
+func NewCanvasWithLayout(pdfSrc, fontPath string, layout *Layout, debugMode bool) (*Canvas, error) {
 	if fontPath == "" {
 		fontPath = DefaultFontPath
 	}
+	if layout == nil {
+		layout = DefaultLayout()
+	}
 	var pdf pdft.PDFt
 	if err := pdf.Open(pdfSrc); err != nil {
 		return nil, fmt.Errorf("failed to open PDF: %w", err)
@@ -51,7 +59,11 @@ func NewCanvasFromTemplate(pdfSrc, fontPath string, debugMode bool) (*Canvas, er
 	if err := pdf.AddFont(DefaultFontName, absFontPath); err != nil {
 		return nil, fmt.Errorf("failed to upload font: %w", err)
 	}
-	return &Canvas{pdf: &pdf, debugMode: debugMode}, nil
+	measurer, err := newTextMeasurer(absFontPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load font metrics: %w", err)
+	}
+	return &Canvas{pdf: &pdf, layout: layout, measurer: measurer, debugMode: debugMode}, nil
 }
 
 // GeneratePersonalReceipt fills the receipt pattern [pdfSrc] wilt payer's credentials [payerData] and
@@ -77,7 +89,7 @@ func GeneratePersonalReceipt(pdfSrc, pdfDst, qrImgPath, fontPath string, payerDa
 	}
 
 	// Save the filled file
-	err = canvas.pdf.Save(pdfDst)
+	err = canvas.Save(pdfDst)
 	if err != nil {
 		return errs.Wrap(errs.System, fmt.Sprintf("failed to save filled pdf receipt `%s`", pdfDst), err)
 	}
@@ -85,99 +97,149 @@ func GeneratePersonalReceipt(pdfSrc, pdfDst, qrImgPath, fontPath string, payerDa
 	return nil
 }
 
+// Fill prints payer's data into the frames of the canvas layout. Text frames get their text templates
+// executed with NewFrameData, QR Code frames get the [qrImg] image.
 func (c *Canvas) Fill(payer model.Payer, qrImg []byte) error {
-	// Write payer's credentials to the receipt
-	err := c.insertCredentials(payer)
-	if err != nil {
-		return fmt.Errorf("failed to insert credentials: %w", err)
-	}
-	// Write amount of the payment
-	err = c.insertPaymentAmount(payer.Sum)
-	if err != nil {
-		return fmt.Errorf("failed to insert payment amount: %w", err)
-	}
-	// Draw QR Code to the receipt
-	err = c.insertQrCode(qrImg)
-	if err != nil {
-		return fmt.Errorf("failed to insert qr code: %w", err)
+	data := NewFrameData(payer)
+
+	for _, frame := range c.layout.Frames {
+		switch frame.Kind {
+		case FrameKindQrCode:
+			if err := c.insertQrCode(frame, qrImg); err != nil {
+				return fmt.Errorf("failed to insert qr code into frame `%s`: %w", frame.Name, err)
+			}
+		case FrameKindText:
+			text, err := frame.text(data)
+			if err != nil {
+				return err
+			}
+			if err := c.printToFrames(frame, text); err != nil {
+				return fmt.Errorf("failed to print text of frame `%s`: %w", frame.Name, err)
+			}
+		}
 	}
 	return nil
 }
 
+// Warnings returns the texts that did not fit their frames with OverflowWarn policy and were printed anyway.
+func (c *Canvas) Warnings() []*TextOverflowError {
+	return c.warnings
+}
+
+// Save writes the filled receipt to [path].
 func (c *Canvas) Save(path string) error {
+	if c.template != nil {
+		return c.template.save(c.pdf, path)
+	}
 	return c.pdf.Save(path)
 }
 
 // insertQrCode inserts a QR Code image of the payment, containing all the credentials provided in the receipt.
-// Locates the image inside specified frame on the page.
-func (c *Canvas) insertQrCode(qrImg []byte) error {
-	x, y, w, h := FrameQrCode.InnerRect()
-	return c.pdf.InsertImg(qrImg, 1, x, y, w, h)
-}
-
-// insertCredentials inserts a text, containing payer's credentials into a receipt.
-// Sets the specified font size before and locates the text at specified position on the page.
-func (c *Canvas) insertCredentials(payerData model.Payer) error {
-	payerInfoText := prettifyCredentialsString(formatPayerInfo(payerData), "Назначение")
-
-	// print the text inside specified frames on the page
-	err := c.printToFrames([]Frame{FramePayerCredentialsTop, FramePayerCredentialsBottom}, payerInfoText, PayerCredentialsFontSize, true)
-	if err != nil {
-		return fmt.Errorf("failed to print payer credentials text: %w", err)
+// Locates the image inside every copy of the frame on the page.
+func (c *Canvas) insertQrCode(frame LayoutFrame, qrImg []byte) error {
+	for _, rect := range frame.Rects() {
+		if c.debugMode {
+			_ = rect.Debug(c.pdf, 1)
+		}
+		x, y, w, h := rect.InnerRect()
+		if err := c.pdf.InsertImg(qrImg, 1, x, y, w, h); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// insertPaymentAmount inserts a text, containing payment amount into a receipt.
-// Sets the specified font size before and locates the text at specified position on the page.
-func (c *Canvas) insertPaymentAmount(amount string) error {
-	amountText := formatAmount(amount)
-
-	// print the text inside specified frames on the page
-	err := c.printToFrames([]Frame{FramePaymentAmountTop, FramePaymentAmountBottom}, amountText, PaymentAmountFontSize, false)
+// printToFrames fits the text into the frame and prints it inside every copy of the frame.
+// The text that does not fit is reported according to the frame overflow policy.
+func (c *Canvas) printToFrames(frame LayoutFrame, text string) error {
+	_, _, w, h := frame.Rect.InnerRect()
+	fitted, err := c.fitText(frame, text, w, h)
 	if err != nil {
-		return fmt.Errorf("failed to print amount text: %w", err)
+		return err
+	}
+	if fitted.overflow {
+		overflowErr := &TextOverflowError{Frame: frame.Name, Text: text, FontSize: fitted.size}
+		if frame.Overflow == OverflowError {
+			return overflowErr
+		}
+		c.warnings = append(c.warnings, overflowErr)
 	}
-	return nil
-}
 
-func (c *Canvas) printToFrames(frames []Frame, text string, fontSize int, multiline bool) error {
-	if err := c.pdf.SetFont(DefaultFontName, FontStyleRegular, fontSize); err != nil {
+	if err := c.pdf.SetFont(DefaultFontName, FontStyleRegular, fitted.size); err != nil {
 		return fmt.Errorf("failed to set font: %w", err)
 	}
-
-	for _, frame := range frames {
-		if err := c.printText(frame, text, multiline); err != nil {
+	for _, rect := range frame.Rects() {
+		if err := c.printText(rect, fitted, frame.Align); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// printText is a utility function that prints text inside a given frame on the PDF receipt page.
-// It uses the `pdf` object to insert the provided `text` into the specified `frame`, horizontally centered.
-// If `multiline` is true, the text is split by line breaks and printed line by line,
-// increasing the y-coordinate by `MultilineTextLineSpacing` for each subsequent line.
-// If `debugMode` is true, the frame’s borders are drawn on the PDF to visualize positioning.
-func (c *Canvas) printText(frame Frame, text string, multiline bool) error {
-	if c.debugMode {
-		_ = frame.Debug(c.pdf, 1)
-	}
+// fittedText is the text prepared to be printed inside the frame
+type fittedText struct {
+	lines       []string
+	size        int     // font size
+	lineSpacing float64 // distance between the lines for the font size
+	overflow    bool    // the text does not fit the frame even with the minimal font size
+}
 
-	x, y, w, h := frame.InnerRect()
+// fitText finds the largest font size between the frame font size and its minimal font size,
+// which lets the text fit the inner rectangle [w]x[h] of the frame. Lines of multiline frames are wrapped
+// to the frame width, single line frames are only reduced. The text fits if its lines are not wider than the frame
+// and the baseline of the last line is inside it: descenders may take the bottom margin.
+func (c *Canvas) fitText(frame LayoutFrame, text string, w, h float64) (fittedText, error) {
+	var fitted fittedText
+	for size := frame.FontSize; size >= frame.MinFontSize; size-- {
+		fitted = fittedText{size: size, lineSpacing: frame.LineSpacing * float64(size) / float64(frame.FontSize)}
+
+		paragraphs := []string{text}
+		if frame.Multiline {
+			paragraphs = strings.Split(text, "\n")
+		}
+		for _, paragraph := range paragraphs {
+			if !frame.Multiline {
+				fitted.lines = append(fitted.lines, paragraph)
+				continue
+			}
+			lines, err := c.measurer.wrap(paragraph, float64(size), w)
+			if err != nil {
+				return fittedText{}, fmt.Errorf("failed to wrap text: %w", err)
+			}
+			fitted.lines = append(fitted.lines, lines...)
+		}
 
-	if multiline {
-		for _, line := range strings.Split(text, "\n") {
-			if err := c.pdf.Insert(line, 1, x, y, w, h, pdft.Center, nil); err != nil {
-				return err // сразу вернуть, а не продолжать
+		fits := float64(len(fitted.lines)-1)*fitted.lineSpacing+c.measurer.ascent(float64(size)) <= h
+		for _, line := range fitted.lines {
+			width, err := c.measurer.width(line, float64(size))
+			if err != nil {
+				return fittedText{}, fmt.Errorf("failed to measure text: %w", err)
 			}
-			y += MultilineTextLineSpacing
+			fits = fits && width <= w
 		}
-	} else {
-		if err := c.pdf.Insert(text, 1, x, y, w, h, pdft.Center, nil); err != nil {
-			return err
+		if fits {
+			return fitted, nil
 		}
 	}
+	fitted.overflow = true
+	return fitted, nil
+}
+
+// printText is a utility function that prints the fitted text inside a given rectangle on the PDF receipt page.
+// It uses the `pdf` object to insert every line into the specified `rect`, aligned as the frame requires,
+// increasing the y-coordinate by the line spacing for each subsequent line.
+// If `debugMode` is true, the frame’s borders are drawn on the PDF to visualize positioning.
+func (c *Canvas) printText(rect Frame, text fittedText, align TextAlign) error {
+	if c.debugMode {
+		_ = rect.Debug(c.pdf, 1)
+	}
 
+	x, y, w, h := rect.InnerRect()
+	for _, line := range text.lines {
+		if err := c.pdf.Insert(line, 1, x, y, w, h, align.pdftAlign(), nil); err != nil {
+			return err // сразу вернуть, а не продолжать
+		}
+		y += text.lineSpacing
+	}
 	return nil
 }
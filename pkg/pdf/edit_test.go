@@ -0,0 +1,140 @@
+package pdf
+
+import (
+	"li-acc/internal/errs"
+	"li-acc/pkg/model"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCanvas(t *testing.T, layout *Layout) *Canvas {
+	t.Helper()
+	data, err := RenderReceiptTemplate(testFontPath, orgFix)
+	require.NoError(t, err)
+	template := filepath.Join(t.TempDir(), "template.pdf")
+	require.NoError(t, os.WriteFile(template, data, 0o644))
+
+	canvas, err := NewCanvasWithLayout(template, testFontPath, layout, false)
+	require.NoError(t, err)
+	return canvas
+}
+
+func TestTextMeasurer_Wrap(t *testing.T) {
+	m, err := newTextMeasurer(testFontPath)
+	require.NoError(t, err)
+
+	width, err := m.width("Сумма", 10)
+	require.NoError(t, err)
+	require.InDelta(t, 2*width, func() float64 { w, _ := m.width("Сумма", 20); return w }(), 1e-9)
+
+	lines, err := m.wrap("КБК: 111;   ОКТМО: 222", 9, 1000)
+	require.NoError(t, err)
+	require.Equal(t, []string{"КБК: 111;   ОКТМО: 222"}, lines, "the text fitting the width is kept as is")
+
+	w, err := m.width("КБК: 111;   ОКТМО:", 9)
+	require.NoError(t, err)
+	lines, err = m.wrap("ЛС: 1;   КБК: 111;   ОКТМО: 222", 9, w+1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ЛС: 1;   КБК: 111;", "ОКТМО: 222"}, lines)
+	for _, line := range lines {
+		lw, err := m.width(line, 9)
+		require.NoError(t, err)
+		require.LessOrEqual(t, lw, w+1)
+	}
+}
+
+func TestCanvas_FitText(t *testing.T) {
+	frame := LayoutFrame{Name: "purpose", FontSize: 9, MinFontSize: 7, LineSpacing: 12, Multiline: true}
+	const w, h = 200, 20 // two lines: 12pt line spacing and 6.5pt of the 9pt font ascent
+
+	canvas := newTestCanvas(t, nil)
+	long := strings.Repeat("доп питание ", 8)
+
+	tests := []struct {
+		name         string
+		text         string
+		multiline    bool
+		wantSize     int
+		wantLines    int
+		wantOverflow bool
+	}{
+		{name: "fits", text: "Назначение: питание", multiline: true, wantSize: 9, wantLines: 1},
+		{name: "wrapped", text: "ФИО: Иванов Иван\n" + strings.Repeat("доп питание ", 3), multiline: true, wantSize: 9, wantLines: 2},
+		{name: "reduced to fit two lines", text: long, multiline: true, wantSize: 8, wantLines: 2},
+		{name: "does not fit", text: long + long, multiline: true, wantSize: 7, wantOverflow: true},
+		{name: "single line reduced", text: strings.Repeat("питание ", 6), wantSize: 8, wantLines: 1},
+		{name: "single line does not fit", text: long, wantSize: 7, wantLines: 1, wantOverflow: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := frame
+			f.Multiline = tt.multiline
+			fitted, err := canvas.fitText(f, strings.TrimSpace(tt.text), w, h)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantSize, fitted.size)
+			require.Equal(t, tt.wantOverflow, fitted.overflow)
+			require.InDelta(t, 12*float64(tt.wantSize)/9, fitted.lineSpacing, 1e-9)
+			if tt.wantLines > 0 {
+				require.Len(t, fitted.lines, tt.wantLines, fitted.lines)
+			}
+		})
+	}
+}
+
+func TestCanvas_FillOverflow(t *testing.T) {
+	payer := model.Payer{
+		PersAcc:  "123456",
+		CHILDFIO: "Зубенко Михаил Петрович",
+		Purpose:  strings.Repeat("доп питание за сентябрь ", 20),
+		CBC:      "82100000000000000131",
+		OKTMO:    "98790098",
+		Sum:      model.NewMoney(100, 0),
+	}
+	qrImg, err := generateFrameImage(10, 10, colorBlack)
+	require.NoError(t, err)
+
+	t.Run("error", func(t *testing.T) {
+		canvas := newTestCanvas(t, nil)
+		err := canvas.Fill(payer, qrImg)
+		var overflow *TextOverflowError
+		require.ErrorAs(t, err, &overflow)
+		require.Equal(t, "payer_credentials", overflow.Frame)
+		require.Equal(t, 7, overflow.FontSize)
+		require.True(t, errs.IsUserError(err))
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		layout, err := ParseLayout([]byte(`{"name": "warn", "frames": [
+			{"name": "credentials", "kind": "text", "rect": {"x": 161, "y": 104, "w": 381, "h": 36, "margin": {"top": 14, "right": 4, "bottom": 2, "left": 4}},
+			 "text": "{{.Credentials}}", "font_size": 9, "min_font_size": 7, "multiline": true, "overflow": "warn"}
+		]}`))
+		require.NoError(t, err)
+		canvas := newTestCanvas(t, layout)
+		require.NoError(t, canvas.Fill(payer, qrImg))
+		require.Len(t, canvas.Warnings(), 1)
+		require.Equal(t, "credentials", canvas.Warnings()[0].Frame)
+	})
+
+	t.Run("amount in words warns", func(t *testing.T) {
+		canvas := newTestCanvas(t, nil)
+		payer := payer
+		payer.Purpose = "10a доп питание сент"
+		payer.Sum = model.NewMoney(77_777_777_777, 77)
+		require.NoError(t, canvas.Fill(payer, qrImg))
+		require.Len(t, canvas.Warnings(), 1)
+		require.Equal(t, "payment_amount_words", canvas.Warnings()[0].Frame)
+	})
+
+	t.Run("fits", func(t *testing.T) {
+		canvas := newTestCanvas(t, nil)
+		payer := payer
+		payer.Purpose = "10a доп питание сент"
+		require.NoError(t, canvas.Fill(payer, qrImg))
+		require.Empty(t, canvas.Warnings())
+	})
+}
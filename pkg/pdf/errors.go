@@ -0,0 +1,26 @@
+package pdf
+
+import (
+	"fmt"
+	"li-acc/internal/errs"
+)
+
+// TextOverflowError is returned when the text does not fit the layout frame even with its minimal font size,
+// e.g. the payment purpose is too long. The data must be shortened, so it is the user error.
+type TextOverflowError struct {
+	Frame    string // name of the LayoutFrame
+	Text     string
+	FontSize int // the minimal font size the text was fitted with
+}
+
+func (e *TextOverflowError) Error() string {
+	return fmt.Sprintf("text does not fit frame `%s` with font size %d: %q", e.Frame, e.FontSize, e.Text)
+}
+
+func (e *TextOverflowError) Kind() errs.Kind {
+	return errs.User
+}
+
+func (e *TextOverflowError) Unwrap() error {
+	return nil
+}
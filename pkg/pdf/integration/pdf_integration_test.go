@@ -5,6 +5,7 @@ package integration
 import (
 	"flag"
 	"li-acc/pkg/pdf"
+	"li-acc/pkg/qr"
 	"os"
 	"path/filepath"
 	"testing"
@@ -27,7 +28,7 @@ func TestGeneratePersonalReceipt_success(t *testing.T) {
 		Purpose:  "10a доп питание сент",
 		CBC:      "82100000000000000131",
 		OKTMO:    "98790098",
-		Sum:      "10150.40",
+		Sum:      model.NewMoney(10150, 40),
 	}
 
 	// origin pattern (must be in в testdata)
@@ -77,6 +78,102 @@ func TestGeneratePersonalReceipt_success(t *testing.T) {
 	}
 }
 
+// receipts embed the QR code rendered in memory as PNG
+func TestCanvasFill_renderedQrCode(t *testing.T) {
+	payer := model.Payer{
+		PersAcc:  "123456",
+		CHILDFIO: "Зубенко Михаил Петрович",
+		Purpose:  "10a доп питание сент",
+		CBC:      "82100000000000000131",
+		OKTMO:    "98790098",
+		Sum:      model.NewMoney(10150, 40),
+	}
+	org := model.Organization{
+		Name:        "МАОУ Тест",
+		PersonalAcc: "20202020202020202020",
+		BankName:    "ОТДЕЛЕНИЕ-НБ РЕСПУБЛИКА ТАТАРСТАН",
+		BIC:         "999999999",
+		CorrespAcc:  "1111111111",
+	}
+
+	pdfTemplatePath := testPath("template.pdf")
+	if _, err := os.Stat(pdfTemplatePath); err != nil {
+		t.Skipf("missing template file: %s", pdfTemplatePath)
+	}
+
+	code := qr.NewQrPattern(org)
+	payload, err := code.GetPayersQrDataString(payer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	img, err := code.RenderQRCode(payload, qr.DefaultRenderOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	canvas, err := pdf.NewCanvasFromTemplate(pdfTemplatePath, testPath("Arial.ttf"), *debugMode)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := canvas.Fill(payer, img); err != nil {
+		t.Fatalf("failed to fill receipt with PNG QR code: %v", err)
+	}
+
+	outDir := t.TempDir()
+	if *keepPDF {
+		outDir = "./testdata/out"
+		_ = os.MkdirAll(outDir, 0o755)
+	}
+	pdfDst := filepath.Join(outDir, "receipt-png-qr.pdf")
+	if err := canvas.Save(pdfDst); err != nil {
+		t.Fatalf("failed to save receipt: %v", err)
+	}
+	if info, err := os.Stat(pdfDst); err != nil || info.Size() == 0 {
+		t.Fatalf("pdf file not created: %v", err)
+	}
+}
+
+func TestGeneratePersonalReceipt_nativeTemplate(t *testing.T) {
+	payer := model.Payer{
+		PersAcc:  "123456",
+		CHILDFIO: "Зубенко Михаил Петрович",
+		Purpose:  "10a доп питание сент",
+		CBC:      "82100000000000000131",
+		OKTMO:    "98790098",
+		Sum:      model.NewMoney(10150, 40),
+	}
+	org := model.Organization{
+		Name:        "МАОУ Тест",
+		PersonalAcc: "20202020202020202020",
+		BankName:    "ОТДЕЛЕНИЕ-НБ РЕСПУБЛИКА ТАТАРСТАН",
+		BIC:         "999999999",
+		PayeeINN:    "1657032924",
+		KPP:         "165701001",
+	}
+	fontPath := testPath("Arial.ttf")
+
+	outDir := t.TempDir()
+	if *keepPDF {
+		outDir = "./testdata/out"
+		_ = os.MkdirAll(outDir, 0o755)
+	}
+
+	// the template is drawn without the converter, and the canvas fills it as the converted one
+	templatePath := filepath.Join(outDir, "native-template.pdf")
+	if err := pdf.GenerateReceiptTemplate(templatePath, fontPath, org); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pdfDst := filepath.Join(outDir, "receipt-native.pdf")
+	err := pdf.GeneratePersonalReceipt(templatePath, pdfDst, testPath("qr-code.jpg"), fontPath, payer, *debugMode)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info, err := os.Stat(pdfDst); err != nil || info.Size() == 0 {
+		t.Fatalf("pdf file not created: %v", err)
+	}
+}
+
 func TestGeneratePersonalReceipt_missingQrFile(t *testing.T) {
 	payer := model.Payer{
 		PersAcc:  "123456",
@@ -84,7 +181,7 @@ func TestGeneratePersonalReceipt_missingQrFile(t *testing.T) {
 		Purpose:  "test",
 		CBC:      "123454656543",
 		OKTMO:    "9879098",
-		Sum:      "10150.40",
+		Sum:      model.NewMoney(10150, 40),
 	}
 
 	pdfTemplatePath := testPath("template.pdf")
@@ -117,3 +214,88 @@ func TestGeneratePersonalReceipt_missingQrFile(t *testing.T) {
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (filepath.Base(s) == substr || (len(s) > len(substr) && (s[:len(substr)] == substr || s[len(s)-len(substr):] == substr)))
 }
+
+// receipts filled from the template converted from Excel are merged into the print file, two per page
+func TestMergeReceiptsForPrint_convertedTemplate(t *testing.T) {
+	payer := model.Payer{
+		PersAcc:  "123456",
+		CHILDFIO: "Зубенко Михаил Петрович",
+		Purpose:  "10a доп питание сент",
+		CBC:      "82100000000000000131",
+		OKTMO:    "98790098",
+		Sum:      model.NewMoney(10150, 40),
+	}
+
+	pdfTemplatePath := testPath("template.pdf")
+	if _, err := os.Stat(pdfTemplatePath); err != nil {
+		t.Skipf("missing template file: %s", pdfTemplatePath)
+	}
+
+	outDir := t.TempDir()
+	if *keepPDF {
+		outDir = "./testdata/out"
+		_ = os.MkdirAll(outDir, 0o755)
+	}
+
+	var receipts []string
+	for _, name := range []string{"first", "second", "third"} {
+		pdfDst := filepath.Join(outDir, "receipt-"+name+".pdf")
+		err := pdf.GeneratePersonalReceipt(pdfTemplatePath, pdfDst, testPath("qr-code.jpg"), testPath("Arial.ttf"), payer, *debugMode)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		receipts = append(receipts, pdfDst)
+	}
+
+	printPath := filepath.Join(outDir, "receipts-print.pdf")
+	if err := pdf.MergeReceiptsForPrint(printPath, receipts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info, err := os.Stat(printPath); err != nil || info.Size() == 0 {
+		t.Fatalf("print file not created: %v", err)
+	}
+}
+
+func TestReceiptTemplate_convertedTemplate(t *testing.T) {
+	pdfTemplatePath := testPath("template.pdf")
+	if _, err := os.Stat(pdfTemplatePath); err != nil {
+		t.Skipf("missing template file: %s", pdfTemplatePath)
+	}
+	img, err := os.ReadFile(testPath("qr-code.jpg"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	template, err := pdf.LoadReceiptTemplate(pdfTemplatePath, testPath("Arial.ttf"), nil, *debugMode)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	outDir := t.TempDir()
+	if *keepPDF {
+		outDir = "./testdata/out"
+		_ = os.MkdirAll(outDir, 0o755)
+	}
+
+	var receipts []string
+	for _, persAcc := range []string{"123456", "123457"} {
+		canvas, err := template.NewCanvas()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		payer := model.Payer{PersAcc: persAcc, CHILDFIO: "Зубенко Михаил Петрович", Purpose: "10a доп питание сент", Sum: model.NewMoney(10150, 40)}
+		if err := canvas.Fill(payer, img); err != nil {
+			t.Fatalf("failed to fill receipt: %v", err)
+		}
+		pdfDst := filepath.Join(outDir, "receipt-loaded-template-"+persAcc+".pdf")
+		if err := canvas.Save(pdfDst); err != nil {
+			t.Fatalf("failed to save receipt: %v", err)
+		}
+		receipts = append(receipts, pdfDst)
+	}
+
+	// the receipts drawn over the loaded template are merged for print as any other receipts
+	if err := pdf.MergeReceiptsForPrint(filepath.Join(outDir, "receipts-loaded-template-print.pdf"), receipts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
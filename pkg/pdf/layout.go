@@ -1,12 +1,29 @@
 package pdf
 
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"li-acc/internal/errs"
+	"li-acc/pkg/model"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/signintech/pdft"
+)
+
 // Frame is a rectangle that represents some field on pdf receipt.
 // In case of current receipts is used to set coordinates of cells that contain
 // credentials (in Excel format they are in column 2).
 // MarginXxxx fields are used to set the margin for text inside the frame.
 type Frame struct {
-	X, Y, W, H float64
-	Margin     Margin
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	W      float64 `json:"w"`
+	H      float64 `json:"h"`
+	Margin Margin  `json:"margin"`
 }
 
 // InnerRect returns the coordinates of the rectangle inside the frame
@@ -20,77 +37,232 @@ func (f Frame) InnerRect() (x, y, w, h float64) {
 
 // Margin defines padding (in points) inside a Frame.
 type Margin struct {
-	Top, Right, Bottom, Left float64
+	Top    float64 `json:"top"`
+	Right  float64 `json:"right"`
+	Bottom float64 `json:"bottom"`
+	Left   float64 `json:"left"`
 }
 
-// Offsets (by Y-coordinate) for certain frames relative to the main frame MainFrame.
-// All offsets are selected empirically.
-const (
-	OffsetPayerCredentialsTopY    = 90
-	OffsetPayerCredentialsBottomY = 281
+// FrameKind defines what is printed inside the LayoutFrame.
+type FrameKind string
 
-	OffsetPaymentAmountTopY    = 125
-	OffsetPaymentAmountBottomY = 316
+const (
+	FrameKindText   FrameKind = "text" // text made from LayoutFrame.Text template
+	FrameKindQrCode FrameKind = "qr"   // QR Code image of the payment
 )
 
-// ----- Margins -----
-// Margins are chosen empirically for each group of fields.
-var (
-	// MarginPayerCredentials is used inside payer credentials frames
-	// (top and bottom parts). Small padding on all sides to fit multiline text.
-	MarginPayerCredentials = Margin{Top: 14, Right: 4, Bottom: 2, Left: 4}
+// TextAlign is horizontal alignment of the text inside the frame.
+type TextAlign string
 
-	// MarginPaymentAmount is used inside payment amount frames
-	// (top and bottom parts). Wide left/right padding to center the text.
-	MarginPaymentAmount = Margin{Top: 17, Right: 104, Bottom: 2, Left: 104}
+const (
+	AlignLeft   TextAlign = "left"
+	AlignCenter TextAlign = "center"
+	AlignRight  TextAlign = "right"
 )
 
-// ----- FRAMES -----
+// pdftAlign returns the alignment flag of pdft.PDFt.Insert
+func (a TextAlign) pdftAlign() int {
+	switch a {
+	case AlignLeft:
+		return pdft.Left
+	case AlignRight:
+		return pdft.Right
+	default:
+		return pdft.Center
+	}
+}
 
-// Frames contain coordinates and margins of itself. All numbers are selected empirically.
-var (
-	// MainFrame is frame containing all cells where some data will be shown:
-	// cell with organization credentials, cell with payer credentials, etc. These cells are in one column.
-	MainFrame = Frame{X: 161, Y: 14, W: 381, H: 383}
-)
+// OverflowPolicy defines what is done with the text that does not fit the frame even with the minimal font size.
+type OverflowPolicy string
 
-// Payer credentials section (two cells: top and bottom).
-var (
-	// FramePayerCredentialsTop contains the top part of payer credentials.
-	FramePayerCredentialsTop = Frame{
-		X:      MainFrame.X,
-		Y:      MainFrame.Y + OffsetPayerCredentialsTopY,
-		W:      MainFrame.W,
-		H:      36,
-		Margin: MarginPayerCredentials}
-
-	// FramePayerCredentialsBottom contains the bottom part of payer credentials.
-	FramePayerCredentialsBottom = Frame{
-		X:      MainFrame.X,
-		Y:      MainFrame.Y + OffsetPayerCredentialsBottomY,
-		W:      MainFrame.W,
-		H:      36,
-		Margin: MarginPayerCredentials}
+const (
+	OverflowError OverflowPolicy = "error" // the receipt is not made, Canvas.Fill returns TextOverflowError
+	OverflowWarn  OverflowPolicy = "warn"  // the text is printed with the minimal font size, see Canvas.Warnings
 )
 
-// Payment amount section (two cells: top and bottom).
-var (
-	// FramePaymentAmountTop contains the top part of the payment amount field.
-	FramePaymentAmountTop = Frame{
-		X:      MainFrame.X,
-		Y:      MainFrame.Y + OffsetPaymentAmountTopY,
-		W:      MainFrame.W,
-		H:      26,
-		Margin: MarginPaymentAmount}
-
-	// FramePaymentAmountBottom contains the bottom part of the payment amount field.
-	FramePaymentAmountBottom = Frame{
-		X:      MainFrame.X,
-		Y:      MainFrame.Y + OffsetPaymentAmountBottomY,
-		W:      MainFrame.W,
-		H:      26,
-		Margin: MarginPaymentAmount}
-)
+// LayoutFrame is a named field of the receipt: where it is placed and what is printed inside.
+type LayoutFrame struct {
+	Name string    `json:"name"`
+	Kind FrameKind `json:"kind"`
+	Rect Frame     `json:"rect"`
+
+	// RepeatY are Y offsets of the frame copies with the same content, e.g. [0, 191] prints it in both
+	// parts of the receipt. Empty list prints the frame once.
+	RepeatY []float64 `json:"repeat_y,omitempty"`
 
-// FrameQrCode defines the square area for placing the QR code in the receipt.
-var FrameQrCode = Frame{X: 35, Y: 270, W: 120, H: 120}
+	// Text is the text/template executed with FrameData, e.g. "{{.Amount}}" or "ФИО: {{.Payer.CHILDFIO}}".
+	// Used only by text frames, as the following fields.
+	Text        string         `json:"text,omitempty"`
+	FontSize    int            `json:"font_size,omitempty"`
+	MinFontSize int            `json:"min_font_size,omitempty"` // the font is reduced down to it to fit the text, FontSize if not set
+	Align       TextAlign      `json:"align,omitempty"`         // center by default
+	Multiline   bool           `json:"multiline,omitempty"`     // print lines of the text separately, wrapping long ones
+	LineSpacing float64        `json:"line_spacing,omitempty"`  // distance between multiline text lines
+	Overflow    OverflowPolicy `json:"overflow,omitempty"`      // error by default
+
+	tmpl *template.Template
+}
+
+// Rects returns the rectangles of all copies of the frame.
+func (f LayoutFrame) Rects() []Frame {
+	if len(f.RepeatY) == 0 {
+		return []Frame{f.Rect}
+	}
+	rects := make([]Frame, len(f.RepeatY))
+	for i, dy := range f.RepeatY {
+		rects[i] = f.Rect
+		rects[i].Y += dy
+	}
+	return rects
+}
+
+// Layout describes the receipt form: the frames filled with payer's data on the receipt template.
+// It is loaded from JSON, so another receipt blank may be supported without changing the code.
+type Layout struct {
+	Name   string        `json:"name"`
+	Frames []LayoutFrame `json:"frames"`
+}
+
+// FrameData is the data available in LayoutFrame.Text templates.
+type FrameData struct {
+	Payer         model.Payer
+	Credentials   string // payer's credentials split on two lines before the payment purpose
+	Amount        string // "Сумма: N руб. NN коп."
+	AmountInWords string // "Одна тысяча двести рублей 50 копеек", see model.Money.Words
+}
+
+// NewFrameData prepares the data of layout templates for the payer.
+func NewFrameData(payer model.Payer) FrameData {
+	return FrameData{
+		Payer:         payer,
+		Credentials:   prettifyCredentialsString(formatPayerInfo(payer), "Назначение"),
+		Amount:        formatAmount(payer.Sum),
+		AmountInWords: payer.Sum.Words(),
+	}
+}
+
+// text executes the text template of the frame
+func (f LayoutFrame) text(data FrameData) (string, error) {
+	var buf bytes.Buffer
+	if err := f.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute text template of frame `%s`: %w", f.Name, err)
+	}
+	return buf.String(), nil
+}
+
+//go:embed layouts/default.json
+var defaultLayoutJSON []byte
+
+// defaultLayout is the layout of the receipt template drawn by GenerateReceiptTemplate
+var defaultLayout = mustParseLayout(defaultLayoutJSON)
+
+// DefaultLayout returns the layout of the standard receipt template. Layouts are not modified after parsing,
+// so the same layout is shared.
+func DefaultLayout() *Layout {
+	return defaultLayout
+}
+
+func mustParseLayout(data []byte) *Layout {
+	layout, err := ParseLayout(data)
+	if err != nil {
+		panic(err)
+	}
+	return layout
+}
+
+// LoadLayout reads and parses the layout JSON file.
+func LoadLayout(path string) (*Layout, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.WrapIOError("read receipt layout", path, err)
+	}
+	layout, err := ParseLayout(data)
+	if err != nil {
+		return nil, errs.Wrap(errs.Validation, fmt.Sprintf("invalid receipt layout `%s`", path), err)
+	}
+	return layout, nil
+}
+
+// ParseLayout parses the layout from JSON and validates it: frame names are unique, rectangles are not empty,
+// text frames have font size and their templates refer only to FrameData fields.
+func ParseLayout(data []byte) (*Layout, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var layout Layout
+	if err := dec.Decode(&layout); err != nil {
+		return nil, fmt.Errorf("failed to decode layout: %w", err)
+	}
+	if len(layout.Frames) == 0 {
+		return nil, fmt.Errorf("layout `%s` has no frames", layout.Name)
+	}
+
+	names := make(map[string]bool, len(layout.Frames))
+	for i := range layout.Frames {
+		f := &layout.Frames[i]
+		if strings.TrimSpace(f.Name) == "" {
+			return nil, fmt.Errorf("frame #%d has no name", i+1)
+		}
+		if names[f.Name] {
+			return nil, fmt.Errorf("frame name `%s` is duplicated", f.Name)
+		}
+		names[f.Name] = true
+
+		if err := f.validate(); err != nil {
+			return nil, fmt.Errorf("frame `%s`: %w", f.Name, err)
+		}
+	}
+	return &layout, nil
+}
+
+// validate checks the frame and parses its text template
+func (f *LayoutFrame) validate() error {
+	if _, _, w, h := f.Rect.InnerRect(); w <= 0 || h <= 0 {
+		return fmt.Errorf("rectangle %vx%v with margins has no space inside", f.Rect.W, f.Rect.H)
+	}
+
+	switch f.Kind {
+	case FrameKindQrCode:
+		return nil
+	case FrameKindText:
+	default:
+		return fmt.Errorf("unknown kind `%s`, expected `%s` or `%s`", f.Kind, FrameKindText, FrameKindQrCode)
+	}
+
+	if f.FontSize <= 0 {
+		return fmt.Errorf("font size must be positive, got %d", f.FontSize)
+	}
+	if f.MinFontSize == 0 {
+		f.MinFontSize = f.FontSize
+	} else if f.MinFontSize < 0 || f.MinFontSize > f.FontSize {
+		return fmt.Errorf("min font size must be between 1 and font size %d, got %d", f.FontSize, f.MinFontSize)
+	}
+	switch f.Overflow {
+	case "":
+		f.Overflow = OverflowError
+	case OverflowError, OverflowWarn:
+	default:
+		return fmt.Errorf("unknown overflow policy `%s`, expected `%s` or `%s`", f.Overflow, OverflowError, OverflowWarn)
+	}
+	switch f.Align {
+	case "":
+		f.Align = AlignCenter
+	case AlignLeft, AlignCenter, AlignRight:
+	default:
+		return fmt.Errorf("unknown align `%s`", f.Align)
+	}
+	if f.Multiline && f.LineSpacing <= 0 {
+		f.LineSpacing = float64(f.FontSize) * 4 / 3
+	}
+
+	tmpl, err := template.New(f.Name).Option("missingkey=error").Parse(f.Text)
+	if err != nil {
+		return fmt.Errorf("invalid text template: %w", err)
+	}
+	f.tmpl = tmpl
+	// unknown fields are reported only when the template is executed
+	if _, err := f.text(FrameData{}); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package pdf
+
+import (
+	"image/color"
+	"li-acc/internal/errs"
+	"li-acc/pkg/model"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultLayout(t *testing.T) {
+	layout := DefaultLayout()
+	require.Equal(t, "default", layout.Name)
+
+	// frames of the template drawn by GenerateReceiptTemplate, the bottom part is lower by 191pt
+	rects := map[string][]Frame{}
+	for _, f := range layout.Frames {
+		rects[f.Name] = f.Rects()
+	}
+	require.Equal(t, []Frame{
+		{X: 161, Y: 104, W: 381, H: 36, Margin: Margin{Top: 14, Right: 4, Bottom: 2, Left: 4}},
+		{X: 161, Y: 295, W: 381, H: 36, Margin: Margin{Top: 14, Right: 4, Bottom: 2, Left: 4}},
+	}, rects["payer_credentials"])
+	require.Len(t, rects["payment_amount"], 2)
+	require.Len(t, rects["payment_amount_words"], 2)
+	require.Equal(t, []Frame{{X: 35, Y: 270, W: 120, H: 120}}, rects["qr_code"])
+}
+
+func TestParseLayout(t *testing.T) {
+	frame := func(fields string) string {
+		return `{"name": "test", "frames": [{"name": "f", "rect": {"x": 10, "y": 10, "w": 100, "h": 20}, ` + fields + `}]}`
+	}
+
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{name: "text", json: frame(`"kind": "text", "text": "ФИО: {{.Payer.CHILDFIO}}", "font_size": 8, "align": "left"`)},
+		{name: "qr", json: frame(`"kind": "qr"`)},
+		{name: "no frames", json: `{"name": "test", "frames": []}`, wantErr: "has no frames"},
+		{name: "unknown field", json: frame(`"kind": "qr", "colour": "red"`), wantErr: "unknown field"},
+		{name: "unknown kind", json: frame(`"kind": "barcode"`), wantErr: "unknown kind"},
+		{name: "min font size too large", json: frame(`"kind": "text", "text": "{{.Amount}}", "font_size": 8, "min_font_size": 9`), wantErr: "min font size"},
+		{name: "unknown overflow", json: frame(`"kind": "text", "text": "{{.Amount}}", "font_size": 8, "overflow": "clip"`), wantErr: "overflow policy"},
+		{name: "no font size", json: frame(`"kind": "text", "text": "{{.Amount}}"`), wantErr: "font size"},
+		{name: "unknown align", json: frame(`"kind": "text", "text": "{{.Amount}}", "font_size": 8, "align": "justify"`), wantErr: "unknown align"},
+		{name: "template syntax", json: frame(`"kind": "text", "text": "{{.Amount", "font_size": 8`), wantErr: "invalid text template"},
+		{name: "unknown template field", json: frame(`"kind": "text", "text": "{{.Payer.Name}}", "font_size": 8`), wantErr: "Name"},
+		{name: "no space inside", json: `{"name": "test", "frames": [{"name": "f", "kind": "qr", "rect": {"w": 10, "h": 10, "margin": {"left": 6, "right": 6}}}]}`, wantErr: "no space"},
+		{
+			name:    "duplicated name",
+			json:    `{"name": "test", "frames": [{"name": "f", "kind": "qr", "rect": {"w": 10, "h": 10}}, {"name": "f", "kind": "qr", "rect": {"w": 10, "h": 10}}]}`,
+			wantErr: "duplicated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout, err := ParseLayout([]byte(tt.json))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, layout.Frames, 1)
+		})
+	}
+}
+
+func TestLayoutFrame_Text(t *testing.T) {
+	layout, err := ParseLayout([]byte(`{"name": "test", "frames": [
+		{"name": "fio", "kind": "text", "rect": {"w": 100, "h": 20}, "text": "{{.Payer.CHILDFIO}}: {{.Amount}}", "font_size": 8, "multiline": true}
+	]}`))
+	require.NoError(t, err)
+	f := layout.Frames[0]
+	require.Equal(t, AlignCenter, f.Align)
+	require.Equal(t, OverflowError, f.Overflow)
+	require.Equal(t, 8, f.MinFontSize)
+	require.InDelta(t, 10.67, f.LineSpacing, 0.01)
+
+	text, err := f.text(NewFrameData(model.Payer{CHILDFIO: "Иванов Иван", Sum: model.NewMoney(1200, 50)}))
+	require.NoError(t, err)
+	require.Equal(t, "Иванов Иван: Сумма: 1200 руб. 50 коп.", text)
+
+	data := NewFrameData(model.Payer{Sum: model.NewMoney(1200, 50)})
+	require.Equal(t, "Одна тысяча двести рублей 50 копеек", data.AmountInWords)
+}
+
+func TestLoadLayout(t *testing.T) {
+	_, err := LoadLayout("not-exists.json")
+	require.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "layout.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"name": "broken"}`), 0o644))
+	_, err = LoadLayout(path)
+	require.True(t, errs.IsValidationError(err), err)
+}
+
+func TestCanvas_FillWithLayout(t *testing.T) {
+	// a form of another blank: the payer name and the amount are printed in separate frames
+	layout, err := ParseLayout([]byte(`{"name": "other", "frames": [
+		{"name": "fio", "kind": "text", "rect": {"x": 161, "y": 104, "w": 381, "h": 20}, "text": "{{.Payer.CHILDFIO}}", "font_size": 9, "align": "left"},
+		{"name": "sum", "kind": "text", "rect": {"x": 161, "y": 124, "w": 381, "h": 20}, "repeat_y": [0, 191], "text": "{{.Amount}}", "font_size": 8, "align": "right"},
+		{"name": "qr", "kind": "qr", "rect": {"x": 35, "y": 270, "w": 120, "h": 120}}
+	]}`))
+	require.NoError(t, err)
+
+	canvas := newTestCanvas(t, layout)
+	qrImg, err := generateFrameImage(10, 10, colorBlack)
+	require.NoError(t, err)
+	require.NoError(t, canvas.Fill(model.Payer{CHILDFIO: "Иванов Иван", Sum: model.NewMoney(1200, 50)}, qrImg))
+
+	dst := filepath.Join(t.TempDir(), "receipt.pdf")
+	require.NoError(t, canvas.Save(dst))
+	saved, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(saved), "%PDF-"))
+}
+
+// colorBlack is the color of the QR code images in tests
+var colorBlack = color.RGBA{A: 255}
@@ -0,0 +1,88 @@
+package pdf
+
+import (
+	"bytes"
+	"strings"
+
+	gopdf "github.com/signintech/pdft/minigopdf"
+)
+
+// textMeasurer measures text written with the loaded font. It keeps its own copy of the font,
+// since GoPdf.MeasureTextWidth panics in this version and pdft.PDFt does not expose the font metrics.
+type textMeasurer struct {
+	font gopdf.SubsetFontObj
+}
+
+func newTextMeasurer(fontPath string) (*textMeasurer, error) {
+	m := &textMeasurer{}
+	m.font.CharacterToGlyphIndex = gopdf.NewMapOfCharacterToGlyphIndex()
+	if err := m.font.SetTTFByPath(fontPath); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// newTextMeasurerFrom loads the font metrics from the TTF [data].
+func newTextMeasurerFrom(data []byte) (*textMeasurer, error) {
+	m := &textMeasurer{}
+	m.font.CharacterToGlyphIndex = gopdf.NewMapOfCharacterToGlyphIndex()
+	if err := m.font.SetTTFByReader(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// width returns the width of the text with the font [size]. The text is written without kerning,
+// so it is the sum of the glyph widths.
+func (m *textMeasurer) width(text string, size float64) (float64, error) {
+	if err := m.font.AddChars(text); err != nil {
+		return 0, err
+	}
+	var sum uint
+	for _, r := range text {
+		cw, err := m.font.CharWidth(r)
+		if err != nil {
+			return 0, err
+		}
+		sum += cw
+	}
+	return float64(sum) * size / 1000, nil
+}
+
+// ascent returns the height of the font [size] above the baseline. pdft places the baseline of the text
+// inserted into the frame this distance below the frame top.
+func (m *textMeasurer) ascent(size float64) float64 {
+	ttf := m.font.GetTTFParser()
+	return float64(ttf.TypoAscender()) * size / float64(ttf.UnitsPerEm())
+}
+
+// wrap splits the text into lines not wider than maxWidth with the font [size], breaking at spaces.
+// Spaces inside the lines are kept as they are, spaces at the breaks are removed.
+// A word wider than maxWidth takes the whole line.
+func (m *textMeasurer) wrap(text string, size, maxWidth float64) ([]string, error) {
+	text = strings.TrimSpace(text)
+	if width, err := m.width(text, size); err != nil || width <= maxWidth {
+		return []string{text}, err
+	}
+
+	var lines []string
+	var line string
+	// joined back with single spaces the words restore the text, empty words keep the repeated spaces
+	for _, word := range strings.Split(text, " ") {
+		if strings.TrimSpace(line) == "" {
+			line = word
+			continue
+		}
+		width, err := m.width(strings.TrimRight(line+" "+word, " "), size)
+		if err != nil {
+			return nil, err
+		}
+		if width > maxWidth && word != "" {
+			lines = append(lines, strings.TrimRight(line, " "))
+			line = word
+		} else {
+			line += " " + word
+		}
+	}
+	return append(lines, strings.TrimRight(line, " ")), nil
+}
@@ -0,0 +1,167 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"li-acc/internal/errs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/signintech/pdft"
+)
+
+// ReceiptTemplate is the receipt template loaded once per batch. Opening the template PDF with pdft parses
+// the whole document, which takes most of the time of one receipt, so the canvases of the template do not open it.
+// Every canvas draws the payer data on the blank page of the template size (the overlay), and the saved receipt
+// is the page with the template and the overlay put over it, both as form XObjects. The template is safe
+// for concurrent use, the canvases are not.
+type ReceiptTemplate struct {
+	layout    *Layout
+	font      []byte // TTF data of the text font, parsed by every canvas, since pdft fonts collect the used glyphs
+	debugMode bool
+
+	// blank is the PDF with one empty page of the template size, the canvases open it for the overlay
+	blank []byte
+	media pdfRect
+
+	// objs are the objects of the receipt made from the template: the catalog and the page tree (set by every
+	// receipt), the page content and the template page as the form XObject with its resources.
+	// The bodies are not modified, the receipts append the overlay objects to the copy of the slice.
+	objs    [][]byte
+	content int // number of the page content object
+	form    int // number of the template form object
+}
+
+// object numbers of the receipt made from the template, see ReceiptTemplate.objs
+const (
+	overlayCatalogID = 1
+	overlayPagesID   = 2
+)
+
+// LoadReceiptTemplate loads the template [pdfSrc] filled by [layout] (DefaultLayout if nil) with the font [fontPath]
+// (DefaultFontPath if empty). The canvases of the template are made by ReceiptTemplate.NewCanvas.
+func LoadReceiptTemplate(pdfSrc, fontPath string, layout *Layout, debugMode bool) (*ReceiptTemplate, error) {
+	if fontPath == "" {
+		fontPath = DefaultFontPath
+	}
+	if layout == nil {
+		layout = DefaultLayout()
+	}
+
+	// the template is saved again by pdft, which writes the classic cross-reference table readPDF reads,
+	// so any template pdft opens is loaded
+	var doc pdft.PDFt
+	if err := doc.Open(pdfSrc); err != nil {
+		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+	var normalized bytes.Buffer
+	if err := doc.SaveTo(&normalized); err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	src, err := readPDF(normalized.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	page, err := src.firstPage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	font, err := os.ReadFile(fontPath)
+	if err != nil {
+		return nil, errs.WrapIOError("read font", fontPath, err)
+	}
+
+	t := &ReceiptTemplate{layout: layout, font: font, debugMode: debugMode, media: src.mediaBox(page)}
+
+	if t.blank, err = blankPage(t.media); err != nil {
+		return nil, fmt.Errorf("failed to make overlay page: %w", err)
+	}
+
+	var w pdfWriter
+	w.reserve() // overlayCatalogID
+	w.reserve() // overlayPagesID
+	// the template form is named /T on the receipt page and the overlay form is /O
+	if t.content, err = w.addStream("", []byte("q /T Do Q q /O Do Q\n")); err != nil {
+		return nil, fmt.Errorf("failed to make page content: %w", err)
+	}
+	if t.form, err = w.importPage(src, wholePage); err != nil {
+		return nil, fmt.Errorf("failed to import template page: %w", err)
+	}
+	t.objs = w.objs
+	return t, nil
+}
+
+// blankPage makes the PDF of one empty page of the [media] size. The page has the content stream and
+// the resources, pdft appends the drawn content and the fonts to them.
+func blankPage(media pdfRect) ([]byte, error) {
+	var w pdfWriter
+	catalog, pages := w.reserve(), w.reserve()
+	resources := w.add([]byte("<< /ProcSet [/PDF /Text /ImageB /ImageC /ImageI] /Font << >> /XObject << >> >>"))
+	content, err := w.addStream("", nil)
+	if err != nil {
+		return nil, err
+	}
+	page := w.add(fmt.Appendf(nil, "<< /Type /Page /Parent %d 0 R /MediaBox %s /Resources %d 0 R /Contents %d 0 R >>",
+		pages, media, resources, content))
+	w.set(pages, fmt.Appendf(nil, "<< /Type /Pages /Kids [%d 0 R] /Count 1 >>", page))
+	w.set(catalog, fmt.Appendf(nil, "<< /Type /Catalog /Pages %d 0 R >>", pages))
+	return w.bytes(catalog), nil
+}
+
+// wholePage is the clip of importPage that keeps the page as it is
+func wholePage(media pdfRect) (pdfRect, float64) {
+	return media, 0
+}
+
+// NewCanvas makes the canvas that fills the template for one payer. It is cheap: only the blank overlay page
+// and the font are parsed.
+func (t *ReceiptTemplate) NewCanvas() (*Canvas, error) {
+	var overlay pdft.PDFt
+	if err := overlay.OpenFrom(bytes.NewReader(t.blank)); err != nil {
+		return nil, fmt.Errorf("failed to open overlay page: %w", err)
+	}
+	if err := overlay.AddFontFrom(DefaultFontName, bytes.NewReader(t.font)); err != nil {
+		return nil, fmt.Errorf("failed to upload font: %w", err)
+	}
+	measurer, err := newTextMeasurerFrom(t.font)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load font metrics: %w", err)
+	}
+	return &Canvas{pdf: &overlay, template: t, layout: t.layout, measurer: measurer, debugMode: t.debugMode}, nil
+}
+
+// compose makes the receipt of the template page with the [overlay] page put over it
+func (t *ReceiptTemplate) compose(overlay []byte) ([]byte, error) {
+	src, err := readPDF(overlay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overlay: %w", err)
+	}
+
+	w := pdfWriter{objs: slices.Clone(t.objs)}
+	overlayID, err := w.importPage(src, wholePage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import overlay: %w", err)
+	}
+	pageID := w.add(fmt.Appendf(nil,
+		"<< /Type /Page /Parent %d 0 R /MediaBox %s /Resources << /XObject << /T %d 0 R /O %d 0 R >> >> /Contents %d 0 R >>",
+		overlayPagesID, t.media, t.form, overlayID, t.content,
+	))
+	w.set(overlayPagesID, fmt.Appendf(nil, "<< /Type /Pages /Kids [%d 0 R] /Count 1 >>", pageID))
+	w.set(overlayCatalogID, fmt.Appendf(nil, "<< /Type /Catalog /Pages %d 0 R >>", overlayPagesID))
+	return w.bytes(overlayCatalogID), nil
+}
+
+// save writes the receipt of the template filled by the canvas to [path]
+func (t *ReceiptTemplate) save(overlay *pdft.PDFt, path string) error {
+	var buf bytes.Buffer
+	if err := overlay.SaveTo(&buf); err != nil {
+		return err
+	}
+	data, err := t.compose(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Clean(path), data, 0644)
+}
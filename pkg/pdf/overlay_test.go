@@ -0,0 +1,131 @@
+package pdf
+
+import (
+	"bytes"
+	"li-acc/internal/errs"
+	"li-acc/pkg/model"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/signintech/pdft"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestTemplate saves the native template and returns its path
+func writeTestTemplate(tb testing.TB) string {
+	tb.Helper()
+	data, err := RenderReceiptTemplate(testFontPath, orgFix)
+	require.NoError(tb, err)
+	path := filepath.Join(tb.TempDir(), "template.pdf")
+	require.NoError(tb, os.WriteFile(path, data, 0o644))
+	return path
+}
+
+func testPayer(i int) model.Payer {
+	return model.Payer{
+		PersAcc:  strconv.Itoa(100000 + i),
+		CHILDFIO: "Зубенко Михаил Петрович",
+		Purpose:  "10a доп питание сент",
+		CBC:      "82100000000000000131",
+		OKTMO:    "98790098",
+		Sum:      model.NewMoney(int64(100+i), 0),
+	}
+}
+
+func TestReceiptTemplate(t *testing.T) {
+	tmpl, err := LoadReceiptTemplate(writeTestTemplate(t), testFontPath, nil, false)
+	require.NoError(t, err)
+	qrImg, err := generateFrameImage(10, 10, colorBlack)
+	require.NoError(t, err)
+
+	var templateForms [][]byte
+	for i := range 2 {
+		canvas, err := tmpl.NewCanvas()
+		require.NoError(t, err)
+		require.NoError(t, canvas.Fill(testPayer(i), qrImg))
+		path := filepath.Join(t.TempDir(), "receipt.pdf")
+		require.NoError(t, canvas.Save(path))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var doc pdft.PDFt
+		require.NoError(t, doc.OpenFrom(bytes.NewReader(data)))
+		require.Equal(t, 1, doc.GetNumberOfPage())
+
+		f, err := readPDF(data)
+		require.NoError(t, err)
+		page, err := f.firstPage()
+		require.NoError(t, err)
+		require.Equal(t, tmpl.media, f.mediaBox(page))
+		content, err := f.pageContent(page)
+		require.NoError(t, err)
+		require.Equal(t, "q /T Do Q q /O Do Q", string(bytes.TrimSpace(content)), "the payer data is drawn over the template")
+
+		xobjects := f.resolve(dictValue(f.resolve(dictValue(page, "Resources")), "XObject"))
+		forms := map[string][]byte{}
+		for _, name := range []string{"T", "O"} {
+			ref, ok := parseRef(dictValue(xobjects, name))
+			require.True(t, ok, name)
+			dict, stream := splitStream(f.objs[ref])
+			forms[name], err = decodeStream(dict, streamData(stream))
+			require.NoError(t, err)
+		}
+		require.Contains(t, string(forms["O"]), "/I1 Do", "QR code is drawn on the overlay")
+		require.NotContains(t, string(forms["T"]), "/I1 Do")
+		templateForms = append(templateForms, forms["T"])
+	}
+	require.Equal(t, templateForms[0], templateForms[1], "canvases do not change the template")
+
+	t.Run("no template", func(t *testing.T) {
+		_, err := LoadReceiptTemplate(filepath.Join(t.TempDir(), "template.pdf"), testFontPath, nil, false)
+		require.Error(t, err)
+	})
+
+	t.Run("no font", func(t *testing.T) {
+		_, err := LoadReceiptTemplate(writeTestTemplate(t), filepath.Join(t.TempDir(), "font.ttf"), nil, false)
+		require.Error(t, err)
+		require.True(t, errs.IsSystemError(err))
+	})
+}
+
+// BenchmarkReceipt compares the receipts made by the canvases opening the template for every payer
+// with the canvases of the template loaded once.
+func BenchmarkReceipt(b *testing.B) {
+	qrImg, err := generateFrameImage(10, 10, colorBlack)
+	require.NoError(b, err)
+	dst := filepath.Join(b.TempDir(), "receipt.pdf")
+
+	fill := func(b *testing.B, canvas *Canvas, i int) {
+		require.NoError(b, canvas.Fill(testPayer(i), qrImg))
+		require.NoError(b, canvas.Save(dst))
+	}
+
+	templates := map[string]string{
+		"native":    writeTestTemplate(b),
+		"converted": "integration/testdata/template.pdf",
+	}
+	for name, templatePath := range templates {
+		b.Run(name+"/template per payer", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := range b.N {
+				canvas, err := NewCanvasWithLayout(templatePath, testFontPath, nil, false)
+				require.NoError(b, err)
+				fill(b, canvas, i)
+			}
+		})
+
+		b.Run(name+"/loaded template", func(b *testing.B) {
+			b.ReportAllocs()
+			tmpl, err := LoadReceiptTemplate(templatePath, testFontPath, nil, false)
+			require.NoError(b, err)
+			b.ResetTimer()
+			for i := range b.N {
+				canvas, err := tmpl.NewCanvas()
+				require.NoError(b, err)
+				fill(b, canvas, i)
+			}
+		})
+	}
+}
@@ -0,0 +1,650 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"li-acc/internal/errs"
+	"os"
+	"strconv"
+)
+
+// Receipts are printed on paper two per A4 sheet. The form takes the top half of the receipt page, so the top halves
+// of two receipt pages are put one under another on the sheet, which is cut along the middle afterward.
+// Every receipt page is embedded as a form XObject clipped to its top half, with its own fonts and images.
+const (
+	// ReceiptsPerSheet is the number of receipts on one page of the print file
+	ReceiptsPerSheet = 2
+
+	printPageWidth  = 595.28
+	printPageHeight = 841.89
+
+	cutLineWidth  = 0.5
+	cutMarkLength = 14 // solid marks at the sheet edges, the dashed line is drawn between them
+)
+
+// MergeReceiptsForPrint makes the print-ready PDF [pdfDst] of the [receipts] saved by Canvas, two receipts per A4 page
+// with the cut marks between them. The receipts are printed in the given order.
+func MergeReceiptsForPrint(pdfDst string, receipts []string) error {
+	data, err := RenderReceiptsForPrint(receipts)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(pdfDst, data, 0644); err != nil {
+		return errs.WrapIOError("save receipts print file", pdfDst, err)
+	}
+	return nil
+}
+
+// RenderReceiptsForPrint makes the print-ready PDF of the [receipts] in memory, see MergeReceiptsForPrint.
+func RenderReceiptsForPrint(receipts []string) ([]byte, error) {
+	if len(receipts) == 0 {
+		return nil, errs.New(errs.System, "no receipts to print")
+	}
+
+	var w pdfWriter
+	catalogID := w.reserve()
+	pagesID := w.reserve()
+
+	forms := make([]int, 0, len(receipts))
+	for _, path := range receipts {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errs.WrapIOError("read receipt to print", path, err)
+		}
+		src, err := readPDF(data)
+		if err != nil {
+			return nil, errs.Wrap(errs.System, fmt.Sprintf("failed to read receipt `%s`", path), err)
+		}
+		formID, err := w.importHalfPage(src)
+		if err != nil {
+			return nil, errs.Wrap(errs.System, fmt.Sprintf("failed to import receipt `%s`", path), err)
+		}
+		forms = append(forms, formID)
+	}
+
+	var kids bytes.Buffer
+	for first := 0; first < len(forms); first += ReceiptsPerSheet {
+		sheet := forms[first:min(first+ReceiptsPerSheet, len(forms))]
+
+		var content, xobjects bytes.Buffer
+		for slot, formID := range sheet {
+			fmt.Fprintf(&xobjects, "/R%d %d 0 R ", slot+1, formID)
+			fmt.Fprintf(&content, "q 1 0 0 1 0 %s cm /R%d Do Q\n", pdfNumber(-float64(slot)*printPageHeight/ReceiptsPerSheet), slot+1)
+		}
+		writeCutMarks(&content)
+
+		contentID, err := w.addStream("", content.Bytes())
+		if err != nil {
+			return nil, errs.Wrap(errs.System, "failed to compress print page content", err)
+		}
+		pageID := w.add(fmt.Appendf(nil,
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /XObject << %s>> >> /Contents %d 0 R >>",
+			pagesID, pdfNumber(printPageWidth), pdfNumber(printPageHeight), xobjects.String(), contentID,
+		))
+		fmt.Fprintf(&kids, "%d 0 R ", pageID)
+	}
+
+	sheets := (len(forms) + ReceiptsPerSheet - 1) / ReceiptsPerSheet
+	w.set(pagesID, fmt.Appendf(nil, "<< /Type /Pages /Kids [%s] /Count %d >>", kids.String(), sheets))
+	w.set(catalogID, fmt.Appendf(nil, "<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+	return w.bytes(catalogID), nil
+}
+
+// writeCutMarks draws the line between the receipts on the sheet: solid marks at the edges and the dashed line between them
+func writeCutMarks(content *bytes.Buffer) {
+	y := pdfNumber(printPageHeight / ReceiptsPerSheet)
+	fmt.Fprintf(content, "q 0 G %s w\n", pdfNumber(cutLineWidth))
+	fmt.Fprintf(content, "0 %[1]s m %[2]s %[1]s l S %[3]s %[1]s m %[4]s %[1]s l S\n",
+		y, pdfNumber(cutMarkLength), pdfNumber(printPageWidth-cutMarkLength), pdfNumber(printPageWidth))
+	fmt.Fprintf(content, "[4 3] 0 d %[2]s %[1]s m %[3]s %[1]s l S\nQ\n",
+		y, pdfNumber(cutMarkLength), pdfNumber(printPageWidth-cutMarkLength))
+}
+
+// importHalfPage copies the first page of [src] into the form XObject clipped to the top half of the page
+// and placed at the top of the print sheet. Returns the number of the form object.
+func (w *pdfWriter) importHalfPage(src *pdfFile) (int, error) {
+	return w.importPage(src, func(media pdfRect) (pdfRect, float64) {
+		top := media
+		top.lly = (media.lly + media.ury) / 2
+		// the source top half is moved to the top of the sheet
+		return top, printPageHeight - media.ury
+	})
+}
+
+// pdfRect is the rectangle of PDF syntax: lower left and upper right corners
+type pdfRect struct {
+	llx, lly, urx, ury float64
+}
+
+func (r pdfRect) String() string {
+	return fmt.Sprintf("[%s %s %s %s]", pdfNumber(r.llx), pdfNumber(r.lly), pdfNumber(r.urx), pdfNumber(r.ury))
+}
+
+// mediaBox returns the media box of the page, A4 if it has none
+func (f *pdfFile) mediaBox(page []byte) pdfRect {
+	if mediaBox, err := f.inherited(page, "MediaBox"); err == nil {
+		if box, err := parseNumbers(f.resolve(mediaBox)); err == nil && len(box) == 4 {
+			return pdfRect{box[0], box[1], box[2], box[3]}
+		}
+	}
+	return pdfRect{0, 0, printPageWidth, printPageHeight}
+}
+
+// importPage copies the first page of [src] with all objects it uses into the form XObject.
+// [clip] returns the bounding box of the form for the page media box and the vertical shift of the form.
+// Returns the number of the form object.
+func (w *pdfWriter) importPage(src *pdfFile, clip func(media pdfRect) (bbox pdfRect, dy float64)) (int, error) {
+	page, err := src.firstPage()
+	if err != nil {
+		return 0, err
+	}
+
+	resources, err := src.inherited(page, "Resources")
+	if err != nil {
+		return 0, err
+	}
+
+	// objects of the resources (fonts, images) get new numbers in the new document
+	numbers := make(map[int]int)
+	queue := refsIn(resources)
+	for len(queue) > 0 {
+		old := queue[0]
+		queue = queue[1:]
+		if _, ok := numbers[old]; ok {
+			continue
+		}
+		body, ok := src.objs[old]
+		if !ok {
+			return 0, fmt.Errorf("object %d is not found", old)
+		}
+		numbers[old] = w.reserve()
+		dict, _ := splitStream(body)
+		queue = append(queue, refsIn(dict)...)
+	}
+	renumber := func(old int) int { return numbers[old] }
+	for old, number := range numbers {
+		dict, stream := splitStream(src.objs[old])
+		w.set(number, append(rewriteRefs(dict, renumber), stream...))
+	}
+
+	bbox, dy := clip(src.mediaBox(page))
+	dict := fmt.Sprintf("/Type /XObject /Subtype /Form /BBox %s /Matrix [1 0 0 1 0 %s] /Resources %s",
+		bbox, pdfNumber(dy), rewriteRefs(resources, renumber))
+
+	// the only content stream is copied encoded, so it is not compressed again
+	if filter, data, ok := src.encodedContent(page); ok {
+		if filter != nil {
+			dict += " /Filter " + string(filter)
+		}
+		return w.addRawStream(dict, data), nil
+	}
+	content, err := src.pageContent(page)
+	if err != nil {
+		return 0, err
+	}
+	return w.addStream(dict, content)
+}
+
+// pdfFile is the PDF document with the classic cross-reference table, as pdft and gopdf write them.
+type pdfFile struct {
+	objs map[int][]byte // object number -> object body between "obj" and "endobj"
+	root []byte         // reference to the document catalog
+}
+
+// readPDF reads the objects of the PDF document by its cross-reference table.
+// Every object is supposed to end where the next one starts, as the writers of this package lay them out.
+func readPDF(data []byte) (*pdfFile, error) {
+	start := bytes.LastIndex(data, []byte("startxref"))
+	if start < 0 {
+		return nil, fmt.Errorf("startxref is not found")
+	}
+	fields := bytes.Fields(data[start+len("startxref"):])
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("startxref offset is not found")
+	}
+	xrefOffset, err := strconv.Atoi(string(fields[0]))
+	if err == nil && xrefOffset >= 0 && xrefOffset < len(data) {
+		xrefOffset = skipSpace(data, xrefOffset) // pdft points to the line break before the table
+	}
+	if err != nil || xrefOffset < 0 || xrefOffset >= len(data) || !bytes.HasPrefix(data[xrefOffset:], []byte("xref")) {
+		return nil, fmt.Errorf("invalid cross-reference table offset %q, cross-reference streams are not supported", fields[0])
+	}
+
+	trailer := bytes.Index(data[xrefOffset:], []byte("trailer"))
+	if trailer < 0 {
+		return nil, fmt.Errorf("trailer is not found")
+	}
+	trailer += xrefOffset
+
+	// subsections of the table: "first count" and then count of "offset generation n|f" entries
+	offsets := make(map[int]int)
+	entries := bytes.Fields(data[xrefOffset+len("xref") : trailer])
+	for len(entries) >= 2 {
+		first, err1 := strconv.Atoi(string(entries[0]))
+		count, err2 := strconv.Atoi(string(entries[1]))
+		if err1 != nil || err2 != nil || count < 0 || len(entries) < 2+3*count {
+			return nil, fmt.Errorf("invalid cross-reference subsection")
+		}
+		for i := 0; i < count; i++ {
+			entry := entries[2+3*i : 5+3*i]
+			if string(entry[2]) != "n" {
+				continue
+			}
+			offset, err := strconv.Atoi(string(entry[0]))
+			if err != nil || offset <= 0 || offset >= xrefOffset {
+				return nil, fmt.Errorf("invalid offset of object %d", first+i)
+			}
+			offsets[first+i] = offset
+		}
+		entries = entries[2+3*count:]
+	}
+
+	ends := make(map[int]int, len(offsets))
+	for number, offset := range offsets {
+		end := xrefOffset
+		for _, other := range offsets {
+			if other > offset && other < end {
+				end = other
+			}
+		}
+		ends[number] = end
+	}
+
+	f := &pdfFile{objs: make(map[int][]byte, len(offsets))}
+	for number, offset := range offsets {
+		body := data[offset:ends[number]]
+		header := fmt.Appendf(nil, "%d 0 obj", number)
+		i := bytes.Index(body, header)
+		if i < 0 || len(bytes.TrimSpace(body[:i])) > 0 {
+			return nil, fmt.Errorf("object %d is not found at its offset", number)
+		}
+		body = bytes.TrimSpace(body[i+len(header):])
+		body = bytes.TrimSpace(bytes.TrimSuffix(body, []byte("endobj")))
+		f.objs[number] = body
+	}
+
+	f.root = dictValue(data[trailer+len("trailer"):], "Root")
+	if f.root == nil {
+		return nil, fmt.Errorf("document catalog is not found")
+	}
+	return f, nil
+}
+
+// resolve returns the object the [value] refers to, or the value itself if it is not a reference
+func (f *pdfFile) resolve(value []byte) []byte {
+	if number, ok := parseRef(value); ok {
+		return f.objs[number]
+	}
+	return value
+}
+
+// firstPage returns the first page object of the document
+func (f *pdfFile) firstPage() ([]byte, error) {
+	node := f.resolve(dictValue(f.resolve(f.root), "Pages"))
+	for depth := 0; depth < 16; depth++ {
+		if node == nil {
+			return nil, fmt.Errorf("page tree is broken")
+		}
+		if string(dictValue(node, "Type")) == "/Page" {
+			return node, nil
+		}
+		kids := refsIn(f.resolve(dictValue(node, "Kids")))
+		if len(kids) == 0 {
+			return nil, fmt.Errorf("document has no pages")
+		}
+		node = f.objs[kids[0]]
+	}
+	return nil, fmt.Errorf("page tree is too deep")
+}
+
+// inherited returns the [key] value of the page, looking it up in the parents if the page has none
+func (f *pdfFile) inherited(page []byte, key string) ([]byte, error) {
+	node := page
+	for depth := 0; node != nil && depth < 16; depth++ {
+		if value := dictValue(node, key); value != nil {
+			return value, nil
+		}
+		node = f.resolve(dictValue(node, "Parent"))
+	}
+	return nil, fmt.Errorf("page has no %s", key)
+}
+
+// pageContent returns the decoded content streams of the page joined together
+func (f *pdfFile) pageContent(page []byte) ([]byte, error) {
+	contents := dictValue(page, "Contents")
+	if contents == nil {
+		return nil, fmt.Errorf("page has no Contents")
+	}
+
+	// the contents are the reference to one stream or the array of references
+	var streams []int
+	if value := bytes.TrimSpace(f.resolve(contents)); bytes.HasPrefix(value, []byte("[")) {
+		streams = refsIn(value)
+	} else if number, ok := parseRef(contents); ok {
+		streams = []int{number}
+	}
+	var content bytes.Buffer
+	for _, number := range streams {
+		body, ok := f.objs[number]
+		if !ok {
+			return nil, fmt.Errorf("content stream %d is not found", number)
+		}
+		dict, stream := splitStream(body)
+		data, err := decodeStream(dict, streamData(stream))
+		if err != nil {
+			return nil, fmt.Errorf("content stream %d: %w", number, err)
+		}
+		content.Write(data)
+		content.WriteByte('\n')
+	}
+	return content.Bytes(), nil
+}
+
+// encodedContent returns the data of the only content stream of the page as it is encoded, and its Filter.
+// ok is false if the page has several content streams, or the stream has decode parameters.
+func (f *pdfFile) encodedContent(page []byte) (filter, data []byte, ok bool) {
+	number, ok := parseRef(dictValue(page, "Contents"))
+	if !ok {
+		return nil, nil, false
+	}
+	dict, stream := splitStream(f.objs[number])
+	if stream == nil || dictValue(dict, "DecodeParms") != nil {
+		return nil, nil, false
+	}
+	return dictValue(dict, "Filter"), streamData(stream), true
+}
+
+// decodeStream decodes the stream data compressed by the filter of its dictionary. Only FlateDecode is supported.
+func decodeStream(dict, data []byte) ([]byte, error) {
+	switch filter := string(bytes.Trim(dictValue(dict, "Filter"), "[] \r\n")); filter {
+	case "":
+		return data, nil
+	case "/FlateDecode":
+		r, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	default:
+		return nil, fmt.Errorf("unsupported stream filter %s", filter)
+	}
+}
+
+// splitStream splits the object body into the dictionary and the stream part starting with the "stream" keyword.
+// The stream part is empty if the object is not a stream.
+func splitStream(body []byte) (dict, stream []byte) {
+	end := skipValue(body, skipSpace(body, 0))
+	if i := skipSpace(body, end); bytes.HasPrefix(body[i:], []byte("stream")) {
+		return body[:end], body[end:]
+	}
+	return body, nil
+}
+
+// streamData returns the data between the "stream" and "endstream" keywords
+func streamData(stream []byte) []byte {
+	data := bytes.TrimLeft(stream, " \t\r\n")
+	data = bytes.TrimPrefix(data, []byte("stream"))
+	data = bytes.TrimPrefix(data, []byte("\r"))
+	data = bytes.TrimPrefix(data, []byte("\n"))
+	if end := bytes.LastIndex(data, []byte("endstream")); end >= 0 {
+		data = data[:end]
+	}
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	return bytes.TrimSuffix(data, []byte("\r"))
+}
+
+// pdfWriter collects the objects of the new PDF document. The object number is its index + 1.
+type pdfWriter struct {
+	objs [][]byte
+}
+
+// reserve returns the number of the object that is set later
+func (w *pdfWriter) reserve() int {
+	w.objs = append(w.objs, nil)
+	return len(w.objs)
+}
+
+func (w *pdfWriter) set(number int, body []byte) {
+	w.objs[number-1] = body
+}
+
+func (w *pdfWriter) add(body []byte) int {
+	number := w.reserve()
+	w.set(number, body)
+	return number
+}
+
+// addStream adds the stream object with the [dict] entries and the [data] compressed by FlateDecode
+func (w *pdfWriter) addStream(dict string, data []byte) (int, error) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(data); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+
+	return w.addRawStream(dict+" /Filter /FlateDecode", compressed.Bytes()), nil
+}
+
+// addRawStream adds the stream object with the [dict] entries and the [data] written as it is
+func (w *pdfWriter) addRawStream(dict string, data []byte) int {
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "<< %s /Length %d >>\nstream\n", dict, len(data))
+	body.Write(data)
+	body.WriteString("\nendstream")
+	return w.add(body.Bytes())
+}
+
+// bytes returns the document with the cross-reference table and the [root] catalog
+func (w *pdfWriter) bytes(root int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(w.objs))
+	for i, body := range w.objs {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		buf.Write(body)
+		buf.WriteString("\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.objs)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.objs)+1, root, xref)
+	return buf.Bytes()
+}
+
+// pdfNumber formats the real number of PDF syntax
+func pdfNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// isPdfDelimiter reports whether the byte ends PDF token: whitespace or delimiter character
+func isPdfDelimiter(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0, '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func skipSpace(b []byte, i int) int {
+	for i < len(b) && (b[i] == ' ' || b[i] == '\t' || b[i] == '\r' || b[i] == '\n' || b[i] == '\f' || b[i] == 0) {
+		i++
+	}
+	return i
+}
+
+// skipValue returns the end of the PDF value starting at [i]: dictionary, array, string, name,
+// reference "N G R" or another token.
+func skipValue(b []byte, i int) int {
+	if i >= len(b) {
+		return i
+	}
+	switch {
+	case bytes.HasPrefix(b[i:], []byte("<<")):
+		for i = skipSpace(b, i+2); i < len(b) && !bytes.HasPrefix(b[i:], []byte(">>")); i = skipSpace(b, i) {
+			i = skipValue(b, i)
+		}
+		return min(i+2, len(b))
+	case b[i] == '[':
+		for i = skipSpace(b, i+1); i < len(b) && b[i] != ']'; i = skipSpace(b, i) {
+			i = skipValue(b, i)
+		}
+		return min(i+1, len(b))
+	case b[i] == '<':
+		if end := bytes.IndexByte(b[i:], '>'); end >= 0 {
+			return i + end + 1
+		}
+		return len(b)
+	case b[i] == '(':
+		return skipLiteralString(b, i)
+	case b[i] == '/':
+		i++
+	case b[i] == ')' || b[i] == '>' || b[i] == ']' || b[i] == '}' || b[i] == '{':
+		return i + 1
+	}
+	if _, end, ok := refAt(b, i); ok {
+		return end
+	}
+	for i < len(b) && !isPdfDelimiter(b[i]) {
+		i++
+	}
+	return i
+}
+
+// skipLiteralString returns the end of the string in parentheses starting at [i], with balanced and escaped parentheses
+func skipLiteralString(b []byte, i int) int {
+	depth := 0
+	for ; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(b)
+}
+
+// refAt parses the reference "N G R" starting at [i], returns the object number and the end of the reference
+func refAt(b []byte, i int) (number, end int, ok bool) {
+	readInt := func(i int) (int, int, bool) {
+		j := i
+		for j < len(b) && b[j] >= '0' && b[j] <= '9' {
+			j++
+		}
+		if j == i || (j < len(b) && !isPdfDelimiter(b[j])) {
+			return 0, i, false
+		}
+		n, err := strconv.Atoi(string(b[i:j]))
+		return n, j, err == nil
+	}
+
+	number, j, ok := readInt(i)
+	if !ok {
+		return 0, i, false
+	}
+	if _, j, ok = readInt(skipSpace(b, j)); !ok {
+		return 0, i, false
+	}
+	j = skipSpace(b, j)
+	if j >= len(b) || b[j] != 'R' || (j+1 < len(b) && !isPdfDelimiter(b[j+1])) {
+		return 0, i, false
+	}
+	return number, j + 1, true
+}
+
+// parseRef parses the value that is a single reference "N G R"
+func parseRef(value []byte) (int, bool) {
+	value = bytes.TrimSpace(value)
+	number, end, ok := refAt(value, 0)
+	return number, ok && end == len(value)
+}
+
+// dictValue returns the value of the [key] in the dictionary at the start of [obj], nil if there is no such key
+func dictValue(obj []byte, key string) []byte {
+	i := skipSpace(obj, 0)
+	if !bytes.HasPrefix(obj[i:], []byte("<<")) {
+		return nil
+	}
+	for i = skipSpace(obj, i+2); i < len(obj) && obj[i] == '/'; {
+		nameEnd := skipValue(obj, i)
+		valueStart := skipSpace(obj, nameEnd)
+		valueEnd := skipValue(obj, valueStart)
+		if string(obj[i+1:nameEnd]) == key {
+			return obj[valueStart:valueEnd]
+		}
+		i = skipSpace(obj, valueEnd)
+	}
+	return nil
+}
+
+// parseNumbers parses the array of numbers, e.g. the MediaBox rectangle
+func parseNumbers(value []byte) ([]float64, error) {
+	var numbers []float64
+	for _, field := range bytes.Fields(bytes.Trim(bytes.TrimSpace(value), "[]")) {
+		n, err := strconv.ParseFloat(string(field), 64)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+// refsIn returns the numbers of the objects referenced in [b], strings are skipped
+func refsIn(b []byte) []int {
+	var refs []int
+	rewriteRefs(b, func(number int) int {
+		refs = append(refs, number)
+		return number
+	})
+	return refs
+}
+
+// rewriteRefs returns the copy of [b] with every reference "N G R" replaced by "renumber(N) 0 R". Strings are kept as is.
+func rewriteRefs(b []byte, renumber func(int) int) []byte {
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); {
+		switch {
+		case bytes.HasPrefix(b[i:], []byte("<<")), bytes.HasPrefix(b[i:], []byte(">>")):
+			out = append(out, b[i:i+2]...)
+			i += 2
+			continue
+		case b[i] == '(':
+			end := skipLiteralString(b, i)
+			out = append(out, b[i:end]...)
+			i = end
+			continue
+		case b[i] == '<':
+			end := skipValue(b, i)
+			out = append(out, b[i:end]...)
+			i = end
+			continue
+		}
+		if i == 0 || isPdfDelimiter(b[i-1]) {
+			if number, end, ok := refAt(b, i); ok {
+				out = fmt.Appendf(out, "%d 0 R", renumber(number))
+				i = end
+				continue
+			}
+		}
+		out = append(out, b[i])
+		i++
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package pdf
+
+import (
+	"bytes"
+	"li-acc/internal/errs"
+	"li-acc/pkg/model"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/signintech/pdft"
+	"github.com/stretchr/testify/require"
+)
+
+// saveTestReceipts fills the native template with [n] payers and returns the paths of the saved receipts
+func saveTestReceipts(t *testing.T, n int) []string {
+	t.Helper()
+	qrImg, err := generateFrameImage(10, 10, colorBlack)
+	require.NoError(t, err)
+
+	var paths []string
+	for i := range n {
+		canvas := newTestCanvas(t, nil)
+		payer := model.Payer{
+			PersAcc:  strconv.Itoa(100000 + i),
+			CHILDFIO: "Зубенко Михаил Петрович",
+			Purpose:  "10a доп питание сент",
+			CBC:      "82100000000000000131",
+			OKTMO:    "98790098",
+			Sum:      model.NewMoney(int64(100+i), 0),
+		}
+		require.NoError(t, canvas.Fill(payer, qrImg))
+		path := filepath.Join(t.TempDir(), payer.PersAcc+".pdf")
+		require.NoError(t, canvas.Save(path))
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestRenderReceiptsForPrint(t *testing.T) {
+	receipts := saveTestReceipts(t, 3)
+
+	data, err := RenderReceiptsForPrint(receipts)
+	require.NoError(t, err)
+
+	// the print file is read by another PDF parser
+	var doc pdft.PDFt
+	require.NoError(t, doc.OpenFrom(bytes.NewReader(data)))
+	require.Equal(t, 2, doc.GetNumberOfPage(), "two receipts per page")
+
+	f, err := readPDF(data)
+	require.NoError(t, err)
+	var forms, pages [][]byte
+	for _, body := range f.objs {
+		switch string(dictValue(body, "Type")) {
+		case "/Page":
+			pages = append(pages, body)
+		case "/XObject":
+			if string(dictValue(body, "Subtype")) == "/Form" {
+				forms = append(forms, body)
+			}
+		}
+	}
+	require.Len(t, forms, 3, "every receipt is embedded once")
+	require.Len(t, pages, 2)
+
+	for _, form := range forms {
+		require.Equal(t, "[0 420.945 595.28 841.89]", string(dictValue(form, "BBox")), "only the top half of the receipt is printed")
+		for _, ref := range refsIn(dictValue(form, "Resources")) {
+			require.Contains(t, f.objs, ref, "resources of the receipt are copied")
+		}
+		dict, stream := splitStream(form)
+		content, err := decodeStream(dict, streamData(stream))
+		require.NoError(t, err)
+		require.Contains(t, string(content), "/I1 Do", "receipt content with QR code is copied")
+	}
+
+	for _, page := range pages {
+		content, err := f.pageContent(page)
+		require.NoError(t, err)
+		require.Contains(t, string(content), "/R1 Do")
+		require.Contains(t, string(content), "[4 3] 0 d", "cut line is drawn")
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := RenderReceiptsForPrint(nil)
+		require.Error(t, err)
+		require.True(t, errs.IsSystemError(err))
+	})
+
+	t.Run("not pdf", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "receipt.pdf")
+		require.NoError(t, os.WriteFile(path, []byte("not a pdf"), 0o644))
+		_, err := RenderReceiptsForPrint([]string{receipts[0], path})
+		require.Error(t, err)
+	})
+}
+
+func TestRewriteRefs(t *testing.T) {
+	obj := []byte("<< /Font 10 0 R /Kids [1 0 R 22 0 R] /Box [0 0 595 841] /Title (1 0 R) /Id <12 0> >>")
+	require.Equal(t, []int{10, 1, 22}, refsIn(obj), "references in strings are skipped")
+
+	got := rewriteRefs(obj, func(n int) int { return n + 100 })
+	require.Equal(t, "<< /Font 110 0 R /Kids [101 0 R 122 0 R] /Box [0 0 595 841] /Title (1 0 R) /Id <12 0> >>", string(got))
+
+	require.Equal(t, "[101 0 R 122 0 R]", string(dictValue(got, "Kids")))
+	require.Equal(t, "(1 0 R)", string(dictValue(got, "Title")))
+	require.Nil(t, dictValue(got, "Parent"))
+}
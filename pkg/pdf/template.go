@@ -0,0 +1,304 @@
+package pdf
+
+import (
+	"fmt"
+	"li-acc/internal/errs"
+	"li-acc/pkg/model"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gopdf "github.com/signintech/pdft/minigopdf"
+)
+
+// The receipt form consists of two equal parts: the notice (top) and the receipt itself (bottom).
+// All coordinates are in points from the top left corner of the A4 page and are taken from the pattern
+// converted from Excel (pkg/pdf/integration/testdata/template.pdf), so the frames of the Canvas fit both templates.
+const (
+	// OffsetBottomPartY is the distance between the same lines of the top and the bottom parts of the form
+	OffsetBottomPartY = 190.942
+
+	formLineWidth      = 0.75
+	formUnderlineWidth = 0.312
+
+	// column of the cells with organization and payer credentials, between the vertical lines
+	formColumnLeft  = 161.291
+	formColumnRight = 541.389
+	formTextX       = 162.312 // left aligned text of the column
+	formWrapWidth   = 376     // width of the column text before it is wrapped
+
+	// column of the part title ("Извещение", "Квитанция"), the narrow column before it is left blank
+	formTitleLeft  = 39.5
+	formTitleRight = formColumnLeft
+)
+
+// TemplateLayoutVersion is the version of the form drawn by RenderReceiptTemplate.
+// It must be increased with any change of the layout, so the templates cached before are made again.
+const TemplateLayoutVersion = 1
+
+// Font sizes of the form text
+const (
+	FormLabelFontSize   = 6
+	FormTitleFontSize   = 10
+	OrgNameFontSize     = 9
+	OrgAccountFontSize  = 8
+	OrgBankFontSize     = 8
+	formLineSpacingRate = 1.11 // line height of wrapped text relative to the font size
+)
+
+// formAlign is the horizontal alignment of the form text
+type formAlign int
+
+const (
+	alignLeft   formAlign = iota // starts at formTextX
+	alignColumn                  // centered in the credentials column
+	alignTitle                   // centered in the title column
+)
+
+// formText is one text of the form part. Y is the baseline of the text, or of its last line if the text is wrapped.
+type formText struct {
+	Text  string
+	Y     float64
+	Size  int
+	Align formAlign
+	Wrap  bool
+}
+
+// formLine is a line of the form part
+type formLine struct {
+	X1, Y1, X2, Y2 float64
+}
+
+// formFrameLines are the lines of the top part of the form. The bottom border is drawn once after both parts.
+var formFrameLines = []formLine{
+	{27.95, 14.118, 541.757, 14.118},                    // top border
+	{28.318, 13.749, 28.318, 205.059},                   // left border
+	{formColumnLeft, 13.749, formColumnLeft, 205.059},   // title column border
+	{formColumnRight, 13.749, formColumnRight, 205.059}, // right border
+	{160.923, 49.352, 541.757, 49.352},                  // under the organization name
+	{160.923, 71.831, 541.757, 71.831},                  // under the INN and account
+	{160.923, 104.798, 541.757, 104.798},                // under the bank
+	{160.923, 139.891, 541.757, 139.891},                // under the payer credentials
+	{160.923, 164.609, 541.757, 164.609},                // under the amount
+}
+
+// formBottomBorder is the line under the bottom part
+var formBottomBorder = formLine{27.95, 396.001, 541.757, 396.001}
+
+// formPart holds the static texts that differ between the parts of the form
+type formPart struct {
+	Title         string
+	Header        string // bank name and the form number
+	AccountLabels string // labels of the INN and the account, under them
+	BankLabel     string
+}
+
+// formParts are the top (notice) and the bottom (receipt) parts of the form
+var formParts = [2]formPart{
+	{
+		Title:         "Извещение",
+		Header:        "ПАО СБЕРБАНК" + strings.Repeat(" ", 114) + "Форма №ПД-4",
+		AccountLabels: "  (инн получателя платежа)" + strings.Repeat(" ", 52) + "(номер счёта получателя платежа)",
+		BankLabel:     "(наименование банка получателя платежа)",
+	},
+	{
+		Title:         "Квитанция",
+		Header:        "ПАО СБЕРБАНК" + strings.Repeat(" ", 119) + "Форма №ПД-4",
+		AccountLabels: "  (инн получателя платежа)" + strings.Repeat(" ", 56) + "(номер счёта получателя платежа)",
+		BankLabel:     " (наименование банка получателя платежа)",
+	},
+}
+
+// labels returns the static texts of the form part, with the coordinates of the top part
+func (p formPart) labels() []formText {
+	return []formText{
+		{Text: p.Header, Y: 21.403, Size: FormLabelFontSize, Align: alignLeft},
+		{Text: p.Title, Y: 46.291, Size: FormTitleFontSize, Align: alignTitle},
+		{Text: "(наименование получателя платежа)", Y: 55.815, Size: FormLabelFontSize, Align: alignColumn},
+		{Text: p.AccountLabels, Y: 78.294, Size: FormLabelFontSize, Align: alignLeft},
+		{Text: p.BankLabel, Y: 111.204, Size: FormLabelFontSize, Align: alignColumn},
+		{Text: "(назначение платежа)", Y: 146.297, Size: FormLabelFontSize, Align: alignColumn},
+		{Text: "(сумма платежа)", Y: 171.015, Size: FormLabelFontSize, Align: alignColumn},
+		{Text: "С условиями приёма указанной в платёжном документе суммы, в т.ч. с суммой взимаемой платы за услуги", Y: 187.088, Size: FormLabelFontSize, Align: alignLeft},
+		{Text: "банка, ознакомлен и согласен.                    Подпись плательщика", Y: 202.792, Size: FormLabelFontSize, Align: alignLeft},
+	}
+}
+
+// signature field of the payer: the underlined blank after the label
+const (
+	formSignatureX     = 345.798
+	formSignatureY     = 202.792
+	formSignatureWidth = 68.003
+	formSignatureDrop  = 0.595 // distance from the baseline to the underline
+)
+
+// orgTexts returns the organization credentials of the form part, as the pattern converted from Excel has them
+func orgTexts(org model.Organization) []formText {
+	return []formText{
+		{Text: org.Name, Y: 46.489, Size: OrgNameFontSize, Align: alignColumn, Wrap: true},
+		{
+			Text: fmt.Sprintf("  ИНН %s КПП %s%s%s", org.PayeeINN, org.KPP, strings.Repeat(" ", 25), org.PersonalAcc),
+			Y:    69.195, Size: OrgAccountFontSize, Align: alignLeft,
+		},
+		{Text: fmt.Sprintf("БИК %s (%s)", org.BIC, org.BankName), Y: 102.105, Size: OrgBankFontSize, Align: alignColumn, Wrap: true},
+	}
+}
+
+// GenerateReceiptTemplate draws the receipt form with the organization credentials and saves it to pdfDst.
+// The template has the same layout as the one converted from the Excel pattern, without any external converter.
+// [fontPath] is the path to the text font. Empty string causes using default font.
+func GenerateReceiptTemplate(pdfDst, fontPath string, org model.Organization) error {
+	data, err := RenderReceiptTemplate(fontPath, org)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(pdfDst, data, 0644); err != nil {
+		return errs.WrapIOError("save receipt template", pdfDst, err)
+	}
+	return nil
+}
+
+// RenderReceiptTemplate draws the receipt form with the organization credentials and returns the PDF in memory.
+func RenderReceiptTemplate(fontPath string, org model.Organization) ([]byte, error) {
+	if fontPath == "" {
+		fontPath = DefaultFontPath
+	}
+	absFontPath, err := filepath.Abs(fontPath)
+	if err != nil {
+		return nil, errs.Wrap(errs.System, "failed to resolve font path", err)
+	}
+
+	var doc gopdf.GoPdf
+	doc.Start(gopdf.Config{Unit: "pt", PageSize: gopdf.Rect{W: 595.28, H: 841.89}})
+	doc.AddPage()
+	if err := doc.AddTTFFont(DefaultFontName, absFontPath); err != nil {
+		return nil, errs.Wrap(errs.System, "failed to upload font", err)
+	}
+	w, err := newFormWriter(&doc, absFontPath)
+	if err != nil {
+		return nil, errs.Wrap(errs.System, "failed to upload font", err)
+	}
+
+	for i, part := range formParts {
+		offset := float64(i) * OffsetBottomPartY
+
+		doc.SetLineWidth(formLineWidth)
+		for _, l := range formFrameLines {
+			doc.Line(l.X1, l.Y1+offset, l.X2, l.Y2+offset)
+		}
+
+		for _, t := range append(part.labels(), orgTexts(org)...) {
+			t.Y += offset
+			if err := w.text(t); err != nil {
+				return nil, errs.Wrap(errs.System, "failed to draw receipt form text", err)
+			}
+		}
+
+		if err := w.signatureField(offset); err != nil {
+			return nil, errs.Wrap(errs.System, "failed to draw receipt form text", err)
+		}
+	}
+	doc.SetLineWidth(formLineWidth)
+	doc.Line(formBottomBorder.X1, formBottomBorder.Y1, formBottomBorder.X2, formBottomBorder.Y2)
+
+	data, err := doc.GetBytesPdfReturnErr()
+	if err != nil {
+		return nil, errs.Wrap(errs.System, "failed to render receipt template", err)
+	}
+	return data, nil
+}
+
+// formWriter writes the form text with the only font of the document
+type formWriter struct {
+	doc      *gopdf.GoPdf
+	measurer *textMeasurer
+	size     int
+}
+
+func newFormWriter(doc *gopdf.GoPdf, fontPath string) (*formWriter, error) {
+	measurer, err := newTextMeasurer(fontPath)
+	if err != nil {
+		return nil, err
+	}
+	return &formWriter{doc: doc, measurer: measurer}, nil
+}
+
+func (w *formWriter) setFontSize(size int) error {
+	w.size = size
+	return w.doc.SetFont(DefaultFontName, FontStyleRegular, size)
+}
+
+// measure returns the width of the text with the current font size.
+func (w *formWriter) measure(text string) (float64, error) {
+	return w.measurer.width(text, float64(w.size))
+}
+
+// text writes the text at its baseline. Wrapped text grows upwards, so its last line stays at the baseline,
+// as in bottom aligned Excel cells.
+func (w *formWriter) text(t formText) error {
+	if err := w.setFontSize(t.Size); err != nil {
+		return err
+	}
+
+	lines := []string{t.Text}
+	if t.Wrap {
+		var err error
+		if lines, err = w.wrap(t.Text, formWrapWidth); err != nil {
+			return err
+		}
+	}
+
+	lineHeight := float64(t.Size) * formLineSpacingRate
+	for i, line := range lines {
+		x, err := w.alignedX(line, t.Align)
+		if err != nil {
+			return err
+		}
+		w.doc.SetX(x)
+		w.doc.SetY(t.Y - float64(len(lines)-1-i)*lineHeight)
+		if err := w.doc.Text(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signatureField writes the underlined blank for the payer's signature
+func (w *formWriter) signatureField(offset float64) error {
+	if err := w.setFontSize(FormLabelFontSize); err != nil {
+		return err
+	}
+	y := formSignatureY + offset
+	w.doc.SetX(formSignatureX + formSignatureWidth)
+	w.doc.SetY(y)
+	if err := w.doc.Text(`\`); err != nil {
+		return err
+	}
+
+	w.doc.SetLineWidth(formUnderlineWidth)
+	w.doc.Line(formSignatureX, y+formSignatureDrop, formSignatureX+formSignatureWidth, y+formSignatureDrop)
+	return nil
+}
+
+// alignedX returns the x coordinate the line starts at
+func (w *formWriter) alignedX(line string, align formAlign) (float64, error) {
+	left, right := formColumnLeft, formColumnRight
+	switch align {
+	case alignLeft:
+		return formTextX, nil
+	case alignTitle:
+		left, right = formTitleLeft, formTitleRight
+	}
+
+	width, err := w.measure(line)
+	if err != nil {
+		return 0, err
+	}
+	return (left + right - width) / 2, nil
+}
+
+// wrap splits the text into lines not wider than maxWidth with the current font size, breaking at spaces.
+// Repeated spaces are collapsed, as Excel does when it wraps the cell text.
+func (w *formWriter) wrap(text string, maxWidth float64) ([]string, error) {
+	return w.measurer.wrap(strings.Join(strings.Fields(text), " "), float64(w.size), maxWidth)
+}
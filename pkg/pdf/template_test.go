@@ -0,0 +1,119 @@
+package pdf
+
+import (
+	"bytes"
+	"li-acc/pkg/model"
+	"testing"
+
+	gopdf "github.com/signintech/pdft/minigopdf"
+	"github.com/stretchr/testify/require"
+)
+
+const testFontPath = "../../static/fonts/Arial.ttf"
+
+// orgFix is the organization of the pattern converted from Excel (integration/testdata/template.pdf)
+var orgFix = model.Organization{
+	Name:        `Муниципальное автономное общеобразовательное учреждение "Лицей-интернат №7" Ново-Савиновского района г.Казани (л/с ЛАВ71821027ЛИЦИН7)`,
+	PayeeINN:    "1657032924",
+	KPP:         "165701001",
+	PersonalAcc: "03234643927010001100",
+	BIC:         "019205400",
+	BankName:    "ОТДЕЛЕНИЕ-НБ РЕСПУБЛИКА ТАТАРСТАН БАНКА РОССИИ//УФК по Республике Татарстан г Казань",
+}
+
+func newTestFormWriter(t *testing.T) *formWriter {
+	t.Helper()
+	var doc gopdf.GoPdf
+	doc.Start(gopdf.Config{Unit: "pt", PageSize: gopdf.Rect{W: 595.28, H: 841.89}})
+	doc.AddPage()
+	require.NoError(t, doc.AddTTFFont(DefaultFontName, testFontPath))
+	w, err := newFormWriter(&doc, testFontPath)
+	require.NoError(t, err)
+	return w
+}
+
+func TestFormWriter_WrapAndAlign(t *testing.T) {
+	w := newTestFormWriter(t)
+
+	// lines and their x coordinates in the pattern converted from Excel
+	tests := []struct {
+		name  string
+		text  string
+		size  int
+		lines []string
+		xs    []float64
+	}{
+		{
+			name: "organization name",
+			text: orgFix.Name,
+			size: OrgNameFontSize,
+			lines: []string{
+				`Муниципальное автономное общеобразовательное учреждение "Лицей-интернат №7"`,
+				`Ново-Савиновского района г.Казани (л/с ЛАВ71821027ЛИЦИН7)`,
+			},
+			xs: []float64{169.087, 216.113},
+		},
+		{
+			name: "bank",
+			text: "БИК 019205400 (" + orgFix.BankName + ")",
+			size: OrgBankFontSize,
+			lines: []string{
+				"БИК 019205400 (ОТДЕЛЕНИЕ-НБ РЕСПУБЛИКА ТАТАРСТАН БАНКА РОССИИ//УФК по Республике",
+				"Татарстан г Казань)",
+			},
+			xs: []float64{164.409, 314.306},
+		},
+		{
+			name:  "short",
+			text:  "  МАОУ   Тест ",
+			size:  OrgNameFontSize,
+			lines: []string{"МАОУ Тест"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, w.setFontSize(tt.size))
+			lines, err := w.wrap(tt.text, formWrapWidth)
+			require.NoError(t, err)
+			require.Equal(t, tt.lines, lines)
+
+			for i, x := range tt.xs {
+				got, err := w.alignedX(lines[i], alignColumn)
+				require.NoError(t, err)
+				require.InDelta(t, x, got, 0.5, lines[i])
+			}
+		})
+	}
+}
+
+func TestFormWriter_TitleAlign(t *testing.T) {
+	w := newTestFormWriter(t)
+	require.NoError(t, w.setFontSize(FormTitleFontSize))
+
+	for i, x := range []float64{73.814, 75.912} {
+		got, err := w.alignedX(formParts[i].Title, alignTitle)
+		require.NoError(t, err)
+		require.InDelta(t, x, got, 0.5, formParts[i].Title)
+	}
+}
+
+func TestFormWriter_LongWord(t *testing.T) {
+	w := newTestFormWriter(t)
+	require.NoError(t, w.setFontSize(OrgNameFontSize))
+
+	long := string(bytes.Repeat([]byte("Ш"), 60))
+	lines, err := w.wrap("ООО "+long+" Рога", formWrapWidth)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ООО", long, "Рога"}, lines)
+}
+
+func TestRenderReceiptTemplate(t *testing.T) {
+	data, err := RenderReceiptTemplate(testFontPath, orgFix)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(data, []byte("%PDF-")))
+	require.Contains(t, string(data), "/MediaBox [ 0 0 595.28 841.89 ]")
+
+	_, err = RenderReceiptTemplate("not-exists.ttf", orgFix)
+	require.Error(t, err)
+}
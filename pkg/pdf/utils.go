@@ -55,36 +55,14 @@ func trimEachLine(s string) string {
 	return strings.Join(lines, "\n")
 }
 
-// formatAmount converts a monetary amount string into the format needed for PDF receipts.
-// The amount can use either comma or dot as a decimal separator.
-// Integers without decimals are treated as zero cents.
-// Returns a string in the format "{rubles} руб. {kopeks} коп." with exactly two digits for kopeks.
+// formatAmount converts a monetary amount into the format needed for PDF receipts.
+// Returns a string in the format "Сумма: {rubles} руб. {kopeks} коп." with exactly two digits for kopeks.
 // Examples:
 //
-//	"2000"    -> "2000 руб. 00 коп."
-//	"3200,80" -> "3200 руб. 80 коп."
-//	"100.05"  -> "100 руб. 05 коп."
-func formatAmount(amount string) string {
-	// Replace comma with dot for uniformity
-	parts := strings.Split(strings.ReplaceAll(amount, ",", "."), ".")
-
-	// rubles part
-	rubles := parts[0]
-
-	// kopeks part
-	var kopeks string
-	if len(parts) > 1 {
-		kopeks = parts[1]
-		if len(kopeks) == 1 {
-			kopeks = kopeks + "0" // pad single digit with zero
-		} else if len(kopeks) > 2 {
-			kopeks = kopeks[:2] // truncate to two digits
-		}
-	} else {
-		kopeks = "00"
-	}
-
-	return fmt.Sprintf("Сумма: %s руб. %s коп.", rubles, kopeks)
+//	2000.00 -> "Сумма: 2000 руб. 00 коп."
+//	100.05  -> "Сумма: 100 руб. 05 коп."
+func formatAmount(amount model.Money) string {
+	return "Сумма: " + amount.RubKopString()
 }
 
 func formatPayerInfo(payerData model.Payer) string {
@@ -3,6 +3,8 @@ package pdf
 import (
 	"testing"
 
+	"li-acc/pkg/model"
+
 	"github.com/stretchr/testify/require"
 )
 
@@ -115,44 +117,29 @@ func TestPrettifyCredentialsString(t *testing.T) {
 func TestFormatAmount(t *testing.T) {
 	tests := []struct {
 		name   string
-		amount string
+		amount model.Money
 		want   string
 	}{
 		{
-			name:   "integer without decimal",
-			amount: "2000",
+			name:   "whole rubles",
+			amount: model.NewMoney(2000, 0),
 			want:   "Сумма: 2000 руб. 00 коп.",
 		},
 		{
-			name:   "integer with comma",
-			amount: "3200,80",
+			name:   "rubles and kopeks",
+			amount: model.NewMoney(3200, 80),
 			want:   "Сумма: 3200 руб. 80 коп.",
 		},
-		{
-			name:   "integer with dot",
-			amount: "450.50",
-			want:   "Сумма: 450 руб. 50 коп.",
-		},
 		{
 			name:   "zero amount",
-			amount: "0",
+			amount: model.NewMoney(0, 0),
 			want:   "Сумма: 0 руб. 00 коп.",
 		},
 		{
-			name:   "decimal less than 10",
-			amount: "100.05",
+			name:   "kopeks less than 10",
+			amount: model.NewMoney(100, 5),
 			want:   "Сумма: 100 руб. 05 коп.",
 		},
-		{
-			name:   "decimal with comma less than 10",
-			amount: "78,07",
-			want:   "Сумма: 78 руб. 07 коп.",
-		},
-		{
-			name:   "integer with only one decimal number",
-			amount: "1200.3",
-			want:   "Сумма: 1200 руб. 30 коп.",
-		},
 	}
 
 	for _, tt := range tests {
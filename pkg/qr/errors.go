@@ -0,0 +1,78 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"li-acc/internal/errs"
+	"strings"
+)
+
+var (
+	// ErrRequired is returned when a required requisite (Name, PersonalAcc, BankName, BIC, CorrespAcc) is missing or empty
+	ErrRequired = errors.New("required requisite is empty")
+	// ErrTooLong is returned when the value is longer than the standard allows, see RequisiteError.MaxLength
+	ErrTooLong = errors.New("value is too long")
+	// ErrNotDigits is returned when the value of a numeric requisite (accounts, BIC, INN, Sum, ...) contains not only digits
+	ErrNotDigits = errors.New("value must contain only digits")
+	// ErrForbiddenChars is returned when the value contains line breaks or other control characters
+	ErrForbiddenChars = errors.New("value contains forbidden characters")
+	// ErrNotEncodable is returned when the value contains characters that the payload encoding can not represent
+	ErrNotEncodable = errors.New("value contains characters not supported by the encoding")
+	// ErrInvalidKey is returned when the requisite name is empty or contains not only latin letters and digits
+	ErrInvalidKey = errors.New("invalid requisite name")
+	// ErrDuplicateKey is returned when the same requisite is met twice in the parsed string
+	ErrDuplicateKey = errors.New("duplicate requisite")
+	// ErrNoSeparator is returned when every separator character is met in the values, so the payload can not be joined
+	ErrNoSeparator = errors.New("no separator character is free in the values")
+	// ErrUnknownEncoding is returned for an encoding code other than 1, 2 or 3
+	ErrUnknownEncoding = errors.New("unknown encoding")
+	// ErrNotPayload is returned when the parsed string does not start with the format identifier "ST0001"
+	ErrNotPayload = errors.New("not a payment payload")
+)
+
+// RequisiteError describes an invalid requisite of the payment payload.
+// Err is one of the errors above, so the problem can be checked with errors.Is.
+type RequisiteError struct {
+	Key       string // name of the requisite, e.g. "BankName"
+	Value     string // the invalid value
+	Err       error  // what is wrong with the value
+	MaxLength int    // for ErrTooLong: maximum number of characters allowed by the standard
+}
+
+func (e *RequisiteError) Error() string {
+	msg := fmt.Sprintf("requisite %s (%q): %v", e.Key, e.Value, e.Err)
+	if errors.Is(e.Err, ErrTooLong) {
+		msg += fmt.Sprintf(" (max %d characters)", e.MaxLength)
+	}
+	return msg
+}
+
+func (e *RequisiteError) Kind() errs.Kind {
+	return errs.User
+}
+
+func (e *RequisiteError) Unwrap() error {
+	return e.Err
+}
+
+// PayloadError error raised when the payment payload can not be built from the organization and payer data.
+// It collects problems of all requisites, so the user can fix the settings at once.
+type PayloadError struct {
+	Problems []*RequisiteError
+}
+
+func (e *PayloadError) Error() string {
+	var msg []string
+	for _, p := range e.Problems {
+		msg = append(msg, p.Error())
+	}
+	return fmt.Sprintf("invalid payment payload: [%s]", strings.Join(msg, "; "))
+}
+
+func (e *PayloadError) Kind() errs.Kind {
+	return errs.User
+}
+
+func (e *PayloadError) Unwrap() error {
+	return nil
+}
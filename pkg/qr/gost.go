@@ -0,0 +1,372 @@
+package qr
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Encoding is the character set of the payment payload. Its code is the last character of the format identifier,
+// e.g. "ST00012" is the payload in UTF-8.
+type Encoding byte
+
+const (
+	EncodingWindows1251 Encoding = '1' // ST00011, cp1251
+	EncodingUTF8        Encoding = '2' // ST00012
+	EncodingKOI8R       Encoding = '3' // ST00013
+)
+
+// formatPrefix is the format identifier without the encoding code: "ST" and the version of the standard "0001"
+const formatPrefix = "ST0001"
+
+// Separator is the recommended separator of the requisites. When some value contains it,
+// Builder chooses another one from separators, as the standard allows any character not met in the values.
+const Separator = '|'
+
+var separators = []rune{Separator, ';', '#', '~', '^', '`'}
+
+// ParseEncoding returns the encoding by its code: "1" - Windows-1251, "2" - UTF-8, "3" - KOI8-R.
+func ParseEncoding(code string) (Encoding, error) {
+	code = strings.TrimSpace(code)
+	if len(code) != 1 {
+		return 0, ErrUnknownEncoding
+	}
+	switch e := Encoding(code[0]); e {
+	case EncodingWindows1251, EncodingUTF8, EncodingKOI8R:
+		return e, nil
+	default:
+		return 0, ErrUnknownEncoding
+	}
+}
+
+// Header returns the format identifier of the payload, e.g. "ST00012".
+func (e Encoding) Header() string {
+	return formatPrefix + string(rune(e))
+}
+
+// charmap returns the single-byte character map of the encoding, or nil for UTF-8.
+func (e Encoding) charmap() *charmap.Charmap {
+	switch e {
+	case EncodingWindows1251:
+		return charmap.Windows1251
+	case EncodingKOI8R:
+		return charmap.KOI8R
+	default:
+		return nil
+	}
+}
+
+// canEncode reports whether all characters of s can be represented in the encoding.
+func (e Encoding) canEncode(s string) bool {
+	cm := e.charmap()
+	if cm == nil {
+		return utf8.ValidString(s)
+	}
+	for _, r := range s {
+		if _, ok := cm.EncodeRune(r); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// EncodePayload converts the payload built by Builder (always a Go UTF-8 string) into the bytes of the encoding
+// declared in its format identifier. Strings without the identifier are returned as is.
+func EncodePayload(payload string) ([]byte, error) {
+	if len(payload) < len(formatPrefix)+1 || !strings.HasPrefix(payload, formatPrefix) {
+		return []byte(payload), nil
+	}
+
+	enc, err := ParseEncoding(payload[len(formatPrefix) : len(formatPrefix)+1])
+	if err != nil {
+		return nil, err
+	}
+	cm := enc.charmap()
+	if cm == nil {
+		return []byte(payload), nil
+	}
+
+	data, err := cm.NewEncoder().Bytes([]byte(payload))
+	if err != nil {
+		return nil, ErrNotEncodable
+	}
+	return data, nil
+}
+
+// Required requisites of the payment, they always go first and in this order
+const (
+	KeyName        = "Name"        // name of the payee
+	KeyPersonalAcc = "PersonalAcc" // settlement account of the payee
+	KeyBankName    = "BankName"    // name of the payee's bank
+	KeyBIC         = "BIC"         // BIC of the payee's bank
+	KeyCorrespAcc  = "CorrespAcc"  // correspondent account of the payee's bank, "0" if there is none
+)
+
+// Optional requisites of the payment (table 2 of the standard)
+const (
+	KeySum          = "Sum"          // amount in kopeks
+	KeyPurpose      = "Purpose"      // purpose of the payment
+	KeyPayeeINN     = "PayeeINN"     // INN of the payee
+	KeyPayerINN     = "PayerINN"     // INN of the payer
+	KeyDrawerStatus = "DrawerStatus" // status of the tax payment drawer
+	KeyKPP          = "KPP"          // KPP of the payee
+	KeyCBC          = "CBC"          // budget classification code (КБК)
+	KeyOKTMO        = "OKTMO"        // municipality code
+	KeyOKATO        = "OKATO"        // administrative division code
+	KeyPaytReason   = "PaytReason"   // basis of the tax payment
+	KeyTaxPeriod    = "TaxPeriod"    // tax period
+	KeyDocNo        = "DocNo"        // number of the tax document
+	KeyDocDate      = "DocDate"      // date of the tax document
+	KeyTaxPaytKind  = "TaxPaytKind"  // type of the tax payment
+)
+
+// Additional requisites of the payment (table 3 of the standard)
+const (
+	KeyLastName        = "LastName"
+	KeyFirstName       = "FirstName"
+	KeyMiddleName      = "MiddleName"
+	KeyPayerAddress    = "PayerAddress"
+	KeyPersonalAccount = "PersonalAccount" // personal account in the budget
+	KeyDocIdx          = "DocIdx"
+	KeyPensAcc         = "PensAcc" // СНИЛС
+	KeyContract        = "Contract"
+	KeyPersAcc         = "PersAcc" // personal account of the payer in the organization
+	KeyFlat            = "Flat"
+	KeyPhone           = "Phone"
+	KeyPayerIdType     = "PayerIdType"
+	KeyPayerIdNum      = "PayerIdNum"
+	KeyChildFio        = "ChildFio"
+	KeyBirthDate       = "BirthDate"
+	KeyPaymTerm        = "PaymTerm"
+	KeyPaymPeriod      = "PaymPeriod"
+	KeyCategory        = "Category"
+	KeyServiceName     = "ServiceName"
+	KeyCounterId       = "CounterId"
+	KeyCounterVal      = "CounterVal"
+	KeyQuittId         = "QuittId"
+	KeyQuittDate       = "QuittDate"
+	KeyInstNum         = "InstNum"
+	KeyClassNum        = "ClassNum"
+	KeySpecFio         = "SpecFio"
+	KeyAddAmount       = "AddAmount"
+	KeyRuleId          = "RuleId"
+	KeyExecId          = "ExecId"
+	KeyRegType         = "RegType"
+	KeyUIN             = "UIN"
+	KeyTechCode        = "TechCode"
+)
+
+// requiredKeys are the required requisites in the order of the standard
+var requiredKeys = []string{KeyName, KeyPersonalAcc, KeyBankName, KeyBIC, KeyCorrespAcc}
+
+// requisiteSpec is the limit of the requisite value set by the standard
+type requisiteSpec struct {
+	maxLength int  // maximum number of characters, 0 if not limited
+	digits    bool // the value consists only of digits
+}
+
+// specs are the limits of the known requisites. Requisites that are not listed here have no limits.
+var specs = map[string]requisiteSpec{
+	KeyName:        {maxLength: 160},
+	KeyPersonalAcc: {maxLength: 20, digits: true},
+	KeyBankName:    {maxLength: 45},
+	KeyBIC:         {maxLength: 9, digits: true},
+	KeyCorrespAcc:  {maxLength: 20, digits: true},
+
+	KeySum:          {maxLength: 18, digits: true},
+	KeyPurpose:      {maxLength: 210},
+	KeyPayeeINN:     {maxLength: 12, digits: true},
+	KeyPayerINN:     {maxLength: 12, digits: true},
+	KeyDrawerStatus: {maxLength: 2},
+	KeyKPP:          {maxLength: 9},
+	KeyCBC:          {maxLength: 20, digits: true},
+	KeyOKTMO:        {maxLength: 11, digits: true},
+	KeyOKATO:        {maxLength: 11, digits: true},
+	KeyPaytReason:   {maxLength: 2},
+	KeyTaxPeriod:    {maxLength: 10},
+	KeyDocNo:        {maxLength: 15},
+	KeyDocDate:      {maxLength: 10},
+	KeyTaxPaytKind:  {maxLength: 2},
+
+	KeyUIN:      {maxLength: 25},
+	KeyTechCode: {maxLength: 2, digits: true},
+}
+
+// knownKeys maps lower-cased names of all requisites of the standard to their canonical spelling
+var knownKeys = func() map[string]string {
+	keys := []string{
+		KeyName, KeyPersonalAcc, KeyBankName, KeyBIC, KeyCorrespAcc,
+		KeySum, KeyPurpose, KeyPayeeINN, KeyPayerINN, KeyDrawerStatus, KeyKPP, KeyCBC, KeyOKTMO, KeyOKATO,
+		KeyPaytReason, KeyTaxPeriod, KeyDocNo, KeyDocDate, KeyTaxPaytKind,
+		KeyLastName, KeyFirstName, KeyMiddleName, KeyPayerAddress, KeyPersonalAccount, KeyDocIdx, KeyPensAcc,
+		KeyContract, KeyPersAcc, KeyFlat, KeyPhone, KeyPayerIdType, KeyPayerIdNum, KeyChildFio, KeyBirthDate,
+		KeyPaymTerm, KeyPaymPeriod, KeyCategory, KeyServiceName, KeyCounterId, KeyCounterVal, KeyQuittId,
+		KeyQuittDate, KeyInstNum, KeyClassNum, KeySpecFio, KeyAddAmount, KeyRuleId, KeyExecId, KeyRegType,
+		KeyUIN, KeyTechCode,
+	}
+	m := make(map[string]string, len(keys))
+	for _, k := range keys {
+		m[strings.ToLower(k)] = k
+	}
+	return m
+}()
+
+// CanonicalKey returns the spelling of the requisite name as in the standard (names are case-insensitive,
+// so "CATEGORY" is "Category"), and false if the standard does not define such requisite.
+func CanonicalKey(key string) (string, bool) {
+	k, ok := knownKeys[strings.ToLower(key)]
+	return k, ok
+}
+
+// Requisite is a single "Key=Value" pair of the payment payload
+type Requisite struct {
+	Key   string
+	Value string
+}
+
+// Builder builds the payment payload in the format of GOST R 56042-2014
+// "Двумерные символы штрихового кода для осуществления платежей физических лиц":
+//
+//	ST00012|Name=...|PersonalAcc=...|BankName=...|BIC=...|CorrespAcc=...|Sum=...|...
+//
+// Required requisites go first in the order of the standard, the others in the order they were set.
+// Requisites that are not defined by the standard (e.g. bank specific ones) are allowed and written as they are.
+type Builder struct {
+	encoding   Encoding
+	requisites []Requisite
+}
+
+// NewBuilder creates the empty payload builder of the given encoding.
+func NewBuilder(encoding Encoding) *Builder {
+	return &Builder{encoding: encoding}
+}
+
+// Set adds the requisite to the payload, or replaces the value of the already set one
+// (names are compared case-insensitively). Empty values are written as "Key=", e.g. for the payer to fill them in.
+func (b *Builder) Set(key, value string) *Builder {
+	for i, r := range b.requisites {
+		if strings.EqualFold(r.Key, key) {
+			b.requisites[i].Value = value
+			return b
+		}
+	}
+	b.requisites = append(b.requisites, Requisite{Key: key, Value: value})
+	return b
+}
+
+// Build validates all requisites and returns the payload string. The string is always in UTF-8,
+// use EncodePayload to get the bytes of the declared encoding.
+// Returns *PayloadError with problems of all invalid requisites.
+func (b *Builder) Build() (string, error) {
+	var problems []*RequisiteError
+
+	values := make(map[string]string, len(b.requisites))
+	for _, r := range b.requisites {
+		values[strings.ToLower(r.Key)] = r.Value
+	}
+	for _, key := range requiredKeys {
+		if values[strings.ToLower(key)] == "" {
+			problems = append(problems, &RequisiteError{Key: key, Err: ErrRequired})
+		}
+	}
+
+	// required requisites first, then the others as they were set
+	ordered := make([]Requisite, 0, len(b.requisites))
+	for _, key := range requiredKeys {
+		for _, r := range b.requisites {
+			if strings.EqualFold(r.Key, key) {
+				ordered = append(ordered, r)
+			}
+		}
+	}
+	for _, r := range b.requisites {
+		if canonical, ok := CanonicalKey(r.Key); !ok || !slices.Contains(requiredKeys, canonical) {
+			ordered = append(ordered, r)
+		}
+	}
+
+	for _, r := range ordered {
+		if err := checkRequisite(r, b.encoding); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if _, err := ParseEncoding(string(rune(b.encoding))); err != nil {
+		problems = append(problems, &RequisiteError{Key: "ST", Value: string(rune(b.encoding)), Err: err})
+	}
+
+	sep, ok := chooseSeparator(ordered)
+	if !ok {
+		problems = append(problems, &RequisiteError{Key: "ST", Err: ErrNoSeparator})
+	}
+
+	if len(problems) > 0 {
+		return "", &PayloadError{Problems: problems}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(b.encoding.Header())
+	for _, r := range ordered {
+		sb.WriteRune(sep)
+		sb.WriteString(r.Key)
+		sb.WriteByte('=')
+		sb.WriteString(r.Value)
+	}
+	return sb.String(), nil
+}
+
+// checkRequisite checks the name of the requisite, the characters of its value and the limits of the standard.
+func checkRequisite(r Requisite, enc Encoding) *RequisiteError {
+	if !isValidKey(r.Key) {
+		return &RequisiteError{Key: r.Key, Value: r.Value, Err: ErrInvalidKey}
+	}
+	if strings.IndexFunc(r.Value, unicode.IsControl) != -1 {
+		return &RequisiteError{Key: r.Key, Value: r.Value, Err: ErrForbiddenChars}
+	}
+	if !enc.canEncode(r.Value) {
+		return &RequisiteError{Key: r.Key, Value: r.Value, Err: ErrNotEncodable}
+	}
+
+	canonical, _ := CanonicalKey(r.Key)
+	spec := specs[canonical]
+	if spec.maxLength > 0 && utf8.RuneCountInString(r.Value) > spec.maxLength {
+		return &RequisiteError{Key: r.Key, Value: r.Value, Err: ErrTooLong, MaxLength: spec.maxLength}
+	}
+	if spec.digits && strings.IndexFunc(r.Value, func(c rune) bool { return c < '0' || c > '9' }) != -1 {
+		return &RequisiteError{Key: r.Key, Value: r.Value, Err: ErrNotDigits}
+	}
+	return nil
+}
+
+// isValidKey reports whether the requisite name is not empty and consists of latin letters and digits.
+func isValidKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// chooseSeparator returns the first of separators that is not met in the values of requisites.
+func chooseSeparator(requisites []Requisite) (rune, bool) {
+	for _, sep := range separators {
+		free := true
+		for _, r := range requisites {
+			if strings.ContainsRune(r.Value, sep) {
+				free = false
+				break
+			}
+		}
+		if free {
+			return sep, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,144 @@
+package qr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// requiredBuilder returns the builder with all required requisites set
+func requiredBuilder(enc Encoding) *Builder {
+	return NewBuilder(enc).
+		Set(KeyName, "ООО Ромашка").
+		Set(KeyPersonalAcc, "40702810938000012345").
+		Set(KeyBankName, "ПАО Сбербанк").
+		Set(KeyBIC, "044525225").
+		Set(KeyCorrespAcc, "30101810400000000225")
+}
+
+func TestBuilder_Build(t *testing.T) {
+	// required requisites go first whatever order they were set in
+	payload, err := NewBuilder(EncodingUTF8).
+		Set(KeySum, "120050").
+		Set(KeyCorrespAcc, "0").
+		Set(KeyBIC, "044525225").
+		Set(KeyBankName, "ПАО Сбербанк").
+		Set(KeyPersonalAcc, "40702810938000012345").
+		Set(KeyName, "ООО Ромашка").
+		Set(KeyPaymPeriod, "09.2025").
+		Set("sum", "300"). // replaces the value, names are case-insensitive
+		Set("SCHOOLCODE", "7").
+		Build()
+	require.NoError(t, err)
+	require.Equal(t, "ST00012|Name=ООО Ромашка|PersonalAcc=40702810938000012345|BankName=ПАО Сбербанк|BIC=044525225|CorrespAcc=0"+
+		"|Sum=300|PaymPeriod=09.2025|SCHOOLCODE=7", payload)
+}
+
+func TestBuilder_Build_Separator(t *testing.T) {
+	payload, err := requiredBuilder(EncodingUTF8).
+		Set(KeyPurpose, "Питание | продленка").
+		Set(KeyPayerAddress, "ул. Баумана; д. 1").
+		Build()
+	require.NoError(t, err)
+	// "|" and ";" are met in the values, so the next free separator is used
+	require.True(t, strings.HasPrefix(payload, "ST00012#Name=ООО Ромашка#PersonalAcc="), payload)
+	require.True(t, strings.HasSuffix(payload, "#Purpose=Питание | продленка#PayerAddress=ул. Баумана; д. 1"), payload)
+
+	_, err = requiredBuilder(EncodingUTF8).Set(KeyPurpose, string(separators)).Build()
+	var pe *PayloadError
+	require.ErrorAs(t, err, &pe)
+	require.ErrorIs(t, pe.Problems[0], ErrNoSeparator)
+}
+
+func TestBuilder_Build_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		enc     Encoding
+		key     string
+		value   string
+		wantErr error
+	}{
+		{name: "too long purpose", enc: EncodingUTF8, key: KeyPurpose, value: strings.Repeat("я", 211), wantErr: ErrTooLong},
+		{name: "letters in sum", enc: EncodingUTF8, key: KeySum, value: "1200.50", wantErr: ErrNotDigits},
+		{name: "letters in CBC", enc: EncodingUTF8, key: "cbc", value: "1820102001001000011O", wantErr: ErrNotDigits},
+		{name: "too long TechCode", enc: EncodingUTF8, key: KeyTechCode, value: "123", wantErr: ErrTooLong},
+		{name: "line break", enc: EncodingUTF8, key: KeyPayerAddress, value: "Казань\nул. Баумана", wantErr: ErrForbiddenChars},
+		{name: "cyrillic key", enc: EncodingUTF8, key: "Категория", value: "4", wantErr: ErrInvalidKey},
+		{name: "empty key", enc: EncodingUTF8, key: "", value: "4", wantErr: ErrInvalidKey},
+		{name: "not in cp1251", enc: EncodingWindows1251, key: KeyChildFio, value: "Ли Сяо 李", wantErr: ErrNotEncodable},
+		{name: "not in koi8-r", enc: EncodingKOI8R, key: KeyPurpose, value: "Оплата №5", wantErr: ErrNotEncodable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := requiredBuilder(tt.enc).Set(tt.key, tt.value).Build()
+
+			var pe *PayloadError
+			require.ErrorAs(t, err, &pe)
+			require.Len(t, pe.Problems, 1)
+			require.Equal(t, tt.key, pe.Problems[0].Key)
+			require.Equal(t, tt.value, pe.Problems[0].Value)
+			require.ErrorIs(t, pe.Problems[0], tt.wantErr)
+		})
+	}
+}
+
+func TestBuilder_Build_Required(t *testing.T) {
+	_, err := NewBuilder(EncodingUTF8).Set(KeyName, "ООО Ромашка").Set(KeyBIC, "").Build()
+
+	var pe *PayloadError
+	require.ErrorAs(t, err, &pe)
+
+	var missing []string
+	for _, p := range pe.Problems {
+		require.ErrorIs(t, p, ErrRequired)
+		missing = append(missing, p.Key)
+	}
+	require.Equal(t, []string{KeyPersonalAcc, KeyBankName, KeyBIC, KeyCorrespAcc}, missing)
+}
+
+func TestEncodePayload(t *testing.T) {
+	for _, enc := range []Encoding{EncodingWindows1251, EncodingUTF8, EncodingKOI8R} {
+		t.Run(enc.Header(), func(t *testing.T) {
+			payload, err := requiredBuilder(enc).Build()
+			require.NoError(t, err)
+
+			data, err := EncodePayload(payload)
+			require.NoError(t, err)
+
+			var decoded []byte
+			switch enc {
+			case EncodingWindows1251:
+				decoded, err = charmap.Windows1251.NewDecoder().Bytes(data)
+			case EncodingKOI8R:
+				decoded, err = charmap.KOI8R.NewDecoder().Bytes(data)
+			default:
+				decoded = data
+			}
+			require.NoError(t, err)
+			require.Equal(t, payload, string(decoded))
+		})
+	}
+
+	t.Run("not a payload", func(t *testing.T) {
+		data, err := EncodePayload("hello")
+		require.NoError(t, err)
+		require.Equal(t, []byte("hello"), data)
+	})
+
+	t.Run("unknown encoding", func(t *testing.T) {
+		_, err := EncodePayload("ST00019|Name=x")
+		require.ErrorIs(t, err, ErrUnknownEncoding)
+	})
+}
+
+func TestCanonicalKey(t *testing.T) {
+	key, ok := CanonicalKey("CHILDFIO")
+	require.True(t, ok)
+	require.Equal(t, KeyChildFio, key)
+
+	_, ok = CanonicalKey("SCHOOLCODE")
+	require.False(t, ok)
+}
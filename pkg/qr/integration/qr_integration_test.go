@@ -4,7 +4,8 @@ package integration
 
 import (
 	"flag"
-	"image/jpeg"
+	"image/png"
+	"io"
 	"li-acc/pkg/model"
 	qr2 "li-acc/pkg/qr"
 	"os"
@@ -20,12 +21,12 @@ var keepQr = flag.Bool("keep-qr", false, "keep generated QRs for manual inspecti
 var orgFix = model.Organization{
 	Name:        "Муниципальное автономное общеобразовательное учреждение \"Тест-тест №7\" Тест-Тестового района г.Казани (л/с 7777777777777777)",
 	PersonalAcc: "20202020202020202020",
-	BankName:    "ОТДЕЛЕНИЕ РЕСПУБЛИКА ТАТАРСТАН БАНКА ТЕСТА//ТТТ по Республике Татарстан г Казань",
+	BankName:    "ОТДЕЛЕНИЕ-НБ РЕСПУБЛИКА ТАТАРСТАН БАНКА ТЕСТА",
 	BIC:         "999999999",
 	CorrespAcc:  "1111111111",
 	PayeeINN:    "2222222222",
 	KPP:         "888888888",
-	ExtraParams: "test_extra_params",
+	ExtraParams: "CATEGORY=4",
 }
 
 var payerFix = model.Payer{
@@ -34,7 +35,7 @@ var payerFix = model.Payer{
 	Purpose:  "test",
 	CBC:      "123454656543",
 	OKTMO:    "9879098",
-	Sum:      "10150.40",
+	Sum:      model.NewMoney(10150, 40),
 }
 
 // общий хелпер, чтобы не дублировать код
@@ -42,9 +43,10 @@ func generateAndCheckQR(t *testing.T, org model.Organization, payer model.Payer,
 	t.Helper()
 
 	qr := qr2.NewQrPattern(org)
-	qrStr := qr.GetPayersQrDataString(payer)
+	qrStr, err := qr.GetPayersQrDataString(payer)
+	require.NoError(t, err)
 
-	err := qr.GenerateQRCode(qrStr, outPath)
+	err = qr.GenerateQRCode(qrStr, outPath, qr2.DefaultRenderOptions())
 	require.NoError(t, err)
 
 	// check file exists and not empty
@@ -52,13 +54,22 @@ func generateAndCheckQR(t *testing.T, org model.Organization, payer model.Payer,
 	require.NoError(t, err)
 	require.Greater(t, fi.Size(), int64(10)) // size > 10 Bytes
 
-	// check it's a valid JPEG
+	// check it's a valid PNG
 	f, err := os.Open(outPath)
 	require.NoError(t, err)
 	defer f.Close()
 
-	_, err = jpeg.Decode(f)
+	_, err = png.Decode(f)
+	require.NoError(t, err)
+
+	// check the scanned QR gives the same requisites
+	_, err = f.Seek(0, io.SeekStart)
 	require.NoError(t, err)
+	payment, err := qr2.ParseImage(f)
+	require.NoError(t, err)
+	require.Equal(t, org, payment.Organization)
+	require.Equal(t, payer.PersAcc, payment.Payer.PersAcc)
+	require.Equal(t, payer.Sum, payment.Payer.Sum)
 
 	if !*keepQr {
 		t.Cleanup(func() {
@@ -69,10 +80,18 @@ func generateAndCheckQR(t *testing.T, org model.Organization, payer model.Payer,
 
 // существующий тест с фиктивными данными
 func TestGenerateQRCode(t *testing.T) {
-	qrPath := "./out/qr-integration-test.jpg"
+	qrPath := "./out/qr-integration-test.png"
 	generateAndCheckQR(t, orgFix, payerFix, qrPath)
 }
 
+// payload in Windows-1251, chosen by the organization setting
+// (KOI8-R does not fit the fixture: it has no "№" sign used in the organization name)
+func TestGenerateQRCode_Windows1251(t *testing.T) {
+	org := orgFix
+	org.ExtraParams += "|Encoding=1"
+	generateAndCheckQR(t, org, payerFix, "./out/qr-cp1251-test.png")
+}
+
 // новый тест с реальными реквизитами из .env.local
 func TestGenerateQRCode_RealData(t *testing.T) {
 	// грузим .env.local из текущей директории (qr/.env.local)
@@ -99,7 +118,7 @@ func TestGenerateQRCode_RealData(t *testing.T) {
 	// payer можно зашить тестовый (например, твой payerFix)
 	payer := payerFix
 
-	qrPath := "./out/qr-real-test.jpg"
+	qrPath := "./out/qr-real-test.png"
 	generateAndCheckQR(t, org, payer, qrPath)
 }
 
@@ -107,6 +126,6 @@ func TestGenerateQRCode_RealData(t *testing.T) {
 func TestGenerateQRCode_EmptyData(t *testing.T) {
 	qr := qr2.NewQrPattern(orgFix)
 	tmpDir := t.TempDir()
-	err := qr.GenerateQRCode("", tmpDir)
+	err := qr.GenerateQRCode("", tmpDir, qr2.DefaultRenderOptions())
 	require.Error(t, err)
 }
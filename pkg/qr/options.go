@@ -0,0 +1,69 @@
+package qr
+
+import (
+	"strings"
+)
+
+// OptionEncoding is the name of the pseudo-requisite of the "Дополнительные параметры ДШК" setting,
+// that chooses the payload encoding by its code, e.g. "Encoding=1" for ST00011 (Windows-1251).
+// It is not written to the payload.
+const OptionEncoding = "Encoding"
+
+// Options are the organization settings of the payment payload, written in the "Дополнительные параметры ДШК" setting
+// in the same form as in the payload: "Category=4|PaymPeriod=09.2025|Encoding=1".
+type Options struct {
+	Encoding   Encoding    // payload encoding, EncodingUTF8 by default
+	Requisites []Requisite // requisites added to the payload of every payer, in the order they are written
+}
+
+// ParseOptions parses the "Дополнительные параметры ДШК" setting. Empty setting gives the default options.
+// Each part separated by Separator must be "Key=Value"; the requisites are checked as Builder does.
+// Returns *PayloadError with problems of all invalid parts.
+func ParseOptions(extraParams string) (Options, error) {
+	opts := Options{Encoding: EncodingUTF8}
+	var problems []*RequisiteError
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(extraParams, string(Separator)) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(part, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok {
+			problems = append(problems, &RequisiteError{Key: part, Err: ErrInvalidKey})
+			continue
+		}
+		if seen[strings.ToLower(key)] {
+			problems = append(problems, &RequisiteError{Key: key, Value: value, Err: ErrDuplicateKey})
+			continue
+		}
+		seen[strings.ToLower(key)] = true
+
+		if strings.EqualFold(key, OptionEncoding) {
+			enc, err := ParseEncoding(value)
+			if err != nil {
+				problems = append(problems, &RequisiteError{Key: key, Value: value, Err: err})
+				continue
+			}
+			opts.Encoding = enc
+			continue
+		}
+
+		opts.Requisites = append(opts.Requisites, Requisite{Key: key, Value: value})
+	}
+
+	// values are checked after the whole setting is read, since the encoding may be written after them
+	for _, r := range opts.Requisites {
+		if err := checkRequisite(r, opts.Encoding); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return Options{}, &PayloadError{Problems: problems}
+	}
+	return opts, nil
+}
@@ -0,0 +1,64 @@
+package qr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		extraParams string
+		want        Options
+	}{
+		{
+			name:        "empty",
+			extraParams: "",
+			want:        Options{Encoding: EncodingUTF8},
+		},
+		{
+			name:        "single requisite",
+			extraParams: "CATEGORY=4",
+			want:        Options{Encoding: EncodingUTF8, Requisites: []Requisite{{Key: "CATEGORY", Value: "4"}}},
+		},
+		{
+			name:        "requisites and encoding with spaces",
+			extraParams: " Category = 4 | Encoding=3|PaymPeriod=09.2025 |",
+			want: Options{
+				Encoding:   EncodingKOI8R,
+				Requisites: []Requisite{{Key: "Category", Value: "4"}, {Key: "PaymPeriod", Value: "09.2025"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := ParseOptions(tt.extraParams)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, opts)
+		})
+	}
+}
+
+func TestParseOptions_Errors(t *testing.T) {
+	_, err := ParseOptions("test_extra_params|Encoding=5|Category=4|category=5|TechCode=ab|Encoding=1")
+
+	var pe *PayloadError
+	require.ErrorAs(t, err, &pe)
+
+	var got []error
+	for _, p := range pe.Problems {
+		got = append(got, p.Err)
+	}
+	require.Equal(t, []error{ErrInvalidKey, ErrUnknownEncoding, ErrDuplicateKey, ErrDuplicateKey, ErrNotDigits}, got)
+}
+
+func TestParseOptions_EncodingAppliesToValues(t *testing.T) {
+	// the encoding written after the requisite is still used to check it
+	_, err := ParseOptions("Purpose=Оплата №5|Encoding=3")
+
+	var pe *PayloadError
+	require.ErrorAs(t, err, &pe)
+	require.ErrorIs(t, pe.Problems[0], ErrNotEncodable)
+}
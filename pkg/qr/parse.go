@@ -0,0 +1,172 @@
+package qr
+
+import (
+	"li-acc/pkg/model"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Payment is the payment payload parsed back into the organization and payer requisites.
+type Payment struct {
+	Encoding Encoding // encoding declared in the format identifier
+
+	// Organization has the payee requisites. Requisites that have no field in model.Organization or model.Payer
+	// are kept in ExtraParams in the form of the "Дополнительные параметры ДШК" setting (see ParseOptions),
+	// so the payload of the same payer can be built again.
+	Organization model.Organization
+	Payer        model.Payer // PersAcc, ChildFio, Purpose, CBC, OKTMO and Sum requisites
+
+	Requisites []Requisite // all requisites in the order of the payload
+	Unknown    []string    // names of the requisites that the standard does not define, as they are written
+}
+
+// ParsePayloadBytes parses the payment payload as it is stored in the QR code: the bytes after the format identifier
+// are decoded from the encoding it declares (Windows-1251, UTF-8 or KOI8-R). See ParsePayload.
+func ParsePayloadBytes(data []byte) (*Payment, error) {
+	if len(data) < len(formatPrefix)+1 || !strings.HasPrefix(string(data[:len(formatPrefix)]), formatPrefix) {
+		return nil, ErrNotPayload
+	}
+
+	enc, err := ParseEncoding(string(data[len(formatPrefix)]))
+	if err != nil {
+		return nil, err
+	}
+
+	text := string(data)
+	if cm := enc.charmap(); cm != nil {
+		decoded, err := cm.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, ErrNotEncodable
+		}
+		text = string(decoded)
+	} else if !utf8.Valid(data) {
+		return nil, ErrNotEncodable
+	}
+
+	return ParsePayload(text)
+}
+
+// ParsePayload parses the payment payload string in the format of GOST R 56042-2014,
+// e.g. "ST00012|Name=...|PersonalAcc=...|...". The separator is the character following the format identifier.
+// Names of the requisites are case-insensitive, so payloads with "CHILDFIO" or "LASTNAME" are read as well.
+// Requisites not defined by the standard are listed in Payment.Unknown and do not make the payload invalid.
+//
+// ErrNotPayload or ErrUnknownEncoding are returned if the string is not a payment payload at all.
+// If the payload is read, but some requisites violate the standard, the parsed Payment is returned
+// together with *PayloadError, so all the data can still be shown.
+func ParsePayload(payload string) (*Payment, error) {
+	header := len(formatPrefix) + 1
+	if len(payload) <= header || !strings.HasPrefix(payload, formatPrefix) {
+		return nil, ErrNotPayload
+	}
+
+	enc, err := ParseEncoding(payload[len(formatPrefix):header])
+	if err != nil {
+		return nil, err
+	}
+
+	sep, size := utf8.DecodeRuneInString(payload[header:])
+	payment := &Payment{Encoding: enc}
+	var problems []*RequisiteError
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(payload[header+size:], string(sep)) {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			// empty parts, e.g. after the trailing separator, are allowed
+			if part != "" {
+				problems = append(problems, &RequisiteError{Key: part, Err: ErrInvalidKey})
+			}
+			continue
+		}
+
+		r := Requisite{Key: key, Value: value}
+		if seen[strings.ToLower(key)] {
+			problems = append(problems, &RequisiteError{Key: key, Value: value, Err: ErrDuplicateKey})
+			continue
+		}
+		seen[strings.ToLower(key)] = true
+
+		if err := checkRequisite(r, enc); err != nil {
+			problems = append(problems, err)
+		}
+		if _, known := CanonicalKey(key); !known {
+			payment.Unknown = append(payment.Unknown, key)
+		}
+		payment.Requisites = append(payment.Requisites, r)
+	}
+
+	for _, key := range requiredKeys {
+		if !seen[strings.ToLower(key)] || payment.Get(key) == "" {
+			problems = append(problems, &RequisiteError{Key: key, Err: ErrRequired})
+		}
+	}
+
+	payment.fill()
+
+	if len(problems) > 0 {
+		return payment, &PayloadError{Problems: problems}
+	}
+	return payment, nil
+}
+
+// Get returns the value of the requisite (names are compared case-insensitively), or empty string if there is none.
+func (p *Payment) Get(key string) string {
+	for _, r := range p.Requisites {
+		if strings.EqualFold(r.Key, key) {
+			return r.Value
+		}
+	}
+	return ""
+}
+
+// fill sets the organization and payer fields from the requisites of the payment.
+func (p *Payment) fill() {
+	var extra []string
+
+	for _, r := range p.Requisites {
+		key, _ := CanonicalKey(r.Key)
+		switch key {
+		case KeyName:
+			p.Organization.Name = r.Value
+		case KeyPersonalAcc:
+			p.Organization.PersonalAcc = r.Value
+		case KeyBankName:
+			p.Organization.BankName = r.Value
+		case KeyBIC:
+			p.Organization.BIC = r.Value
+		case KeyCorrespAcc:
+			p.Organization.CorrespAcc = r.Value
+		case KeyPayeeINN:
+			p.Organization.PayeeINN = r.Value
+		case KeyKPP:
+			p.Organization.KPP = r.Value
+		case KeyPersAcc:
+			p.Payer.PersAcc = r.Value
+		case KeyChildFio:
+			p.Payer.CHILDFIO = r.Value
+		case KeyPurpose:
+			p.Payer.Purpose = r.Value
+		case KeyCBC:
+			p.Payer.CBC = r.Value
+		case KeyOKTMO:
+			p.Payer.OKTMO = r.Value
+		case KeySum:
+			// invalid sum is already reported by checkRequisite, 18 digits always fit in int64
+			if kopeks, err := strconv.ParseInt(r.Value, 10, 64); err == nil {
+				p.Payer.Sum = model.Money(kopeks)
+			}
+		default:
+			// empty values carry nothing, e.g. LastName left for the payer to fill in
+			if r.Value != "" {
+				extra = append(extra, r.Key+"="+r.Value)
+			}
+		}
+	}
+
+	if p.Encoding != EncodingUTF8 {
+		extra = append(extra, OptionEncoding+"="+string(rune(p.Encoding)))
+	}
+	p.Organization.ExtraParams = strings.Join(extra, string(Separator))
+}
@@ -0,0 +1,100 @@
+package qr
+
+import (
+	"strings"
+	"testing"
+
+	"li-acc/pkg/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePayload_RoundTrip(t *testing.T) {
+	for _, enc := range []Encoding{EncodingWindows1251, EncodingUTF8, EncodingKOI8R} {
+		t.Run(enc.Header(), func(t *testing.T) {
+			org := orgFix
+			// "№" is not in KOI8-R
+			org.Name = strings.ReplaceAll(org.Name, "№", "N")
+			if enc != EncodingUTF8 {
+				org.ExtraParams += "|Encoding=" + string(rune(enc))
+			}
+
+			payload, err := NewQrPattern(org).GetPayersQrDataString(payerFix)
+			require.NoError(t, err)
+			data, err := EncodePayload(payload)
+			require.NoError(t, err)
+
+			payment, err := ParsePayloadBytes(data)
+			require.NoError(t, err)
+			require.Equal(t, enc, payment.Encoding)
+			require.Equal(t, org, payment.Organization)
+			require.Equal(t, model.Payer{
+				PersAcc:  payerFix.PersAcc,
+				CHILDFIO: strings.ToUpper(payerFix.CHILDFIO),
+				Purpose:  strings.ToUpper(payerFix.Purpose),
+				CBC:      payerFix.CBC,
+				OKTMO:    payerFix.OKTMO,
+				Sum:      payerFix.Sum,
+			}, payment.Payer)
+			require.Empty(t, payment.Unknown)
+
+			// the parsed data builds the same payload again
+			again, err := NewQrPattern(payment.Organization).GetPayersQrDataString(payment.Payer)
+			require.NoError(t, err)
+			require.Equal(t, payload, again)
+		})
+	}
+}
+
+func TestParsePayload(t *testing.T) {
+	payment, err := ParsePayload("ST00012#Name=ООО Ромашка#PERSONALACC=40702810938000012345#BankName=ПАО Сбербанк" +
+		"#BIC=044525225#CorrespAcc=30101810400000000225#Purpose=Питание | продленка#CHILDFIO=ИВАНОВ ИВАН" +
+		"#SCHOOLCODE=7#PaymPeriod=09.2025#LASTNAME=#")
+	require.NoError(t, err)
+
+	// the separator is taken from the header, names are case-insensitive
+	require.Equal(t, "40702810938000012345", payment.Organization.PersonalAcc)
+	require.Equal(t, "Питание | продленка", payment.Payer.Purpose)
+	require.Equal(t, "ИВАНОВ ИВАН", payment.Payer.CHILDFIO)
+	require.Equal(t, model.Money(0), payment.Payer.Sum)
+
+	require.Equal(t, []string{"SCHOOLCODE"}, payment.Unknown)
+	require.Equal(t, "SCHOOLCODE=7|PaymPeriod=09.2025", payment.Organization.ExtraParams)
+	require.Equal(t, "09.2025", payment.Get("paymperiod"))
+	require.Len(t, payment.Requisites, 10)
+}
+
+func TestParsePayload_NotPayload(t *testing.T) {
+	for _, s := range []string{"", "hello", "ST0001", "ST00012", "st00012|Name=x"} {
+		_, err := ParsePayload(s)
+		require.ErrorIs(t, err, ErrNotPayload, s)
+	}
+
+	_, err := ParsePayload("ST00019|Name=x")
+	require.ErrorIs(t, err, ErrUnknownEncoding)
+
+	_, err = ParsePayloadBytes([]byte("ST00012|Name=\xff"))
+	require.ErrorIs(t, err, ErrNotEncodable)
+}
+
+func TestParsePayload_Problems(t *testing.T) {
+	payment, err := ParsePayload("ST00012|Name=ООО Ромашка|PersonalAcc=4070281093800001234X|BIC=044525225" +
+		"|bic=044525226|Purpose|CorrespAcc=|Sum=1200.50")
+
+	var pe *PayloadError
+	require.ErrorAs(t, err, &pe)
+
+	var got []error
+	for _, p := range pe.Problems {
+		got = append(got, p.Err)
+	}
+	require.Equal(t, []error{ErrNotDigits, ErrDuplicateKey, ErrInvalidKey, ErrNotDigits, ErrRequired, ErrRequired}, got)
+	require.Equal(t, KeyBankName, pe.Problems[4].Key)
+	require.Equal(t, KeyCorrespAcc, pe.Problems[5].Key)
+
+	// the rest of the data is still read
+	require.NotNil(t, payment)
+	require.Equal(t, "ООО Ромашка", payment.Organization.Name)
+	require.Equal(t, "044525225", payment.Organization.BIC)
+	require.Equal(t, model.Money(0), payment.Payer.Sum)
+}
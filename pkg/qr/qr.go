@@ -1,111 +1,76 @@
 package qr
 
 import (
-	"fmt"
-	"image/jpeg"
 	"li-acc/internal/errs"
 	"li-acc/pkg/model"
 	"os"
 	"path/filepath"
-	"reflect"
 	"strings"
-
-	"github.com/skip2/go-qrcode"
 )
 
-// QrCode represents the data that QrCode of a payment contains, such as Receiver (Organization) and Sender (Payer) contains.
+// QrCode is the pattern of the payment QR code: all payment receiver (Organization) information is set up,
+// and each payer adds only his information to get the payment payload.
 type QrCode struct {
-	model.Organization        // The part of QR code containing information about Organization
-	model.Payer               // The part of QR code containing information about Payer
-	LASTNAME           string // Needed in QR pattern, empty string
+	org model.Organization
 }
 
 // NewQrPattern initializes new QrCode object with given model.Organization data
 // It is a pattern, since all payment receiver (organization) information is set up.
 // Each payer enters only his information, and payment QR will be fully ready.
 func NewQrPattern(orgData model.Organization) *QrCode {
-	return &QrCode{
-		Organization: orgData,
-		Payer:        model.Payer{},
-	}
+	return &QrCode{org: orgData}
 }
 
-// GetPayersQrDataString inserts model.Payer's data into QrCode pattern, editing some fields in the beginning.
-// Then it converts all fields of the QrData structure into the string "ST00012|Field1=Value1|Field2=Value2|...|Value_Of_ExtraParams"
-func (q QrCode) GetPayersQrDataString(payerData model.Payer) string {
-	// edit some fields
-	payerData.CHILDFIO = strings.ToUpper(payerData.CHILDFIO)
-	payerData.Purpose = strings.ToUpper(payerData.Purpose)
-	payerData.Sum = formatAmount(payerData.Sum)
-
-	// insert payer's data into qr data
-	q.Payer = payerData
-
-	var parts []string
-
-	// Payment pattern
-	parts = append(parts, "ST00012")
-
-	// Payment parameters below in the format "Param=Value"
-	parts = append(parts, structFieldsToString(q.Organization)...)
-	parts = append(parts, structFieldsToString(q.Payer)...)
-	parts = append(parts, fmt.Sprintf("%s=%v", "LASTNAME", ""))
+// GetPayersQrDataString builds the payment payload of the payer in the format of GOST R 56042-2014 (see Builder):
+// "ST00012|Name=...|PersonalAcc=...|BankName=...|BIC=...|CorrespAcc=...|PayeeINN=...|KPP=...|PersAcc=...|ChildFio=...|...".
+// Child's name and payment purpose are upper-cased, the amount is written in kopeks. LastName is left empty for the payer
+// to fill it in the bank application. Requisites and encoding from the "Дополнительные параметры ДШК" setting
+// (see ParseOptions) are applied last, so they can override the values above.
+// Returns *PayloadError if the settings or payer data do not fit the standard.
+func (q QrCode) GetPayersQrDataString(payerData model.Payer) (string, error) {
+	opts, err := ParseOptions(q.org.ExtraParams)
+	if err != nil {
+		return "", err
+	}
 
-	// Extra parameter, some meta information in format "Value", without parameter as above
-	parts = append(parts, q.ExtraParams)
+	b := NewBuilder(opts.Encoding).
+		Set(KeyName, q.org.Name).
+		Set(KeyPersonalAcc, q.org.PersonalAcc).
+		Set(KeyBankName, q.org.BankName).
+		Set(KeyBIC, q.org.BIC).
+		Set(KeyCorrespAcc, q.org.CorrespAcc).
+		Set(KeyPayeeINN, q.org.PayeeINN).
+		Set(KeyKPP, q.org.KPP).
+		Set(KeyPersAcc, payerData.PersAcc).
+		Set(KeyChildFio, strings.ToUpper(payerData.CHILDFIO)).
+		Set(KeyPurpose, strings.ToUpper(payerData.Purpose)).
+		Set(KeyCBC, payerData.CBC).
+		Set(KeyOKTMO, payerData.OKTMO).
+		Set(KeySum, payerData.Sum.QRString()).
+		Set(KeyLastName, "")
+
+	for _, r := range opts.Requisites {
+		b.Set(r.Key, r.Value)
+	}
 
-	return strings.Join(parts, "|")
+	return b.Build()
 }
 
-// GenerateQRCode generates new qr code from provided data qrData. It stores the image at the given path outPath.
+// GenerateQRCode renders the QR code with the payload qrData (see RenderQRCode) and stores the image at the given path outPath.
 // If the outPath's base does not exist, it creates all needed directories to store file.
-func (q QrCode) GenerateQRCode(qrData, outPath string) error {
-	// create directory of the output file, if it does not exist
-	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
-		return errs.WrapIOError("create QR code directory", outPath, err)
-	}
-
-	qr, err := qrcode.New(qrData, qrcode.Low)
+// Receipts do not need the file, it is kept for debugging and manual inspection.
+func (q QrCode) GenerateQRCode(qrData, outPath string, opts RenderOptions) error {
+	img, err := q.RenderQRCode(qrData, opts)
 	if err != nil {
-		return errs.Wrap(errs.System, "failed to generate QR code", err)
+		return err
 	}
 
-	qr.DisableBorder = true
-
-	img := qr.Image(80)
-	outFile, err := os.Create(outPath)
-	if err != nil {
-		return errs.WrapIOError("create QR code file", outPath, err)
+	// create directory of the output file, if it does not exist
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return errs.WrapIOError("create QR code directory", outPath, err)
 	}
-	defer outFile.Close()
-
-	err = jpeg.Encode(outFile, img, &jpeg.Options{Quality: 100})
-
-	if err != nil {
-		return errs.Wrap(errs.System, "failed to encode QR code image", err)
+	if err := os.WriteFile(outPath, img, 0o644); err != nil {
+		return errs.WrapIOError("write QR code file", outPath, err)
 	}
-
 	return nil
 }
-
-// structFieldsToString gets the struct of the type T (that is either model.Payer or model.Organization) and
-// converts each filed to string of the format "Field=Value", except those, that have tag `includeQr:"false"`. Tag is true by default.
-// Returns an array of string-formatted fields.
-func structFieldsToString[T model.Payer | model.Organization](s T) []string {
-	var parts []string
-
-	v := reflect.ValueOf(s)
-	t := reflect.TypeOf(s)
-	for i := 0; i < v.NumField(); i++ {
-		if t.Field(i).Tag.Get("includeQr") == "false" {
-			continue
-		}
-
-		fieldName := t.Field(i).Name
-		fieldValue := v.Field(i)
-
-		parts = append(parts, fmt.Sprintf("%s=%v", fieldName, fieldValue))
-	}
-
-	return parts
-}
@@ -14,12 +14,12 @@ import (
 var orgFix = model.Organization{
 	Name:        "Муниципальное автономное общеобразовательное учреждение \"Тест-тест №7\" Тест-Тестового района г.Казани (л/с 7777777777777777)",
 	PersonalAcc: "20202020202020202020",
-	BankName:    "ОТДЕЛЕНИЕ РЕСПУБЛИКА ТАТАРСТАН БАНКА ТЕСТА//ТТТ по Республике Татарстан г Казань",
+	BankName:    "ОТДЕЛЕНИЕ-НБ РЕСПУБЛИКА ТАТАРСТАН БАНКА ТЕСТА",
 	BIC:         "999999999",
 	CorrespAcc:  "1111111111",
 	PayeeINN:    "2222222222",
 	KPP:         "888888888",
-	ExtraParams: "test_extra_params",
+	ExtraParams: "CATEGORY=4",
 }
 
 var payerFix = model.Payer{
@@ -28,7 +28,7 @@ var payerFix = model.Payer{
 	Purpose:  "test",
 	CBC:      "123454656543",
 	OKTMO:    "9879098",
-	Sum:      "10150.40",
+	Sum:      model.NewMoney(10150, 40),
 }
 
 // ---- Tests ----
@@ -36,61 +36,80 @@ var payerFix = model.Payer{
 // 1. Unit test: GetPayersQrDataString
 func TestGetPayersQrDataString(t *testing.T) {
 	qr := NewQrPattern(orgFix)
-	qrStr := qr.GetPayersQrDataString(payerFix)
-
-	// must contain payment header
-	require.Contains(t, qrStr, "ST00012")
-
-	// organization data
-	require.Contains(t, qrStr, "Name="+orgFix.Name)
-	require.Contains(t, qrStr, "PersonalAcc="+orgFix.PersonalAcc)
-	require.Contains(t, qrStr, "|"+orgFix.ExtraParams)
+	qrStr, err := qr.GetPayersQrDataString(payerFix)
+	require.NoError(t, err)
+
+	require.Equal(t, "ST00012"+
+		"|Name="+orgFix.Name+
+		"|PersonalAcc=20202020202020202020"+
+		"|BankName="+orgFix.BankName+
+		"|BIC=999999999"+
+		"|CorrespAcc=1111111111"+
+		"|PayeeINN=2222222222"+
+		"|KPP=888888888"+
+		"|PersAcc=123456"+
+		"|ChildFio="+strings.ToUpper(payerFix.CHILDFIO)+ // uppercase transformation
+		"|Purpose=TEST"+
+		"|CBC=123454656543"+
+		"|OKTMO=9879098"+
+		"|Sum=1015040"+ // should be correctly formatted in kopeks
+		"|LastName="+ // always present for the payer to fill it in
+		"|CATEGORY=4", // extra params go last as they are written
+		qrStr)
+}
 
-	// payer data (uppercase transformation)
-	require.Contains(t, qrStr, "CHILDFIO="+strings.ToUpper(payerFix.CHILDFIO))
-	require.Contains(t, qrStr, "Purpose="+strings.ToUpper(payerFix.Purpose))
-	require.Contains(t, qrStr, "Sum="+strings.Replace(payerFix.Sum, ".", "", 1)) // should be correctly formatted with removed point
+func TestGetPayersQrDataString_Options(t *testing.T) {
+	org := orgFix
+	org.ExtraParams = "Encoding=1|PaymPeriod=09.2025|purpose=ПИТАНИЕ ЗА СЕНТЯБРЬ"
 
-	// required LASTNAME field always present
-	require.Contains(t, qrStr, "LASTNAME=")
+	qrStr, err := NewQrPattern(org).GetPayersQrDataString(payerFix)
+	require.NoError(t, err)
 
-	// extra param should be last
-	require.Contains(t, qrStr, orgFix.ExtraParams)
+	require.True(t, strings.HasPrefix(qrStr, "ST00011|Name="))
+	require.NotContains(t, qrStr, "Encoding")
+	require.Contains(t, qrStr, "|PaymPeriod=09.2025")
+	// the setting overrides the payer's value, keeping its place
+	require.Contains(t, qrStr, "|Purpose=ПИТАНИЕ ЗА СЕНТЯБРЬ|CBC=")
 }
 
-// 2. Unit test: structFieldsToString
-func TestStructFieldsToString(t *testing.T) {
-	org := model.Organization{
-		Name:        "Test Org",
-		PersonalAcc: "111",
-		ExtraParams: "zzz",
-	}
-	payer := model.Payer{
-		PersAcc:  "222",
-		CHILDFIO: "Ivanov",
-	}
-
-	partsOrg := structFieldsToString(org)
-	partsPayer := structFieldsToString(payer)
-
-	// all fields represented
-	require.Contains(t, partsOrg, "Name="+org.Name)
-	require.Contains(t, partsOrg, "PersonalAcc="+org.PersonalAcc)
-	// ExtraParams must be missed, since in model.Organization tag 'includeQr' is false
-	require.NotContains(t, partsOrg, "ExtraParams")
-	require.NotContains(t, partsOrg, org.ExtraParams)
-
-	require.Contains(t, partsPayer, "PersAcc="+payer.PersAcc)
-	require.Contains(t, partsPayer, "CHILDFIO="+payer.CHILDFIO)
+func TestGetPayersQrDataString_Invalid(t *testing.T) {
+	t.Run("invalid settings", func(t *testing.T) {
+		org := orgFix
+		org.BankName = "ОТДЕЛЕНИЕ-НБ РЕСПУБЛИКА ТАТАРСТАН БАНКА РОССИИ//УФК по Республике Татарстан г. Казань"
+		org.BIC = ""
+
+		_, err := NewQrPattern(org).GetPayersQrDataString(payerFix)
+
+		var pe *PayloadError
+		require.ErrorAs(t, err, &pe)
+		require.Len(t, pe.Problems, 2)
+		require.Equal(t, KeyBIC, pe.Problems[0].Key)
+		require.ErrorIs(t, pe.Problems[0], ErrRequired)
+		require.Equal(t, KeyBankName, pe.Problems[1].Key)
+		require.ErrorIs(t, pe.Problems[1], ErrTooLong)
+		require.Equal(t, 45, pe.Problems[1].MaxLength)
+	})
+
+	t.Run("invalid extra params", func(t *testing.T) {
+		org := orgFix
+		org.ExtraParams = "test_extra_params"
+
+		_, err := NewQrPattern(org).GetPayersQrDataString(payerFix)
+
+		var pe *PayloadError
+		require.ErrorAs(t, err, &pe)
+		require.ErrorIs(t, pe.Problems[0], ErrInvalidKey)
+	})
 }
 
 // Edge case: empty payer
 func TestGetPayersQrDataString_EmptyPayer(t *testing.T) {
 	qr := NewQrPattern(orgFix)
-	qrStr := qr.GetPayersQrDataString(model.Payer{})
+	qrStr, err := qr.GetPayersQrDataString(model.Payer{})
+	require.NoError(t, err)
 
 	// should still contain org data
 	require.Contains(t, qrStr, "Name="+orgFix.Name)
-	// LASTNAME must be present even if payer empty
-	require.Contains(t, qrStr, "LASTNAME=")
+	// LastName must be present even if payer empty
+	require.Contains(t, qrStr, "LastName=")
 }
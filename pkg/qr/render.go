@@ -0,0 +1,174 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"li-acc/internal/errs"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Format is the image format of the rendered QR code.
+type Format string
+
+const (
+	FormatPNG Format = "png" // lossless raster image, the one to embed into PDF receipts
+	FormatSVG Format = "svg" // vector image, module size sets only its default width and height
+)
+
+// ErrorCorrection is the error correction level of the QR code: the share of damaged modules it survives.
+// Higher level makes the code denser for the same payload.
+type ErrorCorrection byte
+
+const (
+	ErrorCorrectionL ErrorCorrection = 'L' // ~7%
+	ErrorCorrectionM ErrorCorrection = 'M' // ~15%
+	ErrorCorrectionQ ErrorCorrection = 'Q' // ~25%
+	ErrorCorrectionH ErrorCorrection = 'H' // ~30%
+)
+
+// ErrInvalidRenderOptions is returned when the rendering options are out of range
+var ErrInvalidRenderOptions = errors.New("invalid QR code render options")
+
+// ParseErrorCorrection parses the error correction level by its letter: "L", "M", "Q" or "H" (case-insensitive).
+func ParseErrorCorrection(level string) (ErrorCorrection, error) {
+	switch l := strings.ToUpper(strings.TrimSpace(level)); l {
+	case "L", "M", "Q", "H":
+		return ErrorCorrection(l[0]), nil
+	}
+	return 0, fmt.Errorf("%w: unknown error correction level %q, expected L, M, Q or H", ErrInvalidRenderOptions, level)
+}
+
+// recoveryLevel returns the level of the QR code library
+func (e ErrorCorrection) recoveryLevel() qrcode.RecoveryLevel {
+	switch e {
+	case ErrorCorrectionM:
+		return qrcode.Medium
+	case ErrorCorrectionQ:
+		return qrcode.High
+	case ErrorCorrectionH:
+		return qrcode.Highest
+	default:
+		return qrcode.Low
+	}
+}
+
+// RenderOptions are the settings of the QR code image.
+type RenderOptions struct {
+	Format          Format
+	ErrorCorrection ErrorCorrection
+	ModuleSize      int // size of one module (black or white square) in pixels, at least 1
+	QuietZone       int // width of the white margin around the code in modules; the standard recommends 4
+}
+
+// DefaultRenderOptions returns the options the receipts are rendered with, unless configured otherwise:
+// PNG with low error correction, 8 pixels per module and no margin, since the receipt has white space around the code.
+func DefaultRenderOptions() RenderOptions {
+	return RenderOptions{
+		Format:          FormatPNG,
+		ErrorCorrection: ErrorCorrectionL,
+		ModuleSize:      8,
+		QuietZone:       0,
+	}
+}
+
+// Validate returns error wrapping ErrInvalidRenderOptions if some option is out of range.
+func (o RenderOptions) Validate() error {
+	if o.Format != FormatPNG && o.Format != FormatSVG {
+		return fmt.Errorf("%w: unknown format %q, expected %q or %q", ErrInvalidRenderOptions, o.Format, FormatPNG, FormatSVG)
+	}
+	switch o.ErrorCorrection {
+	case ErrorCorrectionL, ErrorCorrectionM, ErrorCorrectionQ, ErrorCorrectionH:
+	default:
+		return fmt.Errorf("%w: unknown error correction level %q", ErrInvalidRenderOptions, rune(o.ErrorCorrection))
+	}
+	if o.ModuleSize < 1 {
+		return fmt.Errorf("%w: module size must be at least 1, got %d", ErrInvalidRenderOptions, o.ModuleSize)
+	}
+	if o.QuietZone < 0 {
+		return fmt.Errorf("%w: quiet zone must not be negative, got %d", ErrInvalidRenderOptions, o.QuietZone)
+	}
+	return nil
+}
+
+// RenderQRCode returns the image of the QR code with the payload qrData in memory.
+// The payload is encoded as its format identifier declares, e.g. ST00011 in Windows-1251 (see EncodePayload).
+func (q QrCode) RenderQRCode(qrData string, opts RenderOptions) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errs.Wrap(errs.System, "failed to render QR code", err)
+	}
+
+	data, err := EncodePayload(qrData)
+	if err != nil {
+		return nil, errs.Wrap(errs.System, "failed to encode QR code data", err)
+	}
+
+	code, err := qrcode.New(string(data), opts.ErrorCorrection.recoveryLevel())
+	if err != nil {
+		return nil, errs.Wrap(errs.System, "failed to generate QR code", err)
+	}
+	code.DisableBorder = true // the quiet zone of the options is drawn instead
+	modules := code.Bitmap()
+
+	var buf bytes.Buffer
+	switch opts.Format {
+	case FormatSVG:
+		writeSVG(&buf, modules, opts)
+	default:
+		if err := png.Encode(&buf, rasterize(modules, opts)); err != nil {
+			return nil, errs.Wrap(errs.System, "failed to encode QR code image", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// rasterize draws the modules into the grayscale image. Paletted PNG would be smaller,
+// but the PDF library does not write the palette of indexed images.
+func rasterize(modules [][]bool, opts RenderOptions) image.Image {
+	side := (len(modules) + 2*opts.QuietZone) * opts.ModuleSize
+	img := image.NewGray(image.Rect(0, 0, side, side))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			x0, y0 := (x+opts.QuietZone)*opts.ModuleSize, (y+opts.QuietZone)*opts.ModuleSize
+			for py := y0; py < y0+opts.ModuleSize; py++ {
+				for px := x0; px < x0+opts.ModuleSize; px++ {
+					img.SetGray(px, py, color.Gray{Y: 0})
+				}
+			}
+		}
+	}
+	return img
+}
+
+// writeSVG writes the modules as one path of horizontal runs of dark modules, in the coordinates of modules
+func writeSVG(buf *bytes.Buffer, modules [][]bool, opts RenderOptions) {
+	side := len(modules) + 2*opts.QuietZone
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		side*opts.ModuleSize, side*opts.ModuleSize, side, side)
+	buf.WriteString(`<rect width="100%" height="100%" fill="#fff"/><path fill="#000" d="`)
+
+	for y, row := range modules {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(buf, "M%d %dh%dv1h-%dz", start+opts.QuietZone, y+opts.QuietZone, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+}
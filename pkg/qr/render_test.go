@@ -0,0 +1,113 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderQRCode_PNG(t *testing.T) {
+	qr := NewQrPattern(orgFix)
+	payload, err := qr.GetPayersQrDataString(payerFix)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		opts RenderOptions
+	}{
+		{name: "default", opts: DefaultRenderOptions()},
+		{name: "small modules", opts: RenderOptions{Format: FormatPNG, ErrorCorrection: ErrorCorrectionL, ModuleSize: 2}},
+		{name: "quiet zone", opts: RenderOptions{Format: FormatPNG, ErrorCorrection: ErrorCorrectionQ, ModuleSize: 4, QuietZone: 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := qr.RenderQRCode(payload, tt.opts)
+			require.NoError(t, err)
+
+			img, err := png.Decode(bytes.NewReader(data))
+			require.NoError(t, err)
+			// the side is a whole number of modules, including the quiet zone
+			side := img.Bounds().Dx()
+			require.Equal(t, side, img.Bounds().Dy())
+			require.Zero(t, side%tt.opts.ModuleSize)
+			require.Greater(t, side/tt.opts.ModuleSize, 2*tt.opts.QuietZone+21)
+
+			// the quiet zone is white, and the finder pattern starts right after it
+			edge := tt.opts.QuietZone * tt.opts.ModuleSize
+			if edge > 0 {
+				r, _, _, _ := img.At(edge-1, edge-1).RGBA()
+				require.Equal(t, uint32(0xffff), r)
+			}
+			r, _, _, _ := img.At(edge, edge).RGBA()
+			require.Zero(t, r)
+
+			require.NoError(t, qr.VerifyQRCode(data, payload))
+		})
+	}
+}
+
+func TestRenderQRCode_SVG(t *testing.T) {
+	qr := NewQrPattern(orgFix)
+	payload, err := qr.GetPayersQrDataString(payerFix)
+	require.NoError(t, err)
+
+	opts := RenderOptions{Format: FormatSVG, ErrorCorrection: ErrorCorrectionM, ModuleSize: 3, QuietZone: 4}
+	data, err := qr.RenderQRCode(payload, opts)
+	require.NoError(t, err)
+	svg := string(data)
+
+	// the PNG of the same options has the same number of modules
+	pngData, err := qr.RenderQRCode(payload, RenderOptions{Format: FormatPNG, ErrorCorrection: ErrorCorrectionM, ModuleSize: 1, QuietZone: 4})
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(pngData))
+	require.NoError(t, err)
+	side := img.Bounds().Dx()
+
+	require.True(t, strings.HasPrefix(svg, fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d"`,
+		side*3, side*3, side, side)), svg)
+	require.True(t, strings.HasSuffix(svg, `"/></svg>`))
+	// top row of the top-left finder pattern: 7 dark modules after the quiet zone
+	require.Contains(t, svg, `d="M4 4h7v1h-7z`)
+}
+
+func TestRenderQRCode_Errors(t *testing.T) {
+	qr := NewQrPattern(orgFix)
+
+	_, err := qr.RenderQRCode("", DefaultRenderOptions())
+	require.Error(t, err)
+
+	_, err = qr.RenderQRCode("ST00012|Name=x", RenderOptions{Format: "jpg", ErrorCorrection: ErrorCorrectionL, ModuleSize: 1})
+	require.ErrorIs(t, err, ErrInvalidRenderOptions)
+}
+
+func TestRenderOptions_Validate(t *testing.T) {
+	require.NoError(t, DefaultRenderOptions().Validate())
+
+	invalid := []RenderOptions{
+		{Format: "gif", ErrorCorrection: ErrorCorrectionL, ModuleSize: 1},
+		{Format: FormatPNG, ErrorCorrection: 'X', ModuleSize: 1},
+		{Format: FormatPNG, ErrorCorrection: ErrorCorrectionL, ModuleSize: 0},
+		{Format: FormatSVG, ErrorCorrection: ErrorCorrectionL, ModuleSize: 1, QuietZone: -1},
+	}
+	for _, opts := range invalid {
+		require.ErrorIs(t, opts.Validate(), ErrInvalidRenderOptions, "%+v", opts)
+	}
+}
+
+func TestParseErrorCorrection(t *testing.T) {
+	for level, want := range map[string]ErrorCorrection{"L": ErrorCorrectionL, "m": ErrorCorrectionM, " q ": ErrorCorrectionQ, "H": ErrorCorrectionH} {
+		got, err := ParseErrorCorrection(level)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+
+	for _, level := range []string{"", "X", "LM", "low"} {
+		_, err := ParseErrorCorrection(level)
+		require.ErrorIs(t, err, ErrInvalidRenderOptions, level)
+	}
+}
@@ -0,0 +1,68 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // photos of printed receipts
+	_ "image/png"
+	"io"
+	"li-acc/internal/errs"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// ScanImage finds the QR code in the image and returns the bytes stored in it.
+// The bytes are not decoded: the payment payload declares its encoding itself, see ParsePayloadBytes.
+// Returns errs.User error if there is no readable QR code in the image.
+func ScanImage(img image.Image) ([]byte, error) {
+	bmp, err := gozxing.NewBinaryBitmapFromImage(withQuietZone(img))
+	if err != nil {
+		return nil, errs.Wrap(errs.System, "failed to prepare image for QR scanning", err)
+	}
+
+	// ISO-8859-1 maps every byte to the rune of the same code, so the bytes are restored from the text as they are
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_CHARACTER_SET: charmap.ISO8859_1,
+		gozxing.DecodeHintType_TRY_HARDER:    true,
+	}
+	res, err := qrcode.NewQRCodeReader().Decode(bmp, hints)
+	if err != nil {
+		return nil, errs.Wrap(errs.User, "QR code not found in the image", err)
+	}
+
+	text := []rune(res.GetText())
+	data := make([]byte, len(text))
+	for i, r := range text {
+		data[i] = byte(r)
+	}
+	return data, nil
+}
+
+// ParseImage reads the image (JPEG or PNG) with the payment QR code and parses its payload, see ParsePayload.
+func ParseImage(r io.Reader) (*Payment, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, errs.Wrap(errs.User, "failed to decode QR code image", err)
+	}
+
+	data, err := ScanImage(img)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePayloadBytes(data)
+}
+
+// withQuietZone returns the image surrounded by a white margin. Receipt QR codes have no quiet zone
+// (see DefaultRenderOptions), and the scanner can not find the finder patterns right at the image edge.
+func withQuietZone(img image.Image) image.Image {
+	b := img.Bounds()
+	margin := max(b.Dx(), b.Dy())/10 + 8
+
+	out := image.NewGray(image.Rect(0, 0, b.Dx()+2*margin, b.Dy()+2*margin))
+	draw.Draw(out, out.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(out, image.Rect(margin, margin, margin+b.Dx(), margin+b.Dy()), img, b.Min, draw.Src)
+	return out
+}
@@ -0,0 +1,40 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"li-acc/internal/errs"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImage(t *testing.T) {
+	org := orgFix
+	org.ExtraParams = "CATEGORY=4|Encoding=1"
+
+	qr := NewQrPattern(org)
+	payload, err := qr.GetPayersQrDataString(payerFix)
+	require.NoError(t, err)
+	img, err := qr.RenderQRCode(payload, DefaultRenderOptions())
+	require.NoError(t, err)
+
+	payment, err := ParseImage(bytes.NewReader(img))
+	require.NoError(t, err)
+	require.Equal(t, EncodingWindows1251, payment.Encoding)
+	require.Equal(t, org, payment.Organization)
+	require.Equal(t, payerFix.Sum, payment.Payer.Sum)
+}
+
+func TestParseImage_NoCode(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 100, 100))))
+
+	_, err := ParseImage(&buf)
+	require.True(t, errs.IsUserError(err))
+
+	_, err = ParseImage(bytes.NewReader([]byte("not an image")))
+	require.True(t, errs.IsUserError(err))
+}
@@ -0,0 +1,55 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"li-acc/internal/errs"
+)
+
+// VerificationError error raised when the generated QR code image does not give back its payload when scanned,
+// so bank applications may fail to read it too.
+type VerificationError struct {
+	Payload string // the payload that was encoded
+	Scanned []byte // bytes read from the image, as they are stored; nil if the code was not read
+	Err     error  // why the code was not read; nil if it was read, but differs from the payload
+}
+
+func (e *VerificationError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("generated QR code can not be scanned: %v", e.Err)
+	}
+	return fmt.Sprintf("scanned QR code does not match the payload: got %q, want %q", e.Scanned, e.Payload)
+}
+
+func (e *VerificationError) Kind() errs.Kind {
+	return errs.System
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyQRCode scans the QR code image (PNG as RenderQRCode returns it, or JPEG) and checks
+// that it holds exactly the qrData payload in the encoding the payload declares.
+// Returns *VerificationError if the code can not be read or holds other data.
+func (q QrCode) VerifyQRCode(imgData []byte, qrData string) error {
+	want, err := EncodePayload(qrData)
+	if err != nil {
+		return errs.Wrap(errs.System, "failed to encode QR code data", err)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(imgData))
+	if err != nil {
+		return &VerificationError{Payload: qrData, Err: err}
+	}
+
+	got, err := ScanImage(img)
+	if err != nil {
+		return &VerificationError{Payload: qrData, Err: err}
+	}
+	if !bytes.Equal(got, want) {
+		return &VerificationError{Payload: qrData, Scanned: got}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/stretchr/testify/require"
+)
+
+// pngQRCode returns PNG image of the QR code with the data
+func pngQRCode(t *testing.T, data string) []byte {
+	t.Helper()
+	png, err := qrcode.Encode(data, qrcode.Low, -8) // 8 pixels per module
+	require.NoError(t, err)
+	return png
+}
+
+func TestVerifyQRCode(t *testing.T) {
+	qr := NewQrPattern(orgFix)
+	payload, err := qr.GetPayersQrDataString(payerFix)
+	require.NoError(t, err)
+
+	t.Run("rendered", func(t *testing.T) {
+		img, err := qr.RenderQRCode(payload, DefaultRenderOptions())
+		require.NoError(t, err)
+
+		require.NoError(t, qr.VerifyQRCode(img, payload))
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		other := payerFix
+		other.Sum++
+		otherPayload, err := qr.GetPayersQrDataString(other)
+		require.NoError(t, err)
+
+		err = qr.VerifyQRCode(pngQRCode(t, otherPayload), payload)
+		var ve *VerificationError
+		require.ErrorAs(t, err, &ve)
+		require.NoError(t, ve.Err)
+		require.Equal(t, payload, ve.Payload)
+		require.Equal(t, []byte(otherPayload), ve.Scanned)
+	})
+
+	t.Run("not scanned", func(t *testing.T) {
+		var blank bytes.Buffer
+		require.NoError(t, png.Encode(&blank, image.NewGray(image.Rect(0, 0, 100, 100))))
+
+		for _, img := range [][]byte{blank.Bytes(), []byte("not an image")} {
+			err := qr.VerifyQRCode(img, payload)
+			var ve *VerificationError
+			require.ErrorAs(t, err, &ve)
+			require.Error(t, ve.Err)
+			require.Nil(t, ve.Scanned)
+		}
+	})
+}
+
+func TestVerifyQRCode_Encoding(t *testing.T) {
+	org := orgFix
+	org.ExtraParams = "Encoding=1"
+	qr := NewQrPattern(org)
+	payload, err := qr.GetPayersQrDataString(payerFix)
+	require.NoError(t, err)
+	data, err := EncodePayload(payload)
+	require.NoError(t, err)
+
+	require.NoError(t, qr.VerifyQRCode(pngQRCode(t, string(data)), payload))
+
+	// the same text in UTF-8 is not what the header declares
+	err = qr.VerifyQRCode(pngQRCode(t, payload), payload)
+	var ve *VerificationError
+	require.ErrorAs(t, err, &ve)
+}
@@ -0,0 +1,142 @@
+// Package requisites validates bank and tax requisites of Russian organizations:
+// BIC, settlement and correspondent accounts (with the Central Bank control key), INN and KPP.
+package requisites
+
+import (
+	"errors"
+	"regexp"
+)
+
+// Lengths of the requisites
+const (
+	BICLength     = 9
+	AccountLength = 20
+
+	INNLegalLength      = 10 // INN of a legal entity
+	INNIndividualLength = 12 // INN of an individual or individual entrepreneur
+)
+
+var (
+	// ErrFormat is returned when the value has wrong length or contains forbidden characters
+	ErrFormat = errors.New("invalid format")
+
+	// ErrChecksum is returned when the value is well-formed, but its control digits do not match
+	ErrChecksum = errors.New("checksum mismatch")
+)
+
+// kppPattern is the format of KPP: 4 digits of the tax office code, 2 characters of the registration reason
+// (digits or capital latin letters) and 3 digits of the serial number.
+var kppPattern = regexp.MustCompile(`^\d{4}[\dA-Z]{2}\d{3}$`)
+
+// accountWeights are the weights of the Central Bank control key algorithm, applied cyclically
+// to the 23-digit string "3 digits from BIC + 20 digits of the account".
+var accountWeights = [3]int{7, 1, 3}
+
+// ValidateBIC checks that BIC consists of exactly 9 digits.
+func ValidateBIC(bic string) error {
+	if !isDigits(bic, BICLength) {
+		return ErrFormat
+	}
+	return nil
+}
+
+// ValidateSettlementAccount checks the settlement account format and its control key against the bank's BIC.
+// For accounts in a credit institution the key is calculated with the last 3 digits of BIC.
+// For accounts opened in the Bank of Russia divisions (BIC ends with 000, 001 or 002) it is calculated
+// in the same way as for the correspondent account.
+func ValidateSettlementAccount(account, bic string) error {
+	if !isDigits(account, AccountLength) {
+		return ErrFormat
+	}
+	if err := ValidateBIC(bic); err != nil {
+		return err
+	}
+
+	prefix := bic[6:]
+	if prefix == "000" || prefix == "001" || prefix == "002" {
+		prefix = "0" + bic[4:6]
+	}
+
+	if !controlKeyValid(prefix + account) {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// ValidateCorrespondentAccount checks the correspondent account format and its control key against the bank's BIC.
+// The key is calculated with "0" followed by the 5th and 6th digits of BIC (conditional number of the cash settlement center).
+func ValidateCorrespondentAccount(account, bic string) error {
+	if !isDigits(account, AccountLength) {
+		return ErrFormat
+	}
+	if err := ValidateBIC(bic); err != nil {
+		return err
+	}
+
+	if !controlKeyValid("0" + bic[4:6] + account) {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// ValidateINN checks the INN of a legal entity (10 digits) or an individual (12 digits) and its control digits.
+func ValidateINN(inn string) error {
+	switch {
+	case isDigits(inn, INNLegalLength):
+		if innControlDigit(inn, []int{2, 4, 10, 3, 5, 9, 4, 6, 8}) != digit(inn, 9) {
+			return ErrChecksum
+		}
+	case isDigits(inn, INNIndividualLength):
+		if innControlDigit(inn, []int{7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) != digit(inn, 10) ||
+			innControlDigit(inn, []int{3, 7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) != digit(inn, 11) {
+			return ErrChecksum
+		}
+	default:
+		return ErrFormat
+	}
+	return nil
+}
+
+// ValidateKPP checks that KPP matches the format NNNNPPNNN, where P is a digit or a capital latin letter.
+func ValidateKPP(kpp string) error {
+	if !kppPattern.MatchString(kpp) {
+		return ErrFormat
+	}
+	return nil
+}
+
+// controlKeyValid applies the Central Bank control key algorithm to the 23-digit string:
+// the sum of the lowest digits of products of each digit and its weight must be divisible by 10.
+func controlKeyValid(s string) bool {
+	sum := 0
+	for i := 0; i < len(s); i++ {
+		sum += digit(s, i) * accountWeights[i%len(accountWeights)] % 10
+	}
+	return sum%10 == 0
+}
+
+// innControlDigit calculates the INN control digit using the given weights for the leading digits.
+func innControlDigit(inn string, weights []int) int {
+	sum := 0
+	for i, w := range weights {
+		sum += digit(inn, i) * w
+	}
+	return sum % 11 % 10
+}
+
+func digit(s string, i int) int {
+	return int(s[i] - '0')
+}
+
+// isDigits reports whether s consists of exactly `length` digits.
+func isDigits(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
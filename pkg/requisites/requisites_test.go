@@ -0,0 +1,119 @@
+package requisites
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateBIC(t *testing.T) {
+	require.NoError(t, ValidateBIC("044525225"))
+	require.ErrorIs(t, ValidateBIC("04452522"), ErrFormat)
+	require.ErrorIs(t, ValidateBIC("0445252250"), ErrFormat)
+	require.ErrorIs(t, ValidateBIC("04452522A"), ErrFormat)
+}
+
+func TestValidateAccounts(t *testing.T) {
+	tests := []struct {
+		name       string
+		bic        string
+		settlement string
+		corresp    string
+		wantSettle error
+		wantCorr   error
+	}{
+		{
+			name:       "credit institution",
+			bic:        "044525225",
+			settlement: "40702810938000012345",
+			corresp:    "30101810400000000225",
+		},
+		{
+			name:       "treasury accounts",
+			bic:        "019205400",
+			settlement: "03234643927010001100",
+			corresp:    "40102810445370000079",
+		},
+		{
+			name:       "typo in account numbers",
+			bic:        "044525225",
+			settlement: "40702810938000012354",
+			corresp:    "30101810400000000252",
+			wantSettle: ErrChecksum,
+			wantCorr:   ErrChecksum,
+		},
+		{
+			name:       "accounts of another bank",
+			bic:        "044030653",
+			settlement: "40702810938000012345",
+			corresp:    "30101810400000000225",
+			wantSettle: ErrChecksum,
+			wantCorr:   ErrChecksum,
+		},
+		{
+			name:       "wrong length",
+			bic:        "044525225",
+			settlement: "4070281093800001234",
+			corresp:    "301018104000000002255",
+			wantSettle: ErrFormat,
+			wantCorr:   ErrFormat,
+		},
+		{
+			name:       "invalid bic",
+			bic:        "04452522",
+			settlement: "40702810938000012345",
+			corresp:    "30101810400000000225",
+			wantSettle: ErrFormat,
+			wantCorr:   ErrFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSettlementAccount(tt.settlement, tt.bic)
+			if tt.wantSettle == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tt.wantSettle)
+			}
+
+			err = ValidateCorrespondentAccount(tt.corresp, tt.bic)
+			if tt.wantCorr == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tt.wantCorr)
+			}
+		})
+	}
+}
+
+func TestValidateINN(t *testing.T) {
+	tests := map[string]error{
+		"7707083893":   nil,
+		"1659012345":   nil,
+		"500100732259": nil,
+		"7707083894":   ErrChecksum,
+		"500100732258": ErrChecksum,
+		"770708389":    ErrFormat,
+		"77070838930":  ErrFormat,
+		"77070838AB":   ErrFormat,
+		"":             ErrFormat,
+	}
+
+	for inn, want := range tests {
+		err := ValidateINN(inn)
+		if want == nil {
+			require.NoError(t, err, inn)
+		} else {
+			require.ErrorIs(t, err, want, inn)
+		}
+	}
+}
+
+func TestValidateKPP(t *testing.T) {
+	require.NoError(t, ValidateKPP("770701001"))
+	require.NoError(t, ValidateKPP("7701AB001"))
+	require.ErrorIs(t, ValidateKPP("77070100"), ErrFormat)
+	require.ErrorIs(t, ValidateKPP("7701ab001"), ErrFormat)
+	require.ErrorIs(t, ValidateKPP("A70701001"), ErrFormat)
+}
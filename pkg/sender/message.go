@@ -27,6 +27,18 @@ type EmailStatus struct {
 //
 //	false - if attachment path is empty or not found, true - otherwise
 func FormMessage(subject, body, attachmentFilePath, senderEmail string, recipientEmail string) (*gomail.Message, bool) {
+	var attachments []string
+	if attachmentFilePath != "" {
+		attachments = append(attachments, attachmentFilePath)
+	}
+	return FormMessageWithAttachments(subject, body, attachments, senderEmail, recipientEmail)
+}
+
+// FormMessageWithAttachments is the same as FormMessage, but attaches all the files from `attachmentFilePaths`.
+// Files that are not found are skipped. Returns *gomail.Message and the boolean:
+//
+//	false - if there are no attachment paths or some of them are not found, true - otherwise
+func FormMessageWithAttachments(subject, body string, attachmentFilePaths []string, senderEmail string, recipientEmail string) (*gomail.Message, bool) {
 	// Create new message
 	message := gomail.NewMessage()
 
@@ -36,12 +48,14 @@ func FormMessage(subject, body, attachmentFilePath, senderEmail string, recipien
 
 	message.SetBody("text/plain", body)
 
-	if attachmentFilePath != "" {
-		if _, err := os.Stat(attachmentFilePath); err == nil {
-			message.Attach(attachmentFilePath)
-			return message, true
+	attachedAll := len(attachmentFilePaths) > 0
+	for _, path := range attachmentFilePaths {
+		if _, err := os.Stat(path); err != nil {
+			attachedAll = false
+			continue
 		}
+		message.Attach(path)
 	}
 
-	return message, false
+	return message, attachedAll
 }
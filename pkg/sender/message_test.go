@@ -1,8 +1,10 @@
 package sender
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -36,3 +38,36 @@ func TestFormMessage(t *testing.T) {
 		require.Equal(t, []string{"you@example.com"}, email.GetHeader("To"))
 	})
 }
+
+func TestFormMessageWithAttachments(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "12345_Иванов_Иван.pdf")
+	second := filepath.Join(dir, "12346_Иванова_Анна.pdf")
+	require.NoError(t, os.WriteFile(first, []byte("first"), 0644))
+	require.NoError(t, os.WriteFile(second, []byte("second"), 0644))
+
+	t.Run("all attachments found", func(t *testing.T) {
+		email, isAttach := FormMessageWithAttachments("Subject", "Body", []string{first, second}, "me@example.com", "you@example.com")
+		require.True(t, isAttach)
+
+		var buf bytes.Buffer
+		_, err := email.WriteTo(&buf)
+		require.NoError(t, err)
+		require.Equal(t, 2, strings.Count(buf.String(), "Content-Disposition: attachment"))
+	})
+
+	t.Run("some attachment not found", func(t *testing.T) {
+		email, isAttach := FormMessageWithAttachments("Subject", "Body", []string{first, "no-such-file.pdf"}, "me@example.com", "you@example.com")
+		require.False(t, isAttach)
+
+		var buf bytes.Buffer
+		_, err := email.WriteTo(&buf)
+		require.NoError(t, err)
+		require.Equal(t, 1, strings.Count(buf.String(), "Content-Disposition: attachment"))
+	})
+
+	t.Run("no attachments", func(t *testing.T) {
+		_, isAttach := FormMessageWithAttachments("Subject", "Body", nil, "me@example.com", "you@example.com")
+		require.False(t, isAttach)
+	})
+}
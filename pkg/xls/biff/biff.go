@@ -0,0 +1,495 @@
+// Package biff reads cell values from legacy Excel 97-2003 workbooks (.xls files in BIFF8 format).
+// Only values are read: styles, formulas and number formats are ignored. Numbers are returned in the shortest form,
+// as Excel shows them with the "General" format; formula cells return their last calculated value.
+package biff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/richardlehane/mscfb"
+)
+
+var (
+	// ErrNoWorkbookStream is returned when the file is an OLE compound file, but not an Excel workbook
+	// (e.g. a Word document or a password protected .xlsx)
+	ErrNoWorkbookStream = errors.New("no workbook stream in the compound file")
+
+	// ErrUnsupportedVersion is returned for workbooks older than Excel 97 (BIFF5 and earlier)
+	ErrUnsupportedVersion = errors.New("unsupported BIFF version, only Excel 97-2003 workbooks are supported")
+
+	// ErrEncrypted is returned when the workbook is protected with a password
+	ErrEncrypted = errors.New("workbook is password protected")
+
+	// ErrCorrupted is returned when the workbook structure can not be read
+	ErrCorrupted = errors.New("corrupted workbook")
+)
+
+// Signature is the header of an OLE compound file. Legacy .xls workbooks are stored in such files.
+var Signature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// Workbook contains all worksheets of the legacy workbook in the order they appear in Excel.
+type Workbook struct {
+	Sheets []Sheet
+}
+
+// Sheet is a worksheet with cell values.
+// Rows[i][j] is the value of the cell in the row i+1 and column j+1; trailing empty cells and rows are trimmed.
+type Sheet struct {
+	Name string
+	Rows [][]string
+}
+
+// IsCompoundFile reports whether the data (at least its first 8 bytes) starts with the OLE compound file signature.
+func IsCompoundFile(header []byte) bool {
+	return bytes.HasPrefix(header, Signature)
+}
+
+// Open reads the legacy workbook from the file at path.
+func Open(path string) (*Workbook, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Read reads the legacy workbook from the OLE compound file.
+func Read(ra io.ReaderAt) (*Workbook, error) {
+	doc, err := mscfb.New(ra)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+
+	for entry, err := doc.Next(); err == nil; entry, err = doc.Next() {
+		switch entry.Name {
+		case "Workbook":
+			stream, err := io.ReadAll(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+			}
+			return parseWorkbook(stream)
+		case "Book": // BIFF5 workbooks (Excel 5.0/95) use another stream name
+			return nil, ErrUnsupportedVersion
+		}
+	}
+	return nil, ErrNoWorkbookStream
+}
+
+// BIFF8 record types used by the reader
+const (
+	recBOF        = 0x0809
+	recEOF        = 0x000A
+	recFilePass   = 0x002F
+	recBoundSheet = 0x0085
+	recSST        = 0x00FC
+	recContinue   = 0x003C
+	recLabelSST   = 0x00FD
+	recLabel      = 0x0204
+	recNumber     = 0x0203
+	recRK         = 0x027E
+	recMulRK      = 0x00BD
+	recFormula    = 0x0006
+	recString     = 0x0207
+	recBoolErr    = 0x0205
+	recDimensions = 0x0200
+)
+
+const (
+	biff8Version  = 0x0600 // version field of BOF record in BIFF8
+	bofWorksheet  = 0x0010 // substream type of BOF record for worksheets
+	sheetTypeWork = 0x00   // sheet type of BoundSheet record for worksheets (not charts or macros)
+	maxSheetRows  = 65536  // BIFF8 worksheet has at most 65536 rows
+	maxSheetCols  = 256    // and 256 columns
+)
+
+type record struct {
+	typ    uint16
+	data   []byte
+	offset int // offset of the record header in the stream
+}
+
+// stream iterates over records of the workbook stream
+type stream struct {
+	data []byte
+	pos  int
+}
+
+// next returns the next record. ok is false at the end of the stream or if the record is truncated.
+func (s *stream) next() (rec record, ok bool) {
+	if s.pos+4 > len(s.data) {
+		return record{}, false
+	}
+	typ := binary.LittleEndian.Uint16(s.data[s.pos:])
+	size := int(binary.LittleEndian.Uint16(s.data[s.pos+2:]))
+	if s.pos+4+size > len(s.data) {
+		return record{}, false
+	}
+
+	rec = record{typ: typ, data: s.data[s.pos+4 : s.pos+4+size], offset: s.pos}
+	s.pos += 4 + size
+	return rec, true
+}
+
+// continuation returns the data of the record followed by the data of all its CONTINUE records.
+func (s *stream) continuation(rec record) [][]byte {
+	segments := [][]byte{rec.data}
+	for {
+		pos := s.pos
+		cont, ok := s.next()
+		if !ok || cont.typ != recContinue {
+			s.pos = pos
+			return segments
+		}
+		segments = append(segments, cont.data)
+	}
+}
+
+type boundSheet struct {
+	name   string
+	offset int // offset of the sheet BOF record in the stream
+}
+
+// parseWorkbook reads the workbook globals substream (sheets list and shared strings),
+// then reads cell values of every worksheet.
+func parseWorkbook(data []byte) (*Workbook, error) {
+	s := &stream{data: data}
+
+	bof, ok := s.next()
+	if !ok || bof.typ != recBOF || len(bof.data) < 4 {
+		return nil, fmt.Errorf("%w: no BOF record in the workbook globals", ErrCorrupted)
+	}
+	if binary.LittleEndian.Uint16(bof.data) != biff8Version {
+		return nil, ErrUnsupportedVersion
+	}
+
+	var (
+		sheets []boundSheet
+		sst    []string
+		err    error
+	)
+
+globals:
+	for {
+		rec, ok := s.next()
+		if !ok {
+			return nil, fmt.Errorf("%w: unexpected end of the workbook globals", ErrCorrupted)
+		}
+
+		switch rec.typ {
+		case recEOF:
+			break globals
+		case recFilePass:
+			return nil, ErrEncrypted
+		case recBoundSheet:
+			sheet, isWorksheet, err := parseBoundSheet(rec.data)
+			if err != nil {
+				return nil, err
+			}
+			if isWorksheet {
+				sheets = append(sheets, sheet)
+			}
+		case recSST:
+			if sst, err = parseSST(s.continuation(rec)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	wb := &Workbook{}
+	for _, bs := range sheets {
+		rows, err := parseSheet(data, bs.offset, sst)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", bs.name, err)
+		}
+		wb.Sheets = append(wb.Sheets, Sheet{Name: bs.name, Rows: rows})
+	}
+	return wb, nil
+}
+
+// parseBoundSheet reads the sheet name and position. isWorksheet is false for chart and macro sheets.
+func parseBoundSheet(data []byte) (sheet boundSheet, isWorksheet bool, err error) {
+	if len(data) < 8 {
+		return boundSheet{}, false, fmt.Errorf("%w: short BoundSheet record", ErrCorrupted)
+	}
+
+	r := newSegmentReader([][]byte{data[6:]})
+	cch := int(r.uint8())
+	name := r.chars(cch, r.uint8()&0x01 != 0)
+	if r.err != nil {
+		return boundSheet{}, false, r.err
+	}
+
+	sheet = boundSheet{name: name, offset: int(binary.LittleEndian.Uint32(data))}
+	return sheet, data[5] == sheetTypeWork, nil
+}
+
+// parseSST reads the shared strings table, that may be split into several CONTINUE records.
+func parseSST(segments [][]byte) ([]string, error) {
+	r := newSegmentReader(segments)
+	r.uint32() // total number of strings references in the workbook
+	count := int(r.uint32())
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	strs := make([]string, 0, min(count, 1<<16))
+	for i := 0; i < count; i++ {
+		str := r.unicodeString()
+		if r.err != nil {
+			return nil, fmt.Errorf("shared string %d: %w", i, r.err)
+		}
+		strs = append(strs, str)
+	}
+	return strs, nil
+}
+
+// parseSheet reads cell values of the worksheet substream starting at the given offset.
+func parseSheet(data []byte, offset int, sst []string) ([][]string, error) {
+	if offset < 0 || offset >= len(data) {
+		return nil, fmt.Errorf("%w: sheet offset is out of the stream", ErrCorrupted)
+	}
+	s := &stream{data: data, pos: offset}
+
+	bof, ok := s.next()
+	if !ok || bof.typ != recBOF || len(bof.data) < 4 || binary.LittleEndian.Uint16(bof.data[2:]) != bofWorksheet {
+		return nil, fmt.Errorf("%w: no worksheet BOF record", ErrCorrupted)
+	}
+
+	var (
+		cells       = cellSet{maxRows: maxSheetRows, maxCols: maxSheetCols}
+		depth       = 1  // embedded substreams (e.g. charts) have their own BOF/EOF
+		formulaCell *ref // cell of the formula, which string result follows in the STRING record
+	)
+
+	for depth > 0 {
+		rec, ok := s.next()
+		if !ok {
+			return nil, fmt.Errorf("%w: unexpected end of the worksheet", ErrCorrupted)
+		}
+
+		switch rec.typ {
+		case recBOF:
+			depth++
+			continue
+		case recEOF:
+			depth--
+			continue
+		}
+		if depth > 1 {
+			continue
+		}
+
+		if err := parseCell(s, rec, sst, &cells, &formulaCell); err != nil {
+			return nil, fmt.Errorf("record at offset %d: %w", rec.offset, err)
+		}
+	}
+
+	return cells.rows, nil
+}
+
+// ref is a zero-based cell position
+type ref struct {
+	row, col int
+}
+
+// cellSet accumulates cell values into rows. The cells out of the sheet dimensions are rejected,
+// so a corrupted record can not grow the rows up to the whole uint16 range.
+type cellSet struct {
+	rows             [][]string
+	maxRows, maxCols int // the sheet dimensions: the DIMENSIONS record or the BIFF8 limits
+}
+
+func (c *cellSet) set(row, col int, value string) error {
+	if value == "" {
+		return nil
+	}
+	if row >= c.maxRows || col >= c.maxCols {
+		return fmt.Errorf("%w: cell (%d, %d) is out of the sheet dimensions %dx%d", ErrCorrupted, row, col, c.maxRows, c.maxCols)
+	}
+	for len(c.rows) <= row {
+		c.rows = append(c.rows, nil)
+	}
+	for len(c.rows[row]) <= col {
+		c.rows[row] = append(c.rows[row], "")
+	}
+	c.rows[row][col] = value
+	return nil
+}
+
+// parseCell stores the value of the cell record into cells. Records that are not cell values are ignored.
+func parseCell(s *stream, rec record, sst []string, cells *cellSet, formulaCell **ref) error {
+	data := rec.data
+
+	cellRef := func(minSize int) (ref, error) {
+		if len(data) < minSize {
+			return ref{}, fmt.Errorf("%w: short cell record %#04x", ErrCorrupted, rec.typ)
+		}
+		return ref{row: int(binary.LittleEndian.Uint16(data)), col: int(binary.LittleEndian.Uint16(data[2:]))}, nil
+	}
+
+	switch rec.typ {
+	case recDimensions:
+		// rwMic, rwMac, colMic, colMac: the sheet has rwMac rows and colMac columns
+		if len(data) < 12 {
+			return fmt.Errorf("%w: short Dimensions record", ErrCorrupted)
+		}
+		rows, cols := int(binary.LittleEndian.Uint32(data[4:])), int(binary.LittleEndian.Uint16(data[10:]))
+		if rows > 0 && cols > 0 { // some writers leave the dimensions empty
+			cells.maxRows, cells.maxCols = min(rows, maxSheetRows), min(cols, maxSheetCols)
+		}
+
+	case recLabelSST:
+		at, err := cellRef(10)
+		if err != nil {
+			return err
+		}
+		idx := int(binary.LittleEndian.Uint32(data[6:]))
+		if idx >= len(sst) {
+			return fmt.Errorf("%w: shared string index %d is out of range", ErrCorrupted, idx)
+		}
+		return cells.set(at.row, at.col, sst[idx])
+
+	case recLabel:
+		at, err := cellRef(9)
+		if err != nil {
+			return err
+		}
+		r := newSegmentReader(s.continuation(record{data: data[6:]}))
+		value := r.charsWithOptions(int(r.uint16()))
+		if r.err != nil {
+			return r.err
+		}
+		return cells.set(at.row, at.col, value)
+
+	case recNumber:
+		at, err := cellRef(14)
+		if err != nil {
+			return err
+		}
+		return cells.set(at.row, at.col, formatNumber(math.Float64frombits(binary.LittleEndian.Uint64(data[6:]))))
+
+	case recRK:
+		at, err := cellRef(10)
+		if err != nil {
+			return err
+		}
+		return cells.set(at.row, at.col, formatNumber(decodeRK(binary.LittleEndian.Uint32(data[6:]))))
+
+	case recMulRK:
+		at, err := cellRef(6)
+		if err != nil {
+			return err
+		}
+		// rw, colFirst, [ixfe, rk] * n, colLast
+		for i, pos := 0, 4; pos+6 <= len(data)-2; i, pos = i+1, pos+6 {
+			if err := cells.set(at.row, at.col+i, formatNumber(decodeRK(binary.LittleEndian.Uint32(data[pos+2:])))); err != nil {
+				return err
+			}
+		}
+
+	case recBoolErr:
+		at, err := cellRef(8)
+		if err != nil {
+			return err
+		}
+		return cells.set(at.row, at.col, formatBoolErr(data[6], data[7] != 0))
+
+	case recFormula:
+		at, err := cellRef(14)
+		if err != nil {
+			return err
+		}
+		result := data[6:14]
+		if result[6] != 0xFF || result[7] != 0xFF { // the result is a number
+			return cells.set(at.row, at.col, formatNumber(math.Float64frombits(binary.LittleEndian.Uint64(result))))
+		}
+		switch result[0] {
+		case 0x00: // string, the value is in the next STRING record
+			*formulaCell = &at
+		case 0x01: // boolean
+			return cells.set(at.row, at.col, formatBoolErr(result[2], false))
+		case 0x02: // error
+			return cells.set(at.row, at.col, formatBoolErr(result[2], true))
+		}
+
+	case recString:
+		if *formulaCell == nil {
+			return nil
+		}
+		at := **formulaCell
+		*formulaCell = nil
+
+		r := newSegmentReader(s.continuation(rec))
+		value := r.charsWithOptions(int(r.uint16()))
+		if r.err != nil {
+			return r.err
+		}
+		return cells.set(at.row, at.col, value)
+	}
+
+	return nil
+}
+
+// decodeRK decodes the compressed number of RK and MULRK records
+func decodeRK(rk uint32) float64 {
+	var value float64
+	if rk&0x02 != 0 { // signed 30-bit integer
+		value = float64(int32(rk) >> 2)
+	} else { // the most significant 30 bits of the float64
+		value = math.Float64frombits(uint64(rk&0xFFFFFFFC) << 32)
+	}
+
+	if rk&0x01 != 0 { // the value is multiplied by 100
+		value /= 100
+	}
+	return value
+}
+
+// formatNumber formats the number as Excel shows it with the "General" format:
+// up to 15 significant digits, without exponent and trailing zeros.
+// It hides float artifacts, e.g. 0.1+0.2 is shown as "0.3", not "0.30000000000000004".
+func formatNumber(value float64) string {
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(value, 'g', 15, 64), 64)
+	if err != nil {
+		rounded = value
+	}
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
+
+// formatBoolErr formats the value of the boolean or error cell
+func formatBoolErr(value byte, isError bool) string {
+	if !isError {
+		if value != 0 {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+
+	switch value {
+	case 0x00:
+		return "#NULL!"
+	case 0x07:
+		return "#DIV/0!"
+	case 0x0F:
+		return "#VALUE!"
+	case 0x17:
+		return "#REF!"
+	case 0x1D:
+		return "#NAME?"
+	case 0x24:
+		return "#NUM!"
+	case 0x2A:
+		return "#N/A"
+	default:
+		return "#ERROR!"
+	}
+}
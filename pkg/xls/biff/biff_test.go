@@ -0,0 +1,367 @@
+package biff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ---- Helpers building BIFF8 workbooks in OLE compound files ----
+
+func le16(v uint16) []byte { return binary.LittleEndian.AppendUint16(nil, v) }
+func le32(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+
+func biffRecord(typ uint16, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	return bytes.Join([][]byte{le16(typ), le16(uint16(len(body))), body}, nil)
+}
+
+func bofRecord(version, dt uint16) []byte {
+	return biffRecord(recBOF, le16(version), le16(dt), make([]byte, 12))
+}
+
+// wideChars returns options byte with "wide" flag followed by UTF-16 characters
+func wideChars(s string) []byte {
+	buf := []byte{0x01}
+	for _, c := range utf16.Encode([]rune(s)) {
+		buf = append(buf, le16(c)...)
+	}
+	return buf
+}
+
+func cellHeader(row, col int) []byte {
+	return bytes.Join([][]byte{le16(uint16(row)), le16(uint16(col)), le16(0)}, nil)
+}
+
+func labelSST(row, col, idx int) []byte {
+	return biffRecord(recLabelSST, cellHeader(row, col), le32(uint32(idx)))
+}
+
+func label(row, col int, s string) []byte {
+	return biffRecord(recLabel, cellHeader(row, col), le16(uint16(len([]rune(s)))), append([]byte{0x00}, s...))
+}
+
+func number(row, col int, v float64) []byte {
+	return biffRecord(recNumber, cellHeader(row, col), binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)))
+}
+
+func rkInt(v int32, x100 bool) uint32 {
+	rk := uint32(v)<<2 | 0x02
+	if x100 {
+		rk |= 0x01
+	}
+	return rk
+}
+
+func rk(row, col int, value uint32) []byte {
+	return biffRecord(recRK, cellHeader(row, col), le32(value))
+}
+
+func mulRK(row, col int, values ...uint32) []byte {
+	data := [][]byte{le16(uint16(row)), le16(uint16(col))}
+	for _, v := range values {
+		data = append(data, le16(0), le32(v))
+	}
+	data = append(data, le16(uint16(col+len(values)-1)))
+	return biffRecord(recMulRK, data...)
+}
+
+func formulaNumber(row, col int, v float64) []byte {
+	return biffRecord(recFormula, cellHeader(row, col), binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)), make([]byte, 6))
+}
+
+func formulaString(row, col int, s string) []byte {
+	result := []byte{0x00, 0, 0, 0, 0, 0, 0xFF, 0xFF}
+	return append(
+		biffRecord(recFormula, cellHeader(row, col), result, make([]byte, 6)),
+		biffRecord(recString, le16(uint16(len(utf16.Encode([]rune(s))))), wideChars(s))...,
+	)
+}
+
+func boolErr(row, col int, value byte, isError bool) []byte {
+	flag := byte(0)
+	if isError {
+		flag = 1
+	}
+	return biffRecord(recBoolErr, cellHeader(row, col), []byte{value, flag})
+}
+
+// dimensions is the DIMENSIONS record of the sheet with the given number of rows and columns
+func dimensions(rows, cols int) []byte {
+	return biffRecord(recDimensions, le32(0), le32(uint32(rows)), le16(0), le16(uint16(cols)), le16(0))
+}
+
+type testSheet struct {
+	name    string
+	records [][]byte
+}
+
+// workbookStream builds the BIFF8 workbook stream with the shared strings and worksheets
+func workbookStream(sst []string, sheets []testSheet, globals ...[]byte) []byte {
+	build := func(offsets []int) []byte {
+		var buf []byte
+		buf = append(buf, bofRecord(biff8Version, 0x0005)...)
+		buf = append(buf, bytes.Join(globals, nil)...)
+		for i, sh := range sheets {
+			name := wideChars(sh.name)
+			buf = append(buf, biffRecord(recBoundSheet, le32(uint32(offsets[i])), []byte{0, sheetTypeWork, byte(len([]rune(sh.name)))}, name)...)
+		}
+		if sst != nil {
+			data := [][]byte{le32(uint32(len(sst))), le32(uint32(len(sst)))}
+			for _, s := range sst {
+				data = append(data, le16(uint16(len(utf16.Encode([]rune(s))))), wideChars(s))
+			}
+			buf = append(buf, biffRecord(recSST, data...)...)
+		}
+		buf = append(buf, biffRecord(recEOF)...)
+
+		for i, sh := range sheets {
+			offsets[i] = len(buf)
+			buf = append(buf, bofRecord(biff8Version, bofWorksheet)...)
+			buf = append(buf, bytes.Join(sh.records, nil)...)
+			buf = append(buf, biffRecord(recEOF)...)
+		}
+		return buf
+	}
+
+	offsets := make([]int, len(sheets))
+	build(offsets) // the first pass calculates offsets of the sheets
+	return build(offsets)
+}
+
+// compoundFile puts the single stream into the minimal version 3 OLE compound file
+func compoundFile(streamName string, stream []byte) []byte {
+	const (
+		sectorSize = 512
+		endOfChain = 0xFFFFFFFE
+		freeSect   = 0xFFFFFFFF
+		noStream   = 0xFFFFFFFF
+	)
+
+	// streams shorter than 4096 bytes are stored in the mini stream, pad the stream to avoid it
+	if len(stream) < 4096 {
+		stream = append(stream, make([]byte, 4096-len(stream))...)
+	}
+	dataSectors := (len(stream) + sectorSize - 1) / sectorSize
+
+	// header, FAT sector, directory sector and stream sectors
+	buf := make([]byte, sectorSize*(3+dataSectors))
+	put16 := func(off int, v uint16) { binary.LittleEndian.PutUint16(buf[off:], v) }
+	put32 := func(off int, v uint32) { binary.LittleEndian.PutUint32(buf[off:], v) }
+
+	copy(buf, Signature)
+	put16(24, 0x003E)     // minor version
+	put16(26, 3)          // major version
+	put16(28, 0xFFFE)     // byte order
+	put16(30, 9)          // sector shift
+	put16(32, 6)          // mini sector shift
+	put32(44, 1)          // number of FAT sectors
+	put32(48, 1)          // first directory sector
+	put32(56, 4096)       // mini stream cutoff
+	put32(60, endOfChain) // first mini FAT sector
+	put32(68, endOfChain) // first DIFAT sector
+	put32(76, 0)          // the FAT is in the sector 0
+	for off := 80; off < sectorSize; off += 4 {
+		put32(off, freeSect)
+	}
+
+	sector := func(n int) int { return sectorSize * (n + 1) }
+
+	// FAT: sector 0 is FAT itself, 1 is the directory, then the stream chain
+	fat := sector(0)
+	for i := 0; i < sectorSize/4; i++ {
+		put32(fat+4*i, freeSect)
+	}
+	put32(fat, 0xFFFFFFFD)
+	put32(fat+4, endOfChain)
+	for i := 0; i < dataSectors; i++ {
+		next := uint32(i + 3)
+		if i == dataSectors-1 {
+			next = endOfChain
+		}
+		put32(fat+4*(i+2), next)
+	}
+
+	dirEntry := func(idx int, name string, typ byte, child, start, size uint32) {
+		off := sector(1) + 128*idx
+		nameUTF16 := utf16.Encode([]rune(name))
+		for i, c := range nameUTF16 {
+			put16(off+2*i, c)
+		}
+		if name != "" {
+			put16(off+64, uint16(2*(len(nameUTF16)+1)))
+		}
+		buf[off+66] = typ
+		buf[off+67] = 1 // black
+		put32(off+68, noStream)
+		put32(off+72, noStream)
+		put32(off+76, child)
+		put32(off+116, start)
+		put32(off+120, size)
+	}
+	dirEntry(0, "Root Entry", 5, 1, endOfChain, 0)
+	dirEntry(1, streamName, 2, noStream, 2, uint32(len(stream)))
+	dirEntry(2, "", 0, noStream, 0, 0)
+	dirEntry(3, "", 0, noStream, 0, 0)
+
+	copy(buf[sector(2):], stream)
+	return buf
+}
+
+// ---- Tests ----
+
+func TestRead(t *testing.T) {
+	stream := workbookStream(
+		[]string{"Лицевой счет", "Иванов Иван"},
+		[]testSheet{
+			{
+				name: "Реестр начислений",
+				records: [][]byte{
+					dimensions(7, 4),
+					labelSST(0, 0, 0),
+					label(0, 1, "Sum"),
+					labelSST(2, 0, 1),
+					number(2, 1, 100.5),
+					rk(3, 0, rkInt(92701000, false)),
+					rk(3, 1, rkInt(10050, true)),
+					mulRK(4, 1, rkInt(1, false), rkInt(2, false), rkInt(3, false)),
+					formulaNumber(5, 0, 0.1+0.2),
+					formulaString(5, 1, "ИТОГО"),
+					boolErr(6, 0, 1, false),
+					boolErr(6, 1, 0x07, true),
+					// embedded chart substream must be skipped
+					bofRecord(biff8Version, 0x0020),
+					labelSST(10, 10, 0),
+					biffRecord(recEOF),
+				},
+			},
+			{name: "Пустой"},
+		},
+	)
+
+	wb, err := Read(bytes.NewReader(compoundFile("Workbook", stream)))
+	require.NoError(t, err)
+	require.Len(t, wb.Sheets, 2)
+
+	require.Equal(t, "Реестр начислений", wb.Sheets[0].Name)
+	require.Equal(t, [][]string{
+		{"Лицевой счет", "Sum"},
+		nil,
+		{"Иванов Иван", "100.5"},
+		{"92701000", "100.5"},
+		{"", "1", "2", "3"},
+		{"0.3", "ИТОГО"},
+		{"TRUE", "#DIV/0!"},
+	}, wb.Sheets[0].Rows)
+
+	require.Equal(t, "Пустой", wb.Sheets[1].Name)
+	require.Empty(t, wb.Sheets[1].Rows)
+}
+
+func TestRead_Errors(t *testing.T) {
+	sheets := []testSheet{{name: "Лист1", records: [][]byte{number(0, 0, 1)}}}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{
+			name: "not a compound file",
+			data: []byte("PK\x03\x04 this is a zip archive"),
+			want: ErrCorrupted,
+		},
+		{
+			name: "not a workbook",
+			data: compoundFile("WordDocument", workbookStream(nil, sheets)),
+			want: ErrNoWorkbookStream,
+		},
+		{
+			name: "Excel 95 workbook",
+			data: compoundFile("Book", workbookStream(nil, sheets)),
+			want: ErrUnsupportedVersion,
+		},
+		{
+			name: "old BIFF version in the Workbook stream",
+			data: compoundFile("Workbook", append(bofRecord(0x0500, 0x0005), biffRecord(recEOF)...)),
+			want: ErrUnsupportedVersion,
+		},
+		{
+			name: "password protected",
+			data: compoundFile("Workbook", workbookStream(nil, sheets, biffRecord(recFilePass, make([]byte, 6)))),
+			want: ErrEncrypted,
+		},
+		{
+			name: "shared string index out of range",
+			data: compoundFile("Workbook", workbookStream([]string{"a"}, []testSheet{{name: "Лист1", records: [][]byte{labelSST(0, 0, 5)}}})),
+			want: ErrCorrupted,
+		},
+		{
+			name: "cell out of the sheet dimensions",
+			data: compoundFile("Workbook", workbookStream(nil, []testSheet{{name: "Лист1", records: [][]byte{dimensions(1, 1), number(65535, 255, 1)}}})),
+			want: ErrCorrupted,
+		},
+		{
+			name: "cells out of the BIFF8 columns",
+			data: compoundFile("Workbook", workbookStream(nil, []testSheet{{name: "Лист1", records: [][]byte{mulRK(0, 255, rkInt(1, false), rkInt(2, false))}}})),
+			want: ErrCorrupted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(bytes.NewReader(tt.data))
+			require.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestParseSST_Continue(t *testing.T) {
+	// "Привет" starts as a wide string in the SST record and continues in the CONTINUE record
+	// with the compressed characters "abc"; the next string goes entirely in the CONTINUE record.
+	sst := bytes.Join([][]byte{le32(2), le32(2), le16(9), wideChars("Привет")}, nil)
+	cont := bytes.Join([][]byte{{0x00}, []byte("abc"), le16(2), {0x08}, le16(1), []byte("ok"), le32(0)}, nil)
+
+	strs, err := parseSST([][]byte{sst, cont})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Приветabc", "ok"}, strs)
+
+	_, err = parseSST([][]byte{sst})
+	require.ErrorIs(t, err, ErrCorrupted)
+}
+
+func TestDecodeRK(t *testing.T) {
+	require.Equal(t, float64(92701000), decodeRK(rkInt(92701000, false)))
+	require.Equal(t, -15.0, decodeRK(rkInt(-15, false)))
+	require.Equal(t, 100.5, decodeRK(rkInt(10050, true)))
+
+	// float with the low 34 bits of mantissa dropped
+	bits := math.Float64bits(1.5)
+	require.Equal(t, 1.5, decodeRK(uint32(bits>>32)))
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := map[float64]string{
+		200:                  "200",
+		100.5:                "100.5",
+		0.1 + 0.2:            "0.3",
+		1199.9999999999998:   "1200",
+		92701000001:          "92701000001",
+		-3.25:                "-3.25",
+		82100000000000000111: "82100000000000000000",
+	}
+
+	for value, want := range tests {
+		require.Equal(t, want, formatNumber(value))
+	}
+}
+
+func TestIsCompoundFile(t *testing.T) {
+	require.True(t, IsCompoundFile(compoundFile("Workbook", nil)))
+	require.False(t, IsCompoundFile([]byte("PK\x03\x04")))
+	require.False(t, IsCompoundFile(nil))
+}
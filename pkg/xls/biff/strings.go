@@ -0,0 +1,153 @@
+package biff
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// segmentReader reads data of the record split into several CONTINUE records.
+// Numbers and skipped bytes are read across segments as a single stream, but characters of a string
+// continued in the next segment are preceded by a new options byte (see chars).
+// The first error is stored in err, after that all reads return zero values.
+type segmentReader struct {
+	segments [][]byte
+	seg, pos int
+	err      error
+}
+
+func newSegmentReader(segments [][]byte) *segmentReader {
+	return &segmentReader{segments: segments}
+}
+
+// read returns the next n bytes, joining segments if needed
+func (r *segmentReader) read(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	buf := make([]byte, 0, n)
+	for len(buf) < n {
+		if r.pos >= len(r.segments[r.seg]) {
+			if !r.nextSegment() {
+				r.err = fmt.Errorf("%w: unexpected end of record", ErrCorrupted)
+				return nil
+			}
+		}
+		take := min(n-len(buf), len(r.segments[r.seg])-r.pos)
+		buf = append(buf, r.segments[r.seg][r.pos:r.pos+take]...)
+		r.pos += take
+	}
+	return buf
+}
+
+// skip moves over the next n bytes, joining segments if needed
+func (r *segmentReader) skip(n int) {
+	for n > 0 && r.err == nil {
+		if r.pos >= len(r.segments[r.seg]) {
+			if !r.nextSegment() {
+				r.err = fmt.Errorf("%w: unexpected end of record", ErrCorrupted)
+				return
+			}
+		}
+		take := min(n, len(r.segments[r.seg])-r.pos)
+		r.pos += take
+		n -= take
+	}
+}
+
+func (r *segmentReader) nextSegment() bool {
+	if r.seg+1 >= len(r.segments) {
+		return false
+	}
+	r.seg++
+	r.pos = 0
+	return true
+}
+
+func (r *segmentReader) uint8() uint8 {
+	b := r.read(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *segmentReader) uint16() uint16 {
+	b := r.read(2)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+func (r *segmentReader) uint32() uint32 {
+	b := r.read(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+// chars reads cch characters. wide tells whether characters are stored in 2 bytes (UTF-16)
+// or in 1 byte (UTF-16 with omitted zero high byte). If the segment ends in the middle of the string,
+// the next segment starts with the options byte that tells the width of the rest characters.
+func (r *segmentReader) chars(cch int, wide bool) string {
+	if r.err != nil {
+		return ""
+	}
+
+	buf := make([]uint16, 0, cch)
+	for len(buf) < cch {
+		if r.pos >= len(r.segments[r.seg]) {
+			if !r.nextSegment() || len(r.segments[r.seg]) == 0 {
+				r.err = fmt.Errorf("%w: unexpected end of string", ErrCorrupted)
+				return ""
+			}
+			wide = r.segments[r.seg][0]&0x01 != 0
+			r.pos = 1
+			continue
+		}
+
+		segment := r.segments[r.seg]
+		if wide {
+			if r.pos+2 > len(segment) {
+				r.err = fmt.Errorf("%w: truncated character", ErrCorrupted)
+				return ""
+			}
+			buf = append(buf, binary.LittleEndian.Uint16(segment[r.pos:]))
+			r.pos += 2
+		} else {
+			buf = append(buf, uint16(segment[r.pos]))
+			r.pos++
+		}
+	}
+	return string(utf16.Decode(buf))
+}
+
+// charsWithOptions reads the options byte followed by cch characters (XLUnicodeString without the length).
+func (r *segmentReader) charsWithOptions(cch int) string {
+	options := r.uint8()
+	return r.chars(cch, options&0x01 != 0)
+}
+
+// unicodeString reads XLUnicodeRichExtendedString used in the shared strings table:
+// length, options, optional formatting runs count and phonetic data size, characters, then runs and phonetic data.
+func (r *segmentReader) unicodeString() string {
+	cch := int(r.uint16())
+	options := r.uint8()
+
+	var runs, extSize int
+	if options&0x08 != 0 { // rich text formatting
+		runs = int(r.uint16())
+	}
+	if options&0x04 != 0 { // phonetic data
+		extSize = int(r.uint32())
+	}
+
+	str := r.chars(cch, options&0x01 != 0)
+
+	// formatting runs and phonetic data are not needed, skip them
+	r.skip(4*runs + extSize)
+	return str
+}
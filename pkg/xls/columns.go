@@ -0,0 +1,132 @@
+package xls
+
+import "strings"
+
+// Canonical header names of the payers sheet columns. They are used as keys of the columns mapping
+// and are shown to the user when the column is missing.
+const (
+	HeaderPersAcc  = "Лицевой счет"
+	HeaderChildFIO = "ФИО обучающегося"
+	HeaderPurpose  = "Назначение"
+	HeaderCBC      = "КБК"
+	HeaderOKTMO    = "ОКТМО"
+	HeaderSum      = "Сумма"
+)
+
+// PayersRequiredHeaders lists all columns that must be present on the payers sheet, in the order
+// they are reported if missing.
+var PayersRequiredHeaders = []string{
+	HeaderPersAcc,
+	HeaderChildFIO,
+	HeaderPurpose,
+	HeaderCBC,
+	HeaderOKTMO,
+	HeaderSum,
+}
+
+// PayersHeaderAliases maps the canonical header of the payers sheet column to alternative names it may be titled with.
+// Matching is case-insensitive, ignores extra spaces and does not distinguish 'е' and 'ё'.
+// Extend it if the registry comes with other column titles.
+var PayersHeaderAliases = map[string][]string{
+	HeaderPersAcc:  {"ЛС", "Номер лицевого счета"},
+	HeaderChildFIO: {"ФИО ребенка", "ФИО ученика", "Обучающийся"},
+	HeaderPurpose:  {"Назначение платежа"},
+	HeaderCBC:      {"Код бюджетной классификации"},
+	HeaderOKTMO:    {"Код ОКТМО"},
+	HeaderSum:      {"Сумма платежа", "Сумма к оплате"},
+}
+
+// legacyPayersColumns is the fixed columns layout used when the sheet has no recognizable header row:
+// A - personal account, B - child full name, C - purpose, D - CBC, E - OKTMO, H - amount.
+var legacyPayersColumns = map[string]int{
+	HeaderPersAcc:  0,
+	HeaderChildFIO: 1,
+	HeaderPurpose:  2,
+	HeaderCBC:      3,
+	HeaderOKTMO:    4,
+	HeaderSum:      7,
+}
+
+const (
+	// PayersHeaderSearchRows is the number of top rows of the payers sheet where the header row is searched
+	PayersHeaderSearchRows = 20
+
+	// payersHeaderMinMatches is the minimal number of recognized titles in a row to consider it as the header row
+	payersHeaderMinMatches = 2
+)
+
+// normalizeHeader brings the cell title to the form used for matching: lower case, 'ё' replaced with 'е',
+// no trailing colon and single spaces between words.
+func normalizeHeader(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.ReplaceAll(s, "ё", "е")
+	s = strings.TrimSuffix(s, ":")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// matchHeader returns the canonical header of the column titled with the given cell value,
+// or empty string if the title is unknown.
+func matchHeader(cell string) string {
+	title := normalizeHeader(cell)
+	if title == "" {
+		return ""
+	}
+
+	for _, header := range PayersRequiredHeaders {
+		if normalizeHeader(header) == title {
+			return header
+		}
+		for _, alias := range PayersHeaderAliases[header] {
+			if normalizeHeader(alias) == title {
+				return header
+			}
+		}
+	}
+	return ""
+}
+
+// mapHeaderRow matches cells of the row against the known column titles.
+// Returns the mapping `canonical header: column index`. If the title appears several times, the first column is used.
+func mapHeaderRow(row []string) map[string]int {
+	columns := make(map[string]int)
+	for i, cell := range row {
+		header := matchHeader(cell)
+		if header == "" {
+			continue
+		}
+		if _, ok := columns[header]; !ok {
+			columns[header] = i
+		}
+	}
+	return columns
+}
+
+// findPayersHeader looks for the header row among the first PayersHeaderSearchRows rows.
+// The row with the most recognized titles wins, but it must contain at least payersHeaderMinMatches of them.
+// Returns the row index (0-based) and its columns mapping; index is -1 if no header row found.
+func findPayersHeader(rows [][]string) (int, map[string]int) {
+	bestIdx, bestColumns := -1, map[string]int(nil)
+
+	for i, row := range rows {
+		if i >= PayersHeaderSearchRows {
+			break
+		}
+
+		columns := mapHeaderRow(row)
+		if len(columns) >= payersHeaderMinMatches && len(columns) > len(bestColumns) {
+			bestIdx, bestColumns = i, columns
+		}
+	}
+	return bestIdx, bestColumns
+}
+
+// missingHeaders returns canonical headers of the required columns that are absent in the mapping.
+func missingHeaders(columns map[string]int) []string {
+	var missing []string
+	for _, header := range PayersRequiredHeaders {
+		if _, ok := columns[header]; !ok {
+			missing = append(missing, header)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,118 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"li-acc/internal/errs"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// CSVDelimiters are the delimiters recognized in CSV files. The one that occurs most often in the first line is used,
+// semicolon is preferred if none of them occur (it is the default delimiter of Russian Excel).
+var CSVDelimiters = []rune{';', ',', '\t'}
+
+// utf8BOM is the byte order mark that Excel writes in the beginning of UTF-8 CSV files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// ReadCSV reads all rows of the CSV file. The encoding is either UTF-8 (with or without BOM) or Windows-1251,
+// and the delimiter is detected among CSVDelimiters.
+// Rows are placed according to their line numbers, so empty lines are kept as empty rows, and row numbers
+// in the parsing errors match the line numbers in a text editor.
+func ReadCSV(filepath string) ([][]string, error) {
+	data, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, errs.WrapIOError("read CSV file", filepath, err)
+	}
+
+	text, err := decodeCSVText(data)
+	if err != nil {
+		return nil, &UnreadableWorkbookError{Format: FormatCSV, Err: err}
+	}
+
+	r := csv.NewReader(strings.NewReader(text))
+	r.Comma = detectCSVDelimiter(text)
+	r.FieldsPerRecord = -1 // rows may have different number of cells
+	r.LazyQuotes = true
+
+	var rows [][]string
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, &UnreadableWorkbookError{Format: FormatCSV, Err: err}
+		}
+
+		line, _ := r.FieldPos(0)
+		for len(rows) < line-1 {
+			rows = append(rows, nil)
+		}
+		rows = append(rows, record)
+	}
+	return rows, nil
+}
+
+// decodeCSVText returns the text of the file in UTF-8. Windows-1251 encoded text is almost never a valid UTF-8,
+// so the file is decoded from Windows-1251 only if it is not a valid UTF-8.
+func decodeCSVText(data []byte) (string, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if utf8.Valid(data) {
+		return string(data), nil
+	}
+
+	decoded, err := charmap.Windows1251.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+// detectCSVDelimiter returns the delimiter from CSVDelimiters that occurs most often
+// in the first non-empty line outside of quoted fields.
+func detectCSVDelimiter(text string) rune {
+	var line string
+	for _, l := range strings.Split(text, "\n") {
+		if strings.TrimSpace(l) != "" {
+			line = l
+			break
+		}
+	}
+
+	counts := make(map[rune]int)
+	quoted := false
+	for _, c := range line {
+		if c == '"' {
+			quoted = !quoted
+			continue
+		}
+		if !quoted {
+			counts[c]++
+		}
+	}
+
+	best := CSVDelimiters[0]
+	for _, d := range CSVDelimiters {
+		if counts[d] > counts[best] {
+			best = d
+		}
+	}
+	return best
+}
+
+// openCSV reads the CSV file as the workbook with a single sheet named `sheet`. CSV file has only one table,
+// so it is used as the sheet requested by the parser and must be laid out the same way as this sheet.
+func openCSV(filepath, sheet string) (*excelize.File, error) {
+	rows, err := ReadCSV(filepath)
+	if err != nil {
+		return nil, err
+	}
+	return toExcelize([]sheetData{{name: sheet, rows: rows}})
+}
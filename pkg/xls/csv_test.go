@@ -0,0 +1,115 @@
+package xls
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+
+	"li-acc/pkg/model"
+)
+
+// writeTemp writes data to the file with the given name in the test temporary directory and returns its path
+func writeTemp(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	return path
+}
+
+func TestReadCSV(t *testing.T) {
+	cp1251, err := charmap.Windows1251.NewEncoder().String("ФИО;Почта\r\nИванов И.И.;ivanov@example.com\r\n")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		data []byte
+		want [][]string
+	}{
+		{
+			name: "semicolon with BOM",
+			data: []byte("\xEF\xBB\xBFФИО;Почта\nИванов И.И.;ivanov@example.com\n"),
+			want: [][]string{{"ФИО", "Почта"}, {"Иванов И.И.", "ivanov@example.com"}},
+		},
+		{
+			name: "windows-1251",
+			data: []byte(cp1251),
+			want: [][]string{{"ФИО", "Почта"}, {"Иванов И.И.", "ivanov@example.com"}},
+		},
+		{
+			name: "comma with quoted decimal",
+			data: []byte("ЛС,Сумма\n12345,\"1200,50\"\n"),
+			want: [][]string{{"ЛС", "Сумма"}, {"12345", "1200,50"}},
+		},
+		{
+			name: "tab",
+			data: []byte("ЛС\tСумма\n12345\t300\n"),
+			want: [][]string{{"ЛС", "Сумма"}, {"12345", "300"}},
+		},
+		{
+			name: "empty lines are kept",
+			data: []byte("\n\na;b\n\nc;d\n"),
+			want: [][]string{nil, nil, {"a", "b"}, nil, {"c", "d"}},
+		},
+		{
+			name: "delimiter inside quotes is ignored",
+			data: []byte("\"a,b,c\";d\n"),
+			want: [][]string{{"a,b,c", "d"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ReadCSV(writeTemp(t, "table.csv", tt.data))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, rows)
+		})
+	}
+}
+
+func TestReadCSV_MissingFile(t *testing.T) {
+	_, err := ReadCSV(filepath.Join(t.TempDir(), "missing.csv"))
+	require.Error(t, err)
+}
+
+func TestParseCSV(t *testing.T) {
+	// settings lines on the top and the payers table below them, as in a single uploaded registry
+	registry := "Параметр;Значение\n" +
+		"Наименование организации;ООО Ромашка\n" +
+		"Расчетный счет;40702810938000012345\n" +
+		"Наименование банка;Сбер\n" +
+		"БИК;044525225\n" +
+		"Корреспондентский счет;30101810400000000225\n" +
+		"ИНН;7707083893\n" +
+		"КПП;770701001\n" +
+		"Дополнительные параметры ДШК;CATEGORY=4\n" +
+		"\n\n\n\n" +
+		"Лицевой счет;ФИО обучающегося;Назначение;КБК;ОКТМО;Сумма\n" +
+		"12345;Иванов И.И.;Питание;82100000000000000123;92701000;\"1 200,50\"\n"
+	path := writeTemp(t, "registry.csv", []byte(registry))
+
+	org, err := ParseSettingsCSV(path)
+	require.NoError(t, err)
+	require.Equal(t, "ООО Ромашка", org.Name)
+	require.Equal(t, "044525225", org.BIC)
+	require.Equal(t, "CATEGORY=4", org.ExtraParams)
+
+	payers, err := ParsePayersCSV(path)
+	require.NoError(t, err)
+	require.Equal(t, []model.Payer{
+		{PersAcc: "12345", CHILDFIO: "Иванов И.И.", Purpose: "Питание", CBC: "82100000000000000123", OKTMO: "92701000", Sum: model.NewMoney(1200, 50)},
+	}, payers)
+
+	emails, err := ParseEmailCSV(writeTemp(t, "emails.csv", []byte("ФИО;Почта\nИванов И.И.;ivanov@example.com;\"ivanova@example.com, papa@example.com\"\n;\n")))
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"иванов и.и.": {"ivanov@example.com", "ivanova@example.com", "papa@example.com"}}, emails)
+}
+
+func TestParseSettingsCSV_ShortFile(t *testing.T) {
+	_, err := ParseSettingsCSV(writeTemp(t, "short.csv", []byte("Параметр;Значение\n")))
+
+	var mp *MissingParamsError
+	require.ErrorAs(t, err, &mp)
+}
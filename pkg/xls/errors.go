@@ -3,6 +3,7 @@ package xls
 import (
 	"fmt"
 	"li-acc/internal/errs"
+	"strings"
 )
 
 // ----- User-facing errors (validation / missing data) -----
@@ -61,6 +62,106 @@ func (e *MissingPayersSheetColumns) Unwrap() error {
 	return nil
 }
 
+// MissingColumnsError error raised when the header row of the sheet is found, but some required columns are absent.
+type MissingColumnsError struct {
+	Sheet   string   // name of the sheet that contains error
+	Missing []string // canonical headers of the missing columns
+}
+
+func (e *MissingColumnsError) Error() string {
+	return fmt.Sprintf("sheet %s missing required columns: %v", e.Sheet, e.Missing)
+}
+
+func (e *MissingColumnsError) Kind() errs.Kind {
+	return errs.User
+}
+
+func (e *MissingColumnsError) Unwrap() error {
+	return nil
+}
+
+// InvalidRowsError error raised when some rows of the sheet contain invalid values.
+// It collects all problems of the sheet, so the user can fix the whole file at once.
+type InvalidRowsError struct {
+	Sheet    string       // name of the sheet that contains error
+	Problems []RowProblem // all found problems, in order of rows
+}
+
+func (e *InvalidRowsError) Error() string {
+	var msg []string
+	for _, p := range e.Problems {
+		msg = append(msg, p.String())
+	}
+	return fmt.Sprintf("sheet %s contains invalid values: [%s]", e.Sheet, strings.Join(msg, "; "))
+}
+
+func (e *InvalidRowsError) Kind() errs.Kind {
+	return errs.User
+}
+
+func (e *InvalidRowsError) Unwrap() error {
+	return nil
+}
+
+// InvalidParamsError error raised when some parameters of the settings sheet have invalid values
+// (wrong format or checksum mismatch of bank requisites).
+type InvalidParamsError struct {
+	Sheet    string         // name of the sheet that contains error
+	Problems []ParamProblem // all found problems
+}
+
+func (e *InvalidParamsError) Error() string {
+	var msg []string
+	for _, p := range e.Problems {
+		msg = append(msg, p.String())
+	}
+	return fmt.Sprintf("sheet %s contains invalid parameters: [%s]", e.Sheet, strings.Join(msg, "; "))
+}
+
+func (e *InvalidParamsError) Kind() errs.Kind {
+	return errs.User
+}
+
+func (e *InvalidParamsError) Unwrap() error {
+	return nil
+}
+
+// UnreadableWorkbookError error raised when the table file of the detected format can not be read:
+// it is password protected, saved in a too old format or corrupted. Err is one of the biff, ods or encoding/csv errors.
+type UnreadableWorkbookError struct {
+	Format Format // detected format of the file
+	Err    error
+}
+
+func (e *UnreadableWorkbookError) Error() string {
+	return fmt.Sprintf("failed to read %s file: %v", e.Format, e.Err)
+}
+
+func (e *UnreadableWorkbookError) Kind() errs.Kind {
+	return errs.User
+}
+
+func (e *UnreadableWorkbookError) Unwrap() error {
+	return e.Err
+}
+
+// UnsupportedFormatError error raised when the uploaded file is not a table of any supported format.
+type UnsupportedFormatError struct {
+	Format Format // detected format of the file
+}
+
+func (e *UnsupportedFormatError) Error() string {
+	return fmt.Sprintf("unsupported file format: %s", e.Format)
+}
+
+func (e *UnsupportedFormatError) Kind() errs.Kind {
+	return errs.User
+}
+
+func (e *UnsupportedFormatError) Unwrap() error {
+	return nil
+}
+
 // ----- Emails sheet incomplete rows -----
 
 type MissingEmailsError struct {
@@ -0,0 +1,205 @@
+package xls
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"li-acc/internal/errs"
+	"li-acc/pkg/xls/biff"
+	"li-acc/pkg/xls/ods"
+	"os"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Format is the format of the uploaded table file, detected by its content.
+type Format int
+
+const (
+	// FormatUnknown is a file that is not a table (e.g. an image or a PDF document)
+	FormatUnknown Format = iota
+	// FormatXLSX is the Office Open XML workbook (.xlsx, .xlsm)
+	FormatXLSX
+	// FormatXLS is the legacy Excel 97-2003 workbook (.xls)
+	FormatXLS
+	// FormatODS is the OpenDocument spreadsheet of LibreOffice (.ods)
+	FormatODS
+	// FormatCSV is the plain text table with delimiter separated values (.csv)
+	FormatCSV
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatXLSX:
+		return "xlsx"
+	case FormatXLS:
+		return "xls"
+	case FormatODS:
+		return "ods"
+	case FormatCSV:
+		return "csv"
+	default:
+		return "unknown"
+	}
+}
+
+// sniffSize is the number of the first bytes of the file used to tell the text file from the binary one
+const sniffSize = 4096
+
+// DetectFormat detects the format of the table file by its content, so the file extension does not matter:
+// OLE compound file is the legacy workbook, zip archive is either Office Open XML workbook or OpenDocument spreadsheet,
+// text without control characters (UTF-8 or Windows-1251) is CSV. Other files are FormatUnknown.
+func DetectFormat(filepath string) (Format, error) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return FormatUnknown, errs.WrapIOError("open file", filepath, err)
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return FormatUnknown, errs.WrapIOError("read file", filepath, err)
+	}
+	head = head[:n]
+
+	switch {
+	case biff.IsCompoundFile(head):
+		return FormatXLS, nil
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return detectZipFormat(f)
+	case isText(head):
+		return FormatCSV, nil
+	default:
+		return FormatUnknown, nil
+	}
+}
+
+// detectZipFormat tells the OpenDocument spreadsheet from the Office Open XML workbook
+func detectZipFormat(f *os.File) (Format, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return FormatUnknown, errs.WrapIOError("stat file", f.Name(), err)
+	}
+
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return FormatUnknown, nil // broken or not a zip archive at all
+	}
+	if ods.IsSpreadsheet(zr) {
+		return FormatODS, nil
+	}
+	for _, zf := range zr.File {
+		if zf.Name == "[Content_Types].xml" {
+			return FormatXLSX, nil
+		}
+	}
+	return FormatUnknown, nil
+}
+
+// isText reports whether the data looks like a text: it has no NUL and other control characters
+// except tabs and line breaks. Both UTF-8 and single byte encodings pass the check.
+func isText(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	for _, b := range data {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// openSpreadsheet opens the workbook of any supported spreadsheet format. Office Open XML workbooks (.xlsx, .xlsm)
+// are opened with excelize directly. Legacy Excel 97-2003 workbooks (.xls) and OpenDocument spreadsheets (.ods)
+// are read with biff and ods packages and converted into an in-memory excelize.File,
+// so all parsers work with them the same way. The format is detected by the file content, not by its extension.
+// CSV files have no sheets and can not be opened as a workbook, see ParsePayersCSV and others.
+func openSpreadsheet(filepath string) (*excelize.File, error) {
+	format, err := DetectFormat(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatXLS:
+		wb, err := biff.Open(filepath)
+		switch {
+		case err == nil:
+			sheets := make([]sheetData, 0, len(wb.Sheets))
+			for _, s := range wb.Sheets {
+				sheets = append(sheets, sheetData{name: s.Name, rows: s.Rows})
+			}
+			return toExcelize(sheets)
+		case errors.Is(err, biff.ErrNoWorkbookStream):
+			// not a legacy workbook, but some other compound file (e.g. password protected .xlsx): let excelize decide
+		default:
+			return nil, &UnreadableWorkbookError{Format: format, Err: err}
+		}
+
+	case FormatODS:
+		wb, err := ods.Open(filepath)
+		if err != nil {
+			return nil, &UnreadableWorkbookError{Format: format, Err: err}
+		}
+		sheets := make([]sheetData, 0, len(wb.Sheets))
+		for _, s := range wb.Sheets {
+			sheets = append(sheets, sheetData{name: s.Name, rows: s.Rows})
+		}
+		return toExcelize(sheets)
+
+	case FormatCSV, FormatUnknown:
+		return nil, &UnsupportedFormatError{Format: format}
+	}
+
+	spreadsheet, err := excelize.OpenFile(filepath)
+	if err != nil {
+		return nil, errs.WrapIOError("open spreadsheet", filepath, err)
+	}
+	return spreadsheet, nil
+}
+
+// sheetData is a sheet read by one of the non-excelize readers
+type sheetData struct {
+	name string
+	rows [][]string
+}
+
+// toExcelize copies cell values of all sheets into a new in-memory excelize.File.
+// All values are stored as strings, exactly as the readers return them.
+func toExcelize(sheets []sheetData) (*excelize.File, error) {
+	f := excelize.NewFile()
+	defaultSheet := f.GetSheetName(0)
+
+	for i, sheet := range sheets {
+		if i == 0 {
+			if err := f.SetSheetName(defaultSheet, sheet.name); err != nil {
+				f.Close()
+				return nil, errs.Wrap(errs.System, "failed to convert workbook", err)
+			}
+		} else if _, err := f.NewSheet(sheet.name); err != nil {
+			f.Close()
+			return nil, errs.Wrap(errs.System, "failed to convert workbook", err)
+		}
+
+		for r, row := range sheet.rows {
+			for c, value := range row {
+				if value == "" {
+					continue
+				}
+				cell, err := excelize.CoordinatesToCellName(c+1, r+1)
+				if err == nil {
+					err = f.SetCellStr(sheet.name, cell, value)
+				}
+				if err != nil {
+					f.Close()
+					return nil, errs.Wrap(errs.System, "failed to convert workbook", err)
+				}
+			}
+		}
+	}
+
+	return f, nil
+}
@@ -0,0 +1,74 @@
+package xls
+
+import (
+	"archive/zip"
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+
+	"li-acc/pkg/xls/biff"
+	"li-acc/pkg/xls/ods"
+)
+
+// zipArchive builds the zip archive with the given files
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestDetectFormat(t *testing.T) {
+	xlsx := excelize.NewFile()
+	xlsxData, err := xlsx.WriteToBuffer()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		data []byte
+		want Format
+	}{
+		{name: "xlsx", data: xlsxData.Bytes(), want: FormatXLSX},
+		{name: "xls", data: append(append([]byte{}, biff.Signature...), make([]byte, 512)...), want: FormatXLS},
+		{name: "ods", data: zipArchive(t, map[string]string{"mimetype": ods.MimeType}), want: FormatODS},
+		{name: "other zip", data: zipArchive(t, map[string]string{"readme.txt": "hello"}), want: FormatUnknown},
+		{name: "utf-8 csv", data: []byte("ФИО;Почта\r\nИванов И.И.;ivanov@example.com\r\n"), want: FormatCSV},
+		{name: "windows-1251 csv", data: []byte{0xD4, 0xC8, 0xCE, ';', 'a', '\n'}, want: FormatCSV},
+		{name: "binary", data: []byte("%PDF-1.7\n\x00\x01\x02"), want: FormatUnknown},
+		{name: "empty", data: nil, want: FormatUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the extension does not match the content on purpose: only the content matters
+			format, err := DetectFormat(writeTemp(t, "upload.xlsx", tt.data))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, format)
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := DetectFormat(filepath.Join(t.TempDir(), "missing.xlsx"))
+		require.Error(t, err)
+	})
+}
+
+func TestOpenAndCheckSheets_UnsupportedFormat(t *testing.T) {
+	for _, data := range [][]byte{[]byte("a;b\n"), []byte("\x89PNG\r\n\x1a\n\x00")} {
+		_, err := OpenAndCheckSheets(writeTemp(t, "upload.xls", data), PayersSheet)
+
+		var uf *UnsupportedFormatError
+		require.ErrorAs(t, err, &uf)
+	}
+}
@@ -3,8 +3,11 @@
 package integration
 
 import (
+	"li-acc/internal/errs"
 	"li-acc/pkg/model"
 	"li-acc/pkg/xls"
+	"li-acc/pkg/xls/biff"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -21,12 +24,12 @@ func TestParseSettings_Integration(t *testing.T) {
 		require.NoError(t, err)
 
 		require.Equal(t, "Школа АБВ (12377№7_) ТЕСТ ТЕСТ", org.Name)
-		require.Equal(t, "7777777777777777777777", org.PersonalAcc)
+		require.Equal(t, "03234643927010001100", org.PersonalAcc)
 		require.Equal(t, "ОТДЕЛЕНИЕ-НБ РЕСПУБЛИКА ТАТАРСТАН БАНКА ТЕСТ", org.BankName)
-		require.Equal(t, "0987098980", org.BIC)
-		require.Equal(t, "7777777777777777777777", org.CorrespAcc)
-		require.Equal(t, "9959992999", org.PayeeINN)
-		require.Equal(t, "3333333333", org.KPP)
+		require.Equal(t, "019205400", org.BIC)
+		require.Equal(t, "40102810445370000079", org.CorrespAcc)
+		require.Equal(t, "1659012345", org.PayeeINN)
+		require.Equal(t, "165901001", org.KPP)
 		require.Equal(t, "CATEGORY=4", org.ExtraParams)
 	})
 
@@ -64,18 +67,18 @@ func TestParsePayers_Integration(t *testing.T) {
 			PersAcc:  "123",
 			CHILDFIO: "Иванов Иван",
 			Purpose:  "Оплата",
-			CBC:      "111",
+			CBC:      "82100000000000000111",
 			OKTMO:    "22222222",
-			Sum:      "100.50", // уже готовая сумма
+			Sum:      model.NewMoney(100, 50), // уже готовая сумма
 		}, payers[0])
 
 		require.Equal(t, model.Payer{
 			PersAcc:  "456",
 			CHILDFIO: "Петров Петр",
 			Purpose:  "Оплата",
-			CBC:      "333",
-			OKTMO:    "444",
-			Sum:      "200.00", // в таблице просто 200
+			CBC:      "82100000000000000333",
+			OKTMO:    "92701444",
+			Sum:      model.NewMoney(200, 0), // в таблице просто 200
 		}, payers[1])
 	})
 
@@ -91,15 +94,15 @@ func TestParseEmail_Integration(t *testing.T) {
 	tests := []struct {
 		name         string
 		filename     string
-		expectEmails map[string]string
+		expectEmails map[string][]string
 		expectError  bool
 	}{
 		{
 			name:     "valid emails file",
 			filename: "emails_valid.xlsx",
-			expectEmails: map[string]string{
-				"Иванов Иван": "ivanov@example.com",
-				"Петров Петр": "petrov@example.com",
+			expectEmails: map[string][]string{
+				"Иванов Иван": {"ivanov@example.com"},
+				"Петров Петр": {"petrov@example.com"},
 			},
 		},
 		{
@@ -123,12 +126,110 @@ func TestParseEmail_Integration(t *testing.T) {
 
 			} else {
 				require.NoError(t, err)
-				want := make(map[string]string)
-				for name, email := range tt.expectEmails {
-					want[strings.ToLower(name)] = email
+				want := make(map[string][]string)
+				for name, emails := range tt.expectEmails {
+					want[strings.ToLower(name)] = emails
 				}
 				require.Equal(t, want, emails)
 			}
 		})
 	}
 }
+
+func TestParseLegacyWorkbook_Integration(t *testing.T) {
+	legacyPath := filepath.Join("testdata", "legacy_valid.xls")
+
+	t.Run("settings", func(t *testing.T) {
+		want, err := xls.ParseSettings(filepath.Join("testdata", "settings_valid.xlsm"))
+		require.NoError(t, err)
+
+		org, err := xls.ParseSettings(legacyPath)
+		require.NoError(t, err)
+		require.Equal(t, want, org)
+	})
+
+	t.Run("payers", func(t *testing.T) {
+		want, err := xls.ParsePayers(filepath.Join("testdata", "payers_valid.xlsm"))
+		require.NoError(t, err)
+
+		payers, err := xls.ParsePayers(legacyPath)
+		require.NoError(t, err)
+		require.Equal(t, want, payers)
+	})
+
+	t.Run("emails", func(t *testing.T) {
+		want, err := xls.ParseEmail(filepath.Join("testdata", "emails_valid.xlsx"))
+		require.NoError(t, err)
+
+		emails, err := xls.ParseEmail(legacyPath)
+		require.NoError(t, err)
+		require.Equal(t, want, emails)
+	})
+
+	t.Run("corrupted file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "corrupted.xls")
+		data := append(append([]byte{}, biff.Signature...), make([]byte, 100)...)
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+
+		payers, err := xls.ParsePayers(path)
+		require.Nil(t, payers)
+
+		var uw *xls.UnreadableWorkbookError
+		require.ErrorAs(t, err, &uw)
+		require.Equal(t, xls.FormatXLS, uw.Format)
+		require.ErrorIs(t, err, biff.ErrCorrupted)
+		require.True(t, errs.IsUserError(err))
+	})
+}
+
+func TestParseODSAndCSV_Integration(t *testing.T) {
+	wantOrg, err := xls.ParseSettings(filepath.Join("testdata", "settings_valid.xlsm"))
+	require.NoError(t, err)
+	wantPayers, err := xls.ParsePayers(filepath.Join("testdata", "payers_valid.xlsm"))
+	require.NoError(t, err)
+	wantEmails, err := xls.ParseEmail(filepath.Join("testdata", "emails_valid.xlsx"))
+	require.NoError(t, err)
+
+	t.Run("ods", func(t *testing.T) {
+		path := filepath.Join("testdata", "table_valid.ods")
+
+		org, err := xls.ParseSettings(path)
+		require.NoError(t, err)
+		require.Equal(t, wantOrg, org)
+
+		payers, err := xls.ParsePayers(path)
+		require.NoError(t, err)
+		require.Equal(t, wantPayers, payers)
+
+		emails, err := xls.ParseEmail(path)
+		require.NoError(t, err)
+		require.Equal(t, wantEmails, emails)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		// Windows-1251 registry with settings lines on the top and the payers table below them
+		registry := filepath.Join("testdata", "registry_valid.csv")
+
+		org, err := xls.ParseSettingsCSV(registry)
+		require.NoError(t, err)
+		require.Equal(t, wantOrg, org)
+
+		payers, err := xls.ParsePayersCSV(registry)
+		require.NoError(t, err)
+		require.Equal(t, wantPayers, payers)
+
+		// UTF-8 with BOM and comma delimiter
+		emails, err := xls.ParseEmailCSV(filepath.Join("testdata", "emails_valid.csv"))
+		require.NoError(t, err)
+		require.Equal(t, wantEmails, emails)
+	})
+
+	t.Run("csv is not a workbook", func(t *testing.T) {
+		_, err := xls.ParsePayers(filepath.Join("testdata", "registry_valid.csv"))
+
+		var uf *xls.UnsupportedFormatError
+		require.ErrorAs(t, err, &uf)
+		require.Equal(t, xls.FormatCSV, uf.Format)
+		require.True(t, errs.IsUserError(err))
+	})
+}
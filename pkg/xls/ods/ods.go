@@ -0,0 +1,353 @@
+// Package ods reads cell values from OpenDocument spreadsheets (.ods files of LibreOffice and OpenOffice).
+// Only values are read: styles and formulas are ignored. Numeric cells return their exact value (e.g. "1200.5"),
+// not the text formatted for display; other cells return the text as it is shown.
+package ods
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MimeType is the content of the "mimetype" file of the OpenDocument spreadsheet archive
+const MimeType = "application/vnd.oasis.opendocument.spreadsheet"
+
+var (
+	// ErrNotSpreadsheet is returned when the zip archive is not an OpenDocument spreadsheet
+	ErrNotSpreadsheet = errors.New("not an OpenDocument spreadsheet")
+
+	// ErrEncrypted is returned when the document is protected with a password
+	ErrEncrypted = errors.New("document is password protected")
+
+	// ErrCorrupted is returned when the document structure can not be read
+	ErrCorrupted = errors.New("corrupted document")
+)
+
+// MaxRows and MaxColumns limit the sheet size. Trailing empty rows and columns are not counted,
+// but repeated non-empty ones are expanded, so a broken file must not make us allocate unlimited memory.
+const (
+	MaxRows    = 1 << 20
+	MaxColumns = 1 << 14
+)
+
+// Workbook contains all sheets of the spreadsheet in the order they appear in the document.
+type Workbook struct {
+	Sheets []Sheet
+}
+
+// Sheet is a table with cell values.
+// Rows[i][j] is the value of the cell in the row i+1 and column j+1; trailing empty cells and rows are trimmed.
+type Sheet struct {
+	Name string
+	Rows [][]string
+}
+
+// IsSpreadsheet reports whether the zip archive is an OpenDocument spreadsheet, checking its "mimetype" file.
+func IsSpreadsheet(zr *zip.Reader) bool {
+	for _, f := range zr.File {
+		if f.Name != "mimetype" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return false
+		}
+		defer rc.Close()
+
+		mime, err := io.ReadAll(io.LimitReader(rc, 256))
+		return err == nil && strings.TrimSpace(string(mime)) == MimeType
+	}
+	return false
+}
+
+// Open reads the spreadsheet from the file at path.
+func Open(path string) (*Workbook, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+	defer zr.Close()
+
+	return Read(&zr.Reader)
+}
+
+// Read reads the spreadsheet from the opened zip archive.
+func Read(zr *zip.Reader) (*Workbook, error) {
+	if !IsSpreadsheet(zr) {
+		return nil, ErrNotSpreadsheet
+	}
+
+	var content *zip.File
+	for _, f := range zr.File {
+		switch f.Name {
+		case "content.xml":
+			content = f
+		case "META-INF/manifest.xml":
+			encrypted, err := isEncrypted(f)
+			if err != nil {
+				return nil, err
+			}
+			if encrypted {
+				return nil, ErrEncrypted
+			}
+		}
+	}
+	if content == nil {
+		return nil, fmt.Errorf("%w: no content.xml", ErrCorrupted)
+	}
+
+	rc, err := content.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+	defer rc.Close()
+
+	wb, err := parseContent(xml.NewDecoder(rc))
+	if err != nil {
+		return nil, err
+	}
+	return wb, nil
+}
+
+// isEncrypted reports whether the manifest describes encryption of the document files
+func isEncrypted(f *zip.File) (bool, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+	defer rc.Close()
+
+	manifest, err := io.ReadAll(rc)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+	return bytes.Contains(manifest, []byte("encryption-data")), nil
+}
+
+// XML namespaces of the OpenDocument content
+const (
+	nsTable  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	nsOffice = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	nsText   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+)
+
+// sheetBuilder accumulates rows of the sheet. Empty rows and cells are kept pending, and added only when
+// a non-empty row or cell follows, so trailing empty ranges (often repeated up to the sheet end) take no memory.
+type sheetBuilder struct {
+	sheet       Sheet
+	pendingRows int
+
+	row          []string
+	pendingCells int
+}
+
+func (b *sheetBuilder) addCell(value string, repeat int) error {
+	if value == "" {
+		b.pendingCells += repeat
+		return nil
+	}
+	if len(b.row)+b.pendingCells+repeat > MaxColumns {
+		return fmt.Errorf("%w: too many columns on sheet %q", ErrCorrupted, b.sheet.Name)
+	}
+
+	for ; b.pendingCells > 0; b.pendingCells-- {
+		b.row = append(b.row, "")
+	}
+	for i := 0; i < repeat; i++ {
+		b.row = append(b.row, value)
+	}
+	return nil
+}
+
+func (b *sheetBuilder) endRow(repeat int) error {
+	row := b.row
+	b.row, b.pendingCells = nil, 0
+
+	if len(row) == 0 {
+		b.pendingRows += repeat
+		return nil
+	}
+	if len(b.sheet.Rows)+b.pendingRows+repeat > MaxRows {
+		return fmt.Errorf("%w: too many rows on sheet %q", ErrCorrupted, b.sheet.Name)
+	}
+
+	for ; b.pendingRows > 0; b.pendingRows-- {
+		b.sheet.Rows = append(b.sheet.Rows, nil)
+	}
+	for i := 0; i < repeat; i++ {
+		b.sheet.Rows = append(b.sheet.Rows, row)
+	}
+	return nil
+}
+
+// parseContent reads tables of the content.xml
+func parseContent(d *xml.Decoder) (*Workbook, error) {
+	wb := &Workbook{}
+
+	var (
+		sheet     *sheetBuilder
+		rowRepeat int
+	)
+
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space != nsTable {
+				continue
+			}
+
+			switch t.Name.Local {
+			case "table":
+				sheet = &sheetBuilder{sheet: Sheet{Name: attr(t, nsTable, "name")}}
+			case "table-row":
+				rowRepeat = repeatAttr(t, "number-rows-repeated")
+			case "table-cell", "covered-table-cell":
+				if sheet == nil {
+					continue
+				}
+				value, err := cellValue(d, t)
+				if err != nil {
+					return nil, err
+				}
+				if err := sheet.addCell(value, repeatAttr(t, "number-columns-repeated")); err != nil {
+					return nil, err
+				}
+			}
+
+		case xml.EndElement:
+			if t.Name.Space != nsTable || sheet == nil {
+				continue
+			}
+
+			switch t.Name.Local {
+			case "table-row":
+				if err := sheet.endRow(rowRepeat); err != nil {
+					return nil, err
+				}
+			case "table":
+				wb.Sheets = append(wb.Sheets, sheet.sheet)
+				sheet = nil
+			}
+		}
+	}
+
+	return wb, nil
+}
+
+// cellValue reads the cell element started with `start` up to its end and returns the cell value.
+func cellValue(d *xml.Decoder, start xml.StartElement) (string, error) {
+	var value string
+	switch attr(start, nsOffice, "value-type") {
+	case "float", "currency", "percentage":
+		value = attr(start, nsOffice, "value")
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			value = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	case "boolean":
+		value = strings.ToUpper(attr(start, nsOffice, "boolean-value"))
+	}
+
+	text, err := cellText(d)
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		value = text
+	}
+	return value, nil
+}
+
+// cellText reads the text paragraphs of the cell up to the cell end. Paragraphs are joined with new lines,
+// <text:s/> elements are replaced with spaces, <text:tab/> and <text:line-break/> with tab and new line.
+// Text outside paragraphs (e.g. formatting whitespace of XML) and comments are ignored.
+func cellText(d *xml.Decoder) (string, error) {
+	var (
+		sb         strings.Builder
+		paragraphs int
+		depth      = 1 // depth of elements inside the cell
+		paragraph  = 0 // depth of the current paragraph element, 0 if outside a paragraph
+	)
+
+	for depth > 0 {
+		tok, err := d.Token()
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrCorrupted, err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space == nsOffice && t.Name.Local == "annotation" {
+				if err := d.Skip(); err != nil {
+					return "", fmt.Errorf("%w: %v", ErrCorrupted, err)
+				}
+				continue
+			}
+			depth++
+			if t.Name.Space != nsText {
+				continue
+			}
+
+			switch t.Name.Local {
+			case "p":
+				if paragraph == 0 {
+					if paragraphs > 0 {
+						sb.WriteByte('\n')
+					}
+					paragraphs++
+					paragraph = depth
+				}
+			case "s":
+				n := 1
+				if c, err := strconv.Atoi(attr(t, nsText, "c")); err == nil && c > 0 {
+					n = min(c, MaxColumns)
+				}
+				sb.WriteString(strings.Repeat(" ", n))
+			case "tab":
+				sb.WriteByte('\t')
+			case "line-break":
+				sb.WriteByte('\n')
+			}
+		case xml.EndElement:
+			if depth == paragraph {
+				paragraph = 0
+			}
+			depth--
+		case xml.CharData:
+			if paragraph > 0 {
+				sb.Write(t)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+func attr(el xml.StartElement, space, local string) string {
+	for _, a := range el.Attr {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// repeatAttr returns the value of the table repeat attribute, 1 if it is absent or invalid
+func repeatAttr(el xml.StartElement, local string) int {
+	n, err := strconv.Atoi(attr(el, nsTable, local))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
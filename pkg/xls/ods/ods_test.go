@@ -0,0 +1,157 @@
+package ods
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// document builds the zip archive of the OpenDocument spreadsheet with `tables` inside the content body.
+// Files with empty content are not added.
+func document(t *testing.T, mime, manifest, tables string) *zip.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct{ name, content string }{
+		{"mimetype", mime},
+		{"META-INF/manifest.xml", manifest},
+		{"content.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content
+	xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:spreadsheet>` + tables + `</office:spreadsheet></office:body>
+</office:document-content>`},
+	}
+	for _, f := range files {
+		if f.content == "" {
+			continue
+		}
+		w, err := zw.Create(f.name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(f.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	return zr
+}
+
+const plainManifest = `<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0">
+<manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>
+</manifest:manifest>`
+
+func TestRead(t *testing.T) {
+	tables := `
+<table:table table:name="Настройки">
+	<table:table-row>
+		<table:table-cell office:value-type="string"><text:p>Наименование</text:p></table:table-cell>
+		<table:table-cell office:value-type="string"><text:p>ООО <text:span>Ромашка</text:span></text:p></table:table-cell>
+	</table:table-row>
+	<table:table-row table:number-rows-repeated="2"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+	<table:table-row>
+		<table:table-cell table:number-columns-repeated="2"/>
+		<table:table-cell office:value-type="float" office:value="1200.5"><text:p>1 200,50</text:p></table:table-cell>
+	</table:table-row>
+	<table:table-row table:number-rows-repeated="1048570"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+</table:table>
+<table:table table:name="Данные">
+	<table:table-row>
+		<table:table-cell office:value-type="currency" office:value="99.90"><text:p>99,90 ₽</text:p></table:table-cell>
+		<table:table-cell office:value-type="percentage" office:value="0.15"><text:p>15%</text:p></table:table-cell>
+		<table:table-cell office:value-type="boolean" office:boolean-value="true"><text:p>ИСТИНА</text:p></table:table-cell>
+		<table:table-cell table:number-columns-repeated="2" office:value-type="string"><text:p>x</text:p></table:table-cell>
+	</table:table-row>
+	<table:table-row>
+		<table:table-cell office:value-type="string">
+			<office:annotation><text:p>comment</text:p></office:annotation>
+			<text:p>first<text:s text:c="3"/>line<text:tab/>tab</text:p>
+			<text:p>second<text:line-break/>third</text:p>
+		</table:table-cell>
+		<table:covered-table-cell/>
+	</table:table-row>
+</table:table>`
+
+	wb, err := Read(document(t, MimeType, plainManifest, tables))
+	require.NoError(t, err)
+	require.Equal(t, []Sheet{
+		{
+			Name: "Настройки",
+			Rows: [][]string{
+				{"Наименование", "ООО Ромашка"},
+				nil,
+				nil,
+				{"", "", "1200.5"},
+			},
+		},
+		{
+			Name: "Данные",
+			Rows: [][]string{
+				{"99.9", "0.15", "TRUE", "x", "x"},
+				{"first   line\ttab\nsecond\nthird"},
+			},
+		},
+	}, wb.Sheets)
+}
+
+func TestRead_Errors(t *testing.T) {
+	encryptedManifest := `<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0">
+<manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml">
+<manifest:encryption-data manifest:checksum-type="SHA1/1K"/>
+</manifest:file-entry>
+</manifest:manifest>`
+
+	tooManyRows := `<table:table table:name="s">
+<table:table-row table:number-rows-repeated="2000000"><table:table-cell office:value-type="string"><text:p>x</text:p></table:table-cell></table:table-row>
+</table:table>`
+
+	tests := []struct {
+		name    string
+		zr      *zip.Reader
+		wantErr error
+	}{
+		{
+			name:    "text document",
+			zr:      document(t, "application/vnd.oasis.opendocument.text", plainManifest, ""),
+			wantErr: ErrNotSpreadsheet,
+		},
+		{
+			name:    "no mimetype",
+			zr:      document(t, "", plainManifest, ""),
+			wantErr: ErrNotSpreadsheet,
+		},
+		{
+			name:    "encrypted",
+			zr:      document(t, MimeType, encryptedManifest, ""),
+			wantErr: ErrEncrypted,
+		},
+		{
+			name:    "broken xml",
+			zr:      document(t, MimeType, plainManifest, `<table:table table:name="s"><table:table-row>`),
+			wantErr: ErrCorrupted,
+		},
+		{
+			name:    "too many rows",
+			zr:      document(t, MimeType, plainManifest, tooManyRows),
+			wantErr: ErrCorrupted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(tt.zr)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestIsSpreadsheet(t *testing.T) {
+	require.True(t, IsSpreadsheet(document(t, MimeType+"\n", plainManifest, "")))
+	require.False(t, IsSpreadsheet(document(t, "application/vnd.oasis.opendocument.text", plainManifest, "")))
+	require.False(t, IsSpreadsheet(document(t, "", plainManifest, "")))
+}
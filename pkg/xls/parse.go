@@ -19,9 +19,21 @@ const (
 	EmailsSheet   = "emails"
 )
 
+// names of the parameters on the settings sheet
+const (
+	ParamName        = "Наименование организации"
+	ParamPersonalAcc = "Расчетный счет"
+	ParamBankName    = "Наименование банка"
+	ParamBIC         = "БИК"
+	ParamCorrespAcc  = "Корреспондентский счет"
+	ParamINN         = "ИНН"
+	ParamKPP         = "КПП"
+	ParamExtraParams = "Дополнительные параметры ДШК"
+)
+
 // rows that are data borders on pages
 const (
-	// PayersRowStart from which row in the table need to start parsing
+	// PayersRowStart from which row in the table need to start parsing, if the sheet has no header row (legacy layout)
 	PayersRowStart = 7
 
 	// SettingsRowStart and SettingsRowEnd parameters are in the range of rows from 2 to 12 (A2:B12)
@@ -33,12 +45,13 @@ const (
 )
 
 // OpenAndCheckSheets gets filename of the xls file needed to open and the sheet name of the sheet, that's presence
-// needs to be checked.
+// needs to be checked. Office Open XML (.xlsx, .xlsm), legacy Excel 97-2003 (.xls) workbooks
+// and OpenDocument spreadsheets (.ods) are supported.
 // Returns the excelize.File object to work with the loaded file, if no error occur.
 func OpenAndCheckSheets(filepath, sheetName string) (*excelize.File, error) {
-	spreadsheet, err := excelize.OpenFile(filepath) // load xls file
+	spreadsheet, err := openSpreadsheet(filepath) // load xls file
 	if err != nil {
-		return nil, errs.WrapIOError("open spreadsheet", filepath, err)
+		return nil, err
 	}
 
 	// Check if the needed sheet is in the sheets list
@@ -51,9 +64,11 @@ func OpenAndCheckSheets(filepath, sheetName string) (*excelize.File, error) {
 }
 
 // get returns all rows of the specified Excel file sheet as [][]string.
+// Cell values are taken as stored, without the number format of the cell: a sum formatted as "#,##0"
+// is read as "1200", not as "1,200", that can not be told from 1.20 rubles.
 // In case of a reading error (e.g., sheet not found), returns the system error errs.System.
 func get(ss *excelize.File, sheet string) ([][]string, error) {
-	rows, err := ss.GetRows(sheet)
+	rows, err := ss.GetRows(sheet, excelize.Options{RawCellValue: true})
 	if err != nil {
 		return nil, errs.Wrap(errs.System, "failed to get rows from excel", err)
 	}
@@ -62,6 +77,7 @@ func get(ss *excelize.File, sheet string) ([][]string, error) {
 
 // ParseSettingsFromFile parses all needed parameters from the given sheet of settings in the given range (SettingsRowStart, SettingsRowEnd).
 // Parameters contain receiver information needed to perform payments.
+// Bank and tax requisites are validated with their checksums, all invalid values are returned together as InvalidParamsError.
 func ParseSettingsFromFile(ss *excelize.File, sheet string) (*model.Organization, error) {
 	// Get all rows of the sheet as 2d array of strings
 	rows, err := get(ss, sheet)
@@ -73,7 +89,14 @@ func ParseSettingsFromFile(ss *excelize.File, sheet string) (*model.Organization
 	// param_name is in cells in column A, and param_value in column B.
 	params := make(map[string]string)
 
-	for _, row := range rows[SettingsRowStart-1 : SettingsRowEnd] { // A2:A12 and B2:B12
+	// the sheet may end before SettingsRowEnd, e.g. if it is a short CSV file
+	if len(rows) < SettingsRowStart {
+		rows = nil
+	} else {
+		rows = rows[SettingsRowStart-1 : min(SettingsRowEnd, len(rows))]
+	}
+
+	for _, row := range rows { // A2:A12 and B2:B12
 		if len(row) < 2 { // if there are missing either param_name or param_value, just skip row
 			continue
 		}
@@ -88,14 +111,14 @@ func ParseSettingsFromFile(ss *excelize.File, sheet string) (*model.Organization
 
 	// requiredKeys contain parameters that must be present in the sheet
 	requiredKeys := []string{
-		"Наименование организации",
-		"Расчетный счет",
-		"Наименование банка",
-		"БИК",
-		"Корреспондентский счет",
-		"ИНН",
-		"КПП",
-		"Дополнительные параметры ДШК",
+		ParamName,
+		ParamPersonalAcc,
+		ParamBankName,
+		ParamBIC,
+		ParamCorrespAcc,
+		ParamINN,
+		ParamKPP,
+		ParamExtraParams,
 	}
 
 	// check if all required parameters exist in parsed params
@@ -111,14 +134,19 @@ func ParseSettingsFromFile(ss *excelize.File, sheet string) (*model.Organization
 	}
 
 	orgData := model.Organization{
-		Name:        params["Наименование организации"],
-		PersonalAcc: params["Расчетный счет"],
-		BankName:    params["Наименование банка"],
-		BIC:         params["БИК"],
-		CorrespAcc:  params["Корреспондентский счет"],
-		PayeeINN:    params["ИНН"],
-		KPP:         params["КПП"],
-		ExtraParams: params["Дополнительные параметры ДШК"],
+		Name:        params[ParamName],
+		PersonalAcc: params[ParamPersonalAcc],
+		BankName:    params[ParamBankName],
+		BIC:         params[ParamBIC],
+		CorrespAcc:  params[ParamCorrespAcc],
+		PayeeINN:    params[ParamINN],
+		KPP:         params[ParamKPP],
+		ExtraParams: params[ParamExtraParams],
+	}
+
+	// check that bank requisites are real: one typo in the account makes all receipts unpayable
+	if problems := validateOrganization(orgData); len(problems) > 0 {
+		return nil, &InvalidParamsError{Sheet: sheet, Problems: problems}
 	}
 
 	return &orgData, nil
@@ -127,6 +155,19 @@ func ParseSettingsFromFile(ss *excelize.File, sheet string) (*model.Organization
 // ParseSettings opens Excel file and checks its validity calling OpenAndCheckSheets.
 // If no error, then calls ParseSettingsFromFile that performs parsing logic.
 func ParseSettings(filepath string) (*model.Organization, error) {
+	return parseSettings(filepath, OpenAndCheckSheets)
+}
+
+// ParseSettingsCSV parses organization parameters from the CSV file (see openCSV), laid out as the settings sheet:
+// parameter names in the first column and values in the second one, lines from SettingsRowStart to SettingsRowEnd.
+func ParseSettingsCSV(filepath string) (*model.Organization, error) {
+	return parseSettings(filepath, openCSV)
+}
+
+// workbookOpener opens the file as a workbook that contains the given sheet
+type workbookOpener func(filepath, sheet string) (*excelize.File, error)
+
+func parseSettings(filepath string, open workbookOpener) (*model.Organization, error) {
 	start := time.Now()
 
 	// error type string for metrics
@@ -144,7 +185,7 @@ func ParseSettings(filepath string) (*model.Organization, error) {
 	}()
 
 	sheet := SettingsSheet // this is current settings sheet that must be in the spreadsheet
-	ss, err := OpenAndCheckSheets(filepath, sheet)
+	ss, err := open(filepath, sheet)
 
 	// Check if the file is valid and open it
 	if err != nil {
@@ -156,15 +197,22 @@ func ParseSettings(filepath string) (*model.Organization, error) {
 	org, err := ParseSettingsFromFile(ss, sheet)
 
 	var mp *MissingParamsError
+	var ip *InvalidParamsError
 	if errors.As(err, &mp) {
 		errorType = "missed_params"
+	} else if errors.As(err, &ip) {
+		errorType = "invalid_params"
 	}
 
 	return org, err
 }
 
-// ParsePayersFromFile parses rows for each payer from the shee in the given range.
-// Parsing starts from the given row (PayersRowStart).
+// ParsePayersFromFile parses rows for each payer from the sheet.
+// Columns are mapped by the header row titles (see PayersRequiredHeaders and PayersHeaderAliases),
+// so their order on the sheet does not matter, and parsing starts from the row right after the header.
+// If the header row is recognized, but some required columns are missing, MissingColumnsError is returned.
+// If there is no header row at all, the legacy fixed layout is used starting from the row PayersRowStart.
+// Every row is validated (see payersValidator); all found problems are returned together as InvalidRowsError.
 // Rows contain payer and payment information as Name and Surname, bank number, payment amount, etc.
 func ParsePayersFromFile(ss *excelize.File, sheet string) ([]model.Payer, error) {
 	// Get all rows of the sheet as 2d array of strings
@@ -173,14 +221,38 @@ func ParsePayersFromFile(ss *excelize.File, sheet string) ([]model.Payer, error)
 		return nil, err
 	}
 
+	// columns maps canonical header to the column index, dataStart is the index of the first data row
+	headerIdx, columns := findPayersHeader(rows)
+	legacy := headerIdx == -1
+
+	var dataStart int
+	if legacy {
+		columns = legacyPayersColumns
+		dataStart = PayersRowStart - 1
+	} else {
+		if missing := missingHeaders(columns); len(missing) > 0 {
+			return nil, &MissingColumnsError{Sheet: sheet, Missing: missing}
+		}
+		dataStart = headerIdx + 1
+	}
+
 	// parsed rows stored here
 	var payers []model.Payer
 
-	for _, row := range rows[PayersRowStart-1:] {
+	if dataStart >= len(rows) {
+		return payers, nil
+	}
+
+	// validator accumulates problems of all rows, so they are reported together
+	validator := newPayersValidator()
+
+	for i, row := range rows[dataStart:] {
+		rowNum := dataStart + i + 1 // row number as it is shown in Excel
+
 		// if all cells in the row are empty, then ignore
 		allEmpty := true
 		for _, cell := range row {
-			if cell != "" {
+			if strings.TrimSpace(cell) != "" {
 				allEmpty = false
 				break
 			}
@@ -189,32 +261,54 @@ func ParsePayersFromFile(ss *excelize.File, sheet string) ([]model.Payer, error)
 			continue
 		}
 
-		// format the cell row[7] that is an amount (column H)
-		var amount string
-		if len(row) > 7 {
-			amount = normalizeAmount(row[7])
-		} else {
-			return nil, &MissingPayersSheetColumns{Want: 7, Have: len(row)}
+		// in the legacy layout the amount is the last column (H), so the row must reach it
+		if legacy && len(row) <= columns[HeaderSum] {
+			return nil, &MissingPayersSheetColumns{Want: columns[HeaderSum], Have: len(row)}
 		}
 
-		// a row with the payer data
+		// cell returns trimmed value of the column with the given header, empty if the row is shorter
+		cell := func(header string) string {
+			idx := columns[header]
+			if idx >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[idx])
+		}
+
+		// a row with the payer data; invalid amount is left zero and reported by the validator
+		rawAmount := cell(HeaderSum)
+		sum, _ := model.ParseMoney(rawAmount)
 		payer := model.Payer{
-			PersAcc:  strings.TrimSpace(row[0]),
-			CHILDFIO: strings.TrimSpace(row[1]),
-			Purpose:  strings.TrimSpace(row[2]),
-			CBC:      strings.TrimSpace(row[3]),
-			OKTMO:    strings.TrimSpace(row[4]),
-			Sum:      amount,
+			PersAcc:  cell(HeaderPersAcc),
+			CHILDFIO: cell(HeaderChildFIO),
+			Purpose:  cell(HeaderPurpose),
+			CBC:      cell(HeaderCBC),
+			OKTMO:    cell(HeaderOKTMO),
+			Sum:      sum,
 		}
 
+		validator.check(rowNum, payer, rawAmount)
 		payers = append(payers, payer)
 	}
+
+	if len(validator.problems) > 0 {
+		return nil, &InvalidRowsError{Sheet: sheet, Problems: validator.problems}
+	}
 	return payers, nil
 }
 
 // ParsePayers opens Excel file and checks its validity calling OpenAndCheckSheets.
 // If no error, then calls ParsePayersFromFile that performs parsing logic.
 func ParsePayers(filepath string) ([]model.Payer, error) {
+	return parsePayers(filepath, OpenAndCheckSheets)
+}
+
+// ParsePayersCSV parses payers from the CSV file (see openCSV), laid out as the payers sheet.
+func ParsePayersCSV(filepath string) ([]model.Payer, error) {
+	return parsePayers(filepath, openCSV)
+}
+
+func parsePayers(filepath string, open workbookOpener) ([]model.Payer, error) {
 	start := time.Now()
 
 	// error type string for metrics
@@ -232,7 +326,7 @@ func ParsePayers(filepath string) ([]model.Payer, error) {
 	}()
 
 	sheet := PayersSheet // this is current settings sheet that must be in the spreadsheet
-	ss, err := OpenAndCheckSheets(filepath, sheet)
+	ss, err := open(filepath, sheet)
 
 	if err != nil {
 		// Check if the file is valid and open it
@@ -243,8 +337,12 @@ func ParsePayers(filepath string) ([]model.Payer, error) {
 
 	payers, err := ParsePayersFromFile(ss, sheet)
 	var mc *MissingPayersSheetColumns
-	if errors.As(err, &mc) {
+	var mh *MissingColumnsError
+	var ir *InvalidRowsError
+	if errors.As(err, &mc) || errors.As(err, &mh) {
 		errorType = "missed_columns"
+	} else if errors.As(err, &ir) {
+		errorType = "invalid_rows"
 	}
 
 	metrics.PayersParsedCount.WithLabelValues("failure").Observe(float64(len(payers)))
@@ -253,57 +351,84 @@ func ParsePayers(filepath string) ([]model.Payer, error) {
 }
 
 // ParseEmailFromFile parses all emails and payers full names from sheet.
-// Each row contains two cells - Full Name and Email.
-func ParseEmailFromFile(ss *excelize.File, sheet string) (map[string]string, error) {
+// Each row contains payer's Full Name in the first cell and one or more emails in the next cells:
+// a cell may contain several emails separated with commas or semicolons (see EmailSeparators).
+// Every email is validated against RFC 5322 and every Full Name must appear on the sheet only once:
+// all invalid emails and duplicate names are returned together as InvalidRowsError.
+// Returned map has lower-cased Full Names as keys and lists of their unique emails as values.
+func ParseEmailFromFile(ss *excelize.File, sheet string) (map[string][]string, error) {
 	// Get all rows of the sheet as 2d array of strings
 	rows, err := get(ss, sheet)
 	if err != nil {
 		return nil, err
 	}
 
-	// emails map will store emails in the format `full_name: email`
-	emails := make(map[string]string)
+	// emails map will store emails in the format `full_name: [email1, email2, ...]`
+	emails := make(map[string][]string)
 
 	// missing will accumulate numbers of incomplete rows
 	var missing []int
 
+	if len(rows) < EmailsRowStart {
+		return emails, nil
+	}
+
+	v := newEmailsValidator()
 	for rowIdx, row := range rows[EmailsRowStart-1:] {
-		// skip empty rows completely
-		if len(row) == 0 || (strings.TrimSpace(row[0]) == "" && strings.TrimSpace(row[1]) == "") {
-			continue
-		}
+		rowNum := EmailsRowStart + rowIdx
 
-		var fio, email string
+		var fio string
 		if len(row) > 0 {
 			fio = strings.TrimSpace(row[0])
 		}
+		var addresses []string
 		if len(row) > 1 {
-			email = strings.TrimSpace(row[1])
+			addresses = splitEmails(row[1:])
+		}
+
+		// skip empty rows completely
+		if fio == "" && len(addresses) == 0 {
+			continue
 		}
 
 		// Check for missing FIO when email exists or vice versa
-		if fio == "" && email != "" || fio != "" && email == "" {
-			missing = append(missing, EmailsRowStart+rowIdx)
+		if fio == "" || len(addresses) == 0 {
+			missing = append(missing, rowNum)
 			continue
 		}
 
-		// if both fields are present → add to map
-		emails[strings.ToLower(fio)] = email
+		// if both fields are present and valid → add to map
+		if v.validate(rowNum, fio, addresses) {
+			emails[strings.ToLower(fio)] = addresses
+		}
 	}
 
 	// if there were missing required fields, return them as error
 	if len(missing) > 0 {
 		return nil, &MissingEmailsError{MissingLines: missing}
 	}
+	if len(v.problems) > 0 {
+		return nil, &InvalidRowsError{Sheet: sheet, Problems: v.problems}
+	}
 
 	return emails, nil
 }
 
 // ParseEmail opens Excel file and checks its validity calling OpenAndCheckSheets.
 // If no error, then calls ParseEmailsFromFile that performs parsing logic.
-func ParseEmail(filepath string) (map[string]string, error) {
+func ParseEmail(filepath string) (map[string][]string, error) {
+	return parseEmail(filepath, OpenAndCheckSheets)
+}
+
+// ParseEmailCSV parses emails from the CSV file (see openCSV), laid out as the emails sheet:
+// full names in the first column and emails in the next ones.
+func ParseEmailCSV(filepath string) (map[string][]string, error) {
+	return parseEmail(filepath, openCSV)
+}
+
+func parseEmail(filepath string, open workbookOpener) (map[string][]string, error) {
 	sheet := EmailsSheet // this is current settings sheet that must be in the spreadsheet
-	ss, err := OpenAndCheckSheets(filepath, sheet)
+	ss, err := open(filepath, sheet)
 
 	// Check if the file is valid and open it
 	if err != nil {
@@ -313,23 +438,3 @@ func ParseEmail(filepath string) (map[string]string, error) {
 
 	return ParseEmailFromFile(ss, sheet)
 }
-
-// normalizeAmount Appends "0" to ensure two decimal places, if there are only one decimal after the dot
-// Example: 1200.5 -> 1200.50; 490.99 -> 490.99
-func normalizeAmount(amount string) string {
-	amount = strings.Replace(amount, ",", ".", 1)
-
-	if strings.Contains(amount, ".") { // check if the cell is the decimal number
-		splitted := strings.Split(amount, ".")
-
-		tail := splitted[len(splitted)-1]
-
-		if len(tail) == 1 { // if there are only one decimal after the dot
-			amount = strings.TrimSpace(amount) + "0" // Append "0" to ensure two decimal places
-		}
-	} else {
-		amount = strings.TrimSpace(amount) + ".00"
-	}
-
-	return amount
-}
@@ -20,26 +20,26 @@ func TestParseSettingsFromFile(t *testing.T) {
 			name: "valid data",
 			data: map[string]string{
 				"Наименование организации": "ООО Ромашка",
-				"Расчетный счет":           "1234567890",
+				"Расчетный счет":           "40702810938000012345",
 				"Наименование банка":       "Сбер",
-				"БИК":                      "123456",
-				"Корреспондентский счет":   "0987654321",
-				"ИНН": "7701234567",
-				"КПП": "770101001",
-				"Дополнительные параметры ДШК": "extra",
+				"БИК":                      "044525225",
+				"Корреспондентский счет":   "30101810400000000225",
+				"ИНН": "7707083893",
+				"КПП": "770701001",
+				"Дополнительные параметры ДШК": "CATEGORY=4",
 				"Шаблон":     "pattern",
 				"Код услуги": "code",
 				"Каталог для выгрузки реестра": "catalog",
 			},
 			wantOrg: &model.Organization{
 				Name:        "ООО Ромашка",
-				PersonalAcc: "1234567890",
+				PersonalAcc: "40702810938000012345",
 				BankName:    "Сбер",
-				BIC:         "123456",
-				CorrespAcc:  "0987654321",
-				PayeeINN:    "7701234567",
-				KPP:         "770101001",
-				ExtraParams: "extra",
+				BIC:         "044525225",
+				CorrespAcc:  "30101810400000000225",
+				PayeeINN:    "7707083893",
+				KPP:         "770701001",
+				ExtraParams: "CATEGORY=4",
 			},
 			wantError: false,
 		},
@@ -53,7 +53,7 @@ func TestParseSettingsFromFile(t *testing.T) {
 				"Корреспондентский счет": "0987654321",
 				"ИНН": "7701234567",
 				"КПП": "770101001",
-				"Дополнительные параметры ДШК": "extra",
+				"Дополнительные параметры ДШК": "CATEGORY=4",
 			},
 			wantOrg:   nil,
 			wantError: true,
@@ -91,6 +91,42 @@ func TestParseSettingsFromFile(t *testing.T) {
 	}
 }
 
+func TestParseSettingsFromFile_InvalidRequisites(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := SettingsSheet
+	index, _ := f.NewSheet(sheet)
+	f.SetActiveSheet(index)
+
+	data := [][]string{
+		{ParamName, "ООО Ромашка"},
+		{ParamPersonalAcc, "40702810938000012354"}, // две последние цифры переставлены
+		{ParamBankName, "Сбер"},
+		{ParamBIC, "044525225"},
+		{ParamCorrespAcc, "3010181040000000022"}, // 19 цифр
+		{ParamINN, "7707083894"},
+		{ParamKPP, "77070100"},
+		{ParamExtraParams, "CATEGORY=4|Encoding=9"}, // неизвестная кодировка
+	}
+	for i, row := range data {
+		cell, _ := excelize.CoordinatesToCellName(1, SettingsRowStart+i)
+		_ = f.SetSheetRow(sheet, cell, &row)
+	}
+
+	org, err := ParseSettingsFromFile(f, sheet)
+	require.Error(t, err)
+	require.Nil(t, org)
+
+	var ip *InvalidParamsError
+	require.ErrorAs(t, err, &ip)
+	require.Equal(t, []ParamProblem{
+		{Param: ParamPersonalAcc, Value: "40702810938000012354", Kind: ProblemChecksum},
+		{Param: ParamCorrespAcc, Value: "3010181040000000022", Kind: ProblemFormat},
+		{Param: ParamINN, Value: "7707083894", Kind: ProblemChecksum},
+		{Param: ParamKPP, Value: "77070100", Kind: ProblemFormat},
+		{Param: ParamExtraParams, Value: "CATEGORY=4|Encoding=9", Kind: ProblemFormat},
+	}, ip.Problems)
+}
+
 func TestParsePayersFromFile(t *testing.T) {
 	f := excelize.NewFile()
 	sheet := PayersSheet
@@ -98,13 +134,13 @@ func TestParsePayersFromFile(t *testing.T) {
 	f.SetActiveSheet(index)
 
 	// Старт с 7-й строки
-	_ = f.SetCellValue(sheet, "A7", "12345")       // PersAcc
-	_ = f.SetCellValue(sheet, "B7", "Иванов И.И.") // CHILDFIO
-	_ = f.SetCellValue(sheet, "C7", "Назначение")  // Purpose
-	_ = f.SetCellValue(sheet, "D7", "123")         // CBC
-	_ = f.SetCellValue(sheet, "E7", "456")         // OKTMO
-	_ = f.SetCellValue(sheet, "H7", "100.5")       // Sum (col H = index 7)
-	_ = f.SetCellValue(sheet, "A8", "")            // пустая строка для проверки пропуска
+	_ = f.SetCellValue(sheet, "A7", "12345")                // PersAcc
+	_ = f.SetCellValue(sheet, "B7", "Иванов И.И.")          // CHILDFIO
+	_ = f.SetCellValue(sheet, "C7", "Назначение")           // Purpose
+	_ = f.SetCellValue(sheet, "D7", "82100000000000000123") // CBC
+	_ = f.SetCellValue(sheet, "E7", "92701000")             // OKTMO
+	_ = f.SetCellValue(sheet, "H7", "100.5")                // Sum (col H = index 7)
+	_ = f.SetCellValue(sheet, "A8", "")                     // пустая строка для проверки пропуска
 
 	payers, err := ParsePayersFromFile(f, sheet)
 	require.NoError(t, err)
@@ -113,12 +149,36 @@ func TestParsePayersFromFile(t *testing.T) {
 		PersAcc:  "12345",
 		CHILDFIO: "Иванов И.И.",
 		Purpose:  "Назначение",
-		CBC:      "123",
-		OKTMO:    "456",
-		Sum:      "100.50", // проверка на нормализацию десятичной
+		CBC:      "82100000000000000123",
+		OKTMO:    "92701000",
+		Sum:      model.NewMoney(100, 50), // проверка на нормализацию десятичной
 	}, payers[0])
 }
 
+func TestParsePayersFromFile_FormattedSum(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := PayersSheet
+	index, _ := f.NewSheet(sheet)
+	f.SetActiveSheet(index)
+
+	// numeric sums with thousands separators, shown as "1,200" and "1,200.50"
+	thousands, err := f.NewStyle(&excelize.Style{NumFmt: 3}) // #,##0
+	require.NoError(t, err)
+	kopeks, err := f.NewStyle(&excelize.Style{NumFmt: 4}) // #,##0.00
+	require.NoError(t, err)
+
+	_ = f.SetSheetRow(sheet, "A7", &[]any{"12345", "Иванов И.И.", "Питание", "82100000000000000123", "92701000", "", "", 1200})
+	_ = f.SetSheetRow(sheet, "A8", &[]any{"54321", "Петров П.П.", "Питание", "82100000000000000321", "92701000", "", "", 1200.5})
+	require.NoError(t, f.SetCellStyle(sheet, "H7", "H7", thousands))
+	require.NoError(t, f.SetCellStyle(sheet, "H8", "H8", kopeks))
+
+	payers, err := ParsePayersFromFile(f, sheet)
+	require.NoError(t, err)
+	require.Len(t, payers, 2)
+	require.Equal(t, model.NewMoney(1200, 0), payers[0].Sum)
+	require.Equal(t, model.NewMoney(1200, 50), payers[1].Sum)
+}
+
 func TestParseEmailFromFile(t *testing.T) {
 	f := excelize.NewFile()
 	sheet := EmailsSheet
@@ -126,11 +186,133 @@ func TestParseEmailFromFile(t *testing.T) {
 	f.SetActiveSheet(index)
 
 	// начинаем с 2-й строки
-	f.SetCellValue(sheet, "A2", "Иванов И.И.")
-	f.SetCellValue(sheet, "B2", "ivanov@example.com")
+	_ = f.SetSheetRow(sheet, "A2", &[]string{"Иванов И.И.", "ivanov@example.com"})
+	// несколько почт в ячейке через запятую и точку с запятой, и в дополнительных колонках
+	_ = f.SetSheetRow(sheet, "A3", &[]string{"Петров П.П.", "mama@example.com, papa@example.com;", "", "PAPA@example.com", "babushka@example.com"})
+	_ = f.SetSheetRow(sheet, "A5", &[]string{"Сидоров С.С.", "mama@example.com\nsidorov@example.com"})
 
 	emails, err := ParseEmailFromFile(f, sheet)
 	require.NoError(t, err)
-	require.Len(t, emails, 1)
-	require.Equal(t, "ivanov@example.com", emails["иванов и.и."])
+	require.Equal(t, map[string][]string{
+		"иванов и.и.":  {"ivanov@example.com"},
+		"петров п.п.":  {"mama@example.com", "papa@example.com", "babushka@example.com"},
+		"сидоров с.с.": {"mama@example.com", "sidorov@example.com"},
+	}, emails)
+}
+
+func TestParseEmailFromFile_Validation(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := EmailsSheet
+	index, _ := f.NewSheet(sheet)
+	f.SetActiveSheet(index)
+
+	_ = f.SetSheetRow(sheet, "A2", &[]string{"Иванов И.И.", "ivanov@example.com"})
+	_ = f.SetSheetRow(sheet, "A3", &[]string{"Петров П.П.", "petrov@example.com; petrov.example.com", "Петров <p@example.com>"})
+	_ = f.SetSheetRow(sheet, "A4", &[]string{" иванов и.и.", "ivanova@example.com"})
+
+	emails, err := ParseEmailFromFile(f, sheet)
+	require.Nil(t, emails)
+
+	var ir *InvalidRowsError
+	require.ErrorAs(t, err, &ir)
+	require.Equal(t, sheet, ir.Sheet)
+	require.Equal(t, []RowProblem{
+		{Row: 3, Column: EmailsColumnEmail, Value: "petrov.example.com", Kind: ProblemFormat},
+		{Row: 3, Column: EmailsColumnEmail, Value: "Петров <p@example.com>", Kind: ProblemFormat},
+		{Row: 4, Column: EmailsColumnFIO, Value: "иванов и.и.", Kind: ProblemDuplicate, DuplicateOf: 2},
+	}, ir.Problems)
+}
+
+func TestIsValidEmail(t *testing.T) {
+	valid := []string{"ivanov@example.com", "i.ivanov+school@mail.example.ru", "user@localhost", "ivanov_i-i@sub.example.com"}
+	for _, email := range valid {
+		require.True(t, isValidEmail(email), email)
+	}
+
+	invalid := []string{"", "ivanov", "ivanov@", "@example.com", "ivanov@@example.com", "iva nov@example.com",
+		"Иванов <ivanov@example.com>", "<ivanov@example.com>", "ivanov@example.com (папа)"}
+	for _, email := range invalid {
+		require.False(t, isValidEmail(email), email)
+	}
+}
+
+func TestParsePayersFromFile_HeaderMapping(t *testing.T) {
+	t.Run("reordered columns with aliases", func(t *testing.T) {
+		f := excelize.NewFile()
+		sheet := PayersSheet
+		index, _ := f.NewSheet(sheet)
+		f.SetActiveSheet(index)
+
+		// заголовок на 3-й строке, колонки в другом порядке, часть названий - синонимы
+		_ = f.SetSheetRow(sheet, "A3", &[]string{"Сумма к оплате", "КБК", "ЛС", "Код ОКТМО", "Примечание", "ФИО ребёнка", "Назначение платежа"})
+		_ = f.SetSheetRow(sheet, "A4", &[]string{"1200,5", "82100000000000000123", "12345", "92701000", "-", "Иванов И.И.", "Питание"})
+		_ = f.SetSheetRow(sheet, "A6", &[]string{"300", "82100000000000000321", "54321", "92701000001", "", "Петров П.П.", "Питание"})
+
+		payers, err := ParsePayersFromFile(f, sheet)
+		require.NoError(t, err)
+		require.Equal(t, []model.Payer{
+			{PersAcc: "12345", CHILDFIO: "Иванов И.И.", Purpose: "Питание", CBC: "82100000000000000123", OKTMO: "92701000", Sum: model.NewMoney(1200, 50)},
+			{PersAcc: "54321", CHILDFIO: "Петров П.П.", Purpose: "Питание", CBC: "82100000000000000321", OKTMO: "92701000001", Sum: model.NewMoney(300, 0)},
+		}, payers)
+	})
+
+	t.Run("missing required columns", func(t *testing.T) {
+		f := excelize.NewFile()
+		sheet := PayersSheet
+		index, _ := f.NewSheet(sheet)
+		f.SetActiveSheet(index)
+
+		_ = f.SetSheetRow(sheet, "A6", &[]string{"Лицевой счет", "ОКТМО", "ФИО", "Сумма"})
+		_ = f.SetSheetRow(sheet, "A7", &[]string{"12345", "456", "", "100"})
+
+		payers, err := ParsePayersFromFile(f, sheet)
+		require.Error(t, err)
+		require.Nil(t, payers)
+
+		var mc *MissingColumnsError
+		require.ErrorAs(t, err, &mc)
+		require.Equal(t, []string{HeaderChildFIO, HeaderPurpose, HeaderCBC}, mc.Missing)
+	})
+
+	t.Run("legacy layout with short row", func(t *testing.T) {
+		f := excelize.NewFile()
+		sheet := PayersSheet
+		index, _ := f.NewSheet(sheet)
+		f.SetActiveSheet(index)
+
+		_ = f.SetSheetRow(sheet, "A7", &[]string{"12345", "Иванов И.И.", "Питание", "123", "456"})
+
+		_, err := ParsePayersFromFile(f, sheet)
+		var ms *MissingPayersSheetColumns
+		require.ErrorAs(t, err, &ms)
+	})
+}
+
+func TestParsePayersFromFile_Validation(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := PayersSheet
+	index, _ := f.NewSheet(sheet)
+	f.SetActiveSheet(index)
+
+	_ = f.SetSheetRow(sheet, "A6", &[]string{"Лицевой счет", "ФИО обучающегося", "Назначение", "КБК", "ОКТМО", "Сумма"})
+	_ = f.SetSheetRow(sheet, "A7", &[]string{"1", "Иванов И.И.", "Питание", "82100000000000000123", "92701000", "100"})
+	_ = f.SetSheetRow(sheet, "A8", &[]string{"", "Петров П.П.", "Питание", "821000000000000001", "92701000", "100"})
+	_ = f.SetSheetRow(sheet, "A9", &[]string{"1", "Сидоров С.С.", "Питание", "82100000000000000123", "9270100", "-5"})
+	_ = f.SetSheetRow(sheet, "A10", &[]string{"2", "Козлов К.К.", "Питание", "8210000000000000012A", "92701000", "сто"})
+
+	payers, err := ParsePayersFromFile(f, sheet)
+	require.Error(t, err)
+	require.Nil(t, payers)
+
+	var ir *InvalidRowsError
+	require.ErrorAs(t, err, &ir)
+	require.Equal(t, []RowProblem{
+		{Row: 8, Column: HeaderPersAcc, Value: "", Kind: ProblemEmpty},
+		{Row: 8, Column: HeaderCBC, Value: "821000000000000001", Kind: ProblemFormat},
+		{Row: 9, Column: HeaderPersAcc, Value: "1", Kind: ProblemDuplicate, DuplicateOf: 7},
+		{Row: 9, Column: HeaderOKTMO, Value: "9270100", Kind: ProblemFormat},
+		{Row: 9, Column: HeaderSum, Value: "-5", Kind: ProblemNotPositive},
+		{Row: 10, Column: HeaderCBC, Value: "8210000000000000012A", Kind: ProblemFormat},
+		{Row: 10, Column: HeaderSum, Value: "сто", Kind: ProblemNotPositive},
+	}, ir.Problems)
 }
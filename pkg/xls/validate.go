@@ -0,0 +1,247 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+	"li-acc/pkg/model"
+	"li-acc/pkg/qr"
+	"li-acc/pkg/requisites"
+	"net/mail"
+	"strings"
+)
+
+// Lengths of the payers sheet codes
+const (
+	CBCLength = 20 // КБК consists of exactly 20 digits
+
+	OKTMOShortLength = 8  // ОКТМО of the municipality
+	OKTMOLongLength  = 11 // ОКТМО of the settlement
+)
+
+// ProblemKind is the category of the invalid cell value
+type ProblemKind int
+
+const (
+	// ProblemEmpty means the required value is not filled
+	ProblemEmpty ProblemKind = iota
+	// ProblemFormat means the value does not match the expected format (e.g. wrong number of digits)
+	ProblemFormat
+	// ProblemNotPositive means the amount is not a number or is not greater than zero
+	ProblemNotPositive
+	// ProblemDuplicate means the value must be unique on the sheet, but it was already met in the row RowProblem.DuplicateOf
+	ProblemDuplicate
+	// ProblemChecksum means the value is well-formed, but its control digits are wrong (e.g. a typo in the account number)
+	ProblemChecksum
+)
+
+func (k ProblemKind) String() string {
+	switch k {
+	case ProblemEmpty:
+		return "empty value"
+	case ProblemFormat:
+		return "invalid format"
+	case ProblemNotPositive:
+		return "not a positive number"
+	case ProblemDuplicate:
+		return "duplicate value"
+	case ProblemChecksum:
+		return "checksum mismatch"
+	default:
+		return "unknown problem"
+	}
+}
+
+// RowProblem describes a single invalid value found on the sheet.
+type RowProblem struct {
+	Row         int         // number of the row on the sheet (1-based, as Excel shows it)
+	Column      string      // canonical header of the column containing invalid value
+	Value       string      // the value as it is written in the cell
+	Kind        ProblemKind // what is wrong with the value
+	DuplicateOf int         // for ProblemDuplicate: number of the row where the value first appeared
+}
+
+func (p RowProblem) String() string {
+	msg := fmt.Sprintf("row %d, column %s (%q): %s", p.Row, p.Column, p.Value, p.Kind)
+	if p.Kind == ProblemDuplicate {
+		msg += fmt.Sprintf(" of row %d", p.DuplicateOf)
+	}
+	return msg
+}
+
+// payersValidator checks parsed payers row by row and accumulates all found problems.
+// It remembers personal accounts of already checked rows to find duplicates.
+type payersValidator struct {
+	problems []RowProblem
+	accounts map[string]int // personal account -> number of the row it first appeared in
+}
+
+func newPayersValidator() *payersValidator {
+	return &payersValidator{accounts: make(map[string]int)}
+}
+
+func (v *payersValidator) add(row int, column, value string, kind ProblemKind) {
+	v.problems = append(v.problems, RowProblem{Row: row, Column: column, Value: value, Kind: kind})
+}
+
+// check validates the payer parsed from the row number `row`. rawAmount is the amount as written in the cell,
+// it is shown to the user if the parsed amount is not positive.
+func (v *payersValidator) check(row int, payer model.Payer, rawAmount string) {
+	if payer.PersAcc == "" {
+		v.add(row, HeaderPersAcc, payer.PersAcc, ProblemEmpty)
+	} else if first, ok := v.accounts[payer.PersAcc]; ok {
+		v.problems = append(v.problems, RowProblem{
+			Row: row, Column: HeaderPersAcc, Value: payer.PersAcc, Kind: ProblemDuplicate, DuplicateOf: first,
+		})
+	} else {
+		v.accounts[payer.PersAcc] = row
+	}
+
+	if !isDigits(payer.CBC, CBCLength) {
+		v.add(row, HeaderCBC, payer.CBC, ProblemFormat)
+	}
+
+	if !isDigits(payer.OKTMO, OKTMOShortLength, OKTMOLongLength) {
+		v.add(row, HeaderOKTMO, payer.OKTMO, ProblemFormat)
+	}
+
+	if !payer.Sum.IsPositive() {
+		v.add(row, HeaderSum, rawAmount, ProblemNotPositive)
+	}
+}
+
+// Column names of the emails sheet, used in RowProblem.Column
+const (
+	EmailsColumnFIO   = "ФИО"
+	EmailsColumnEmail = "Почта"
+)
+
+// EmailSeparators are the characters separating several emails written in one cell of the emails sheet
+const EmailSeparators = ",;\n"
+
+// splitEmails returns all emails written in the cells, in order of appearance. Each cell may contain several emails
+// separated with EmailSeparators. Empty values are skipped, repeated emails (case-insensitive) are kept once.
+func splitEmails(cells []string) []string {
+	var emails []string
+	seen := make(map[string]bool)
+	for _, cell := range cells {
+		for _, email := range strings.FieldsFunc(cell, func(r rune) bool { return strings.ContainsRune(EmailSeparators, r) }) {
+			email = strings.TrimSpace(email)
+			if email == "" || seen[strings.ToLower(email)] {
+				continue
+			}
+			seen[strings.ToLower(email)] = true
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
+
+// isValidEmail reports whether s is a single address in the RFC 5322 addr-spec form (e.g. "ivanov@example.com").
+// Display names and angle brackets ("Иванов <ivanov@example.com>") are not allowed, the cell must contain the bare address.
+func isValidEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Name == "" && addr.Address == s
+}
+
+// emailsValidator checks rows of the emails sheet and accumulates all found problems.
+// It remembers full names of already checked rows to find duplicates.
+type emailsValidator struct {
+	problems []RowProblem
+	names    map[string]int // lower-cased full name -> number of the row it first appeared in
+}
+
+func newEmailsValidator() *emailsValidator {
+	return &emailsValidator{names: make(map[string]int)}
+}
+
+// validate checks the full name and emails of the row number `row` and reports whether the row is valid.
+func (v *emailsValidator) validate(row int, fio string, emails []string) bool {
+	valid := true
+
+	key := strings.ToLower(fio)
+	if first, ok := v.names[key]; ok {
+		v.problems = append(v.problems, RowProblem{
+			Row: row, Column: EmailsColumnFIO, Value: fio, Kind: ProblemDuplicate, DuplicateOf: first,
+		})
+		valid = false
+	} else {
+		v.names[key] = row
+	}
+
+	for _, email := range emails {
+		if !isValidEmail(email) {
+			v.problems = append(v.problems, RowProblem{Row: row, Column: EmailsColumnEmail, Value: email, Kind: ProblemFormat})
+			valid = false
+		}
+	}
+	return valid
+}
+
+// ParamProblem describes an invalid value of the settings sheet parameter.
+type ParamProblem struct {
+	Param string      // name of the parameter as written on the sheet
+	Value string      // the parameter value
+	Kind  ProblemKind // what is wrong with the value: ProblemFormat or ProblemChecksum
+}
+
+func (p ParamProblem) String() string {
+	return fmt.Sprintf("%s (%q): %s", p.Param, p.Value, p.Kind)
+}
+
+// validateOrganization checks bank and tax requisites of the organization: BIC, settlement and correspondent accounts
+// (including the Central Bank control key against BIC), INN and KPP, and the QR payload options. Returns all found problems.
+func validateOrganization(org model.Organization) []ParamProblem {
+	var problems []ParamProblem
+
+	add := func(param, value string, err error) {
+		if err == nil {
+			return
+		}
+		kind := ProblemFormat
+		if errors.Is(err, requisites.ErrChecksum) {
+			kind = ProblemChecksum
+		}
+		problems = append(problems, ParamProblem{Param: param, Value: value, Kind: kind})
+	}
+
+	bicErr := requisites.ValidateBIC(org.BIC)
+	add(ParamBIC, org.BIC, bicErr)
+
+	// control keys of the accounts can be checked only against valid BIC, otherwise check just the format
+	if bicErr == nil {
+		add(ParamPersonalAcc, org.PersonalAcc, requisites.ValidateSettlementAccount(org.PersonalAcc, org.BIC))
+		add(ParamCorrespAcc, org.CorrespAcc, requisites.ValidateCorrespondentAccount(org.CorrespAcc, org.BIC))
+	} else {
+		if !isDigits(org.PersonalAcc, requisites.AccountLength) {
+			add(ParamPersonalAcc, org.PersonalAcc, requisites.ErrFormat)
+		}
+		if !isDigits(org.CorrespAcc, requisites.AccountLength) {
+			add(ParamCorrespAcc, org.CorrespAcc, requisites.ErrFormat)
+		}
+	}
+
+	add(ParamINN, org.PayeeINN, requisites.ValidateINN(org.PayeeINN))
+	add(ParamKPP, org.KPP, requisites.ValidateKPP(org.KPP))
+
+	// the options of the QR payload are checked now, not when the receipts are generated
+	_, err := qr.ParseOptions(org.ExtraParams)
+	add(ParamExtraParams, org.ExtraParams, err)
+
+	return problems
+}
+
+// isDigits reports whether s consists only of digits and its length is one of `lengths`.
+func isDigits(s string, lengths ...int) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	for _, l := range lengths {
+		if len(s) == l {
+			return true
+		}
+	}
+	return false
+}
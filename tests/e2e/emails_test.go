@@ -42,6 +42,37 @@ func TestUploadEmailsFile(t *testing.T) {
 				assert.Contains(t, errorMsg, "рядах", "Should mention row numbers")
 			},
 		},
+		{
+			name:               "Invalid email addresses in all rows",
+			emailsFile:         "testdata/emails/all_invalid_smtp.xlsx",
+			expectedHTTPStatus: http.StatusBadRequest,
+			checkResponse: func(t *testing.T, body map[string]interface{}) {
+				errorMsg := body["error"].(string)
+				assert.Contains(t, errorMsg, "найдены ошибки (2)")
+				assert.Contains(t, errorMsg, "строка 2, Почта «labubu»: неверный адрес электронной почты")
+				assert.Contains(t, errorMsg, "строка 3, Почта «kzn_gangster!@.»")
+			},
+		},
+		{
+			name:               "Invalid email address in some rows",
+			emailsFile:         "testdata/emails/some_invalid_smtp.xlsx",
+			expectedHTTPStatus: http.StatusBadRequest,
+			checkResponse: func(t *testing.T, body map[string]interface{}) {
+				errorMsg := body["error"].(string)
+				assert.Contains(t, errorMsg, "найдены ошибки (1)")
+				assert.Contains(t, errorMsg, "строка 3, Почта «kzn_gangster!@.»")
+				assert.NotContains(t, errorMsg, "labubu@gmail.com")
+			},
+		},
+		{
+			name:               "Invalid email address among valid ones",
+			emailsFile:         "testdata/emails/mixed_partial_and_invalid.xlsx",
+			expectedHTTPStatus: http.StatusBadRequest,
+			checkResponse: func(t *testing.T, body map[string]interface{}) {
+				errorMsg := body["error"].(string)
+				assert.Contains(t, errorMsg, "строка 4, Почта «пфпф»")
+			},
+		},
 		{
 			name:               "Emails sheet is not present",
 			emailsFile:         "testdata/emails/no_emails_sheet.xlsx",
@@ -18,6 +18,7 @@ import (
 )
 
 const EndpointUploadPayers = "/api/upload-payers"
+const EndpointPreviewPayers = "/api/preview-payers"
 const EndpointUploadEmails = "/api/settings/upload-emails"
 
 // uploadEmailsFileRaw uploads emails file and returns raw HTTP response (for testing errors)
@@ -163,6 +164,34 @@ func uploadPayersFileRaw(t *testing.T, env *TestEnvironment, filePath string) *h
 	return resp
 }
 
+// previewPayersFile sends payers file to the dry run endpoint and returns raw HTTP response
+func previewPayersFile(t *testing.T, env *TestEnvironment, filePath string) *http.Response {
+	t.Helper()
+
+	file, err := os.Open(filePath)
+	require.NoError(t, err)
+	defer file.Close()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile("file", filepath.Base(filePath))
+	require.NoError(t, err)
+
+	_, err = io.Copy(part, file)
+	require.NoError(t, err)
+	writer.Close()
+
+	req, err := http.NewRequest("POST", env.AppURL+EndpointPreviewPayers, body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	return resp
+}
+
 // uploadPayersFileNoFile uploads without file attached
 func uploadPayersFileNoFile(t *testing.T, env *TestEnvironment) *http.Response {
 	t.Helper()
@@ -63,14 +63,33 @@ func TestUploadPayersFile(t *testing.T) {
 			},
 		},
 
+		{
+			name:               "Siblings with the same email - one message with both receipts",
+			emailsFile:         "testdata/emails/shared_email.xlsx",
+			payersFile:         "testdata/payers/valid_payers.xlsm",
+			expectedHTTPStatus: http.StatusOK,
+			expectedPartial:    false,
+			expectedEmailCount: 1,
+			expectedRecipients: []string{"ex1@example.com"},
+			checkResponse: func(t *testing.T, resp handler.PayersFileUploadResponse) {
+				assert.False(t, resp.PartialSuccess)
+				assert.Equal(t, 1, resp.SentAmount)
+				assert.Empty(t, resp.MissingPayers)
+			},
+			checkEmails: func(t *testing.T, emails []MailHogMessage, resp handler.PayersFileUploadResponse) {
+				require.Len(t, emails, 1)
+				assert.Equal(t, 2, countEmailAttachments(&emails[0]), "Email should have receipts of both payers")
+			},
+		},
+
 		// ========== PARTIAL SUCCESS - EMAIL SENDING ERRORS ==========
 		{
-			name:               "Some emails fail to send (invalid SMTP addresses)",
-			emailsFile:         "testdata/emails/some_invalid_smtp.xlsx",
+			name:               "Some emails fail to send (addresses rejected by SMTP server)",
+			emailsFile:         "testdata/emails/some_rejected_smtp.xlsx",
 			payersFile:         "testdata/payers/valid_payers.xlsm",
 			expectedHTTPStatus: http.StatusOK,
 			expectedPartial:    true,
-			expectedEmailCount: 1, // Only 1 valid email
+			expectedEmailCount: 1, // Only 1 accepted email
 			checkResponse: func(t *testing.T, resp handler.PayersFileUploadResponse) {
 				assert.True(t, resp.PartialSuccess, "Should be partial success")
 				assert.Equal(t, 1, resp.SentAmount, "Only 1 email should succeed")
@@ -83,7 +102,7 @@ func TestUploadPayersFile(t *testing.T) {
 				}
 			},
 			checkEmails: func(t *testing.T, emails []MailHogMessage, resp handler.PayersFileUploadResponse) {
-				// Only valid emails should be in MailHog
+				// Only accepted emails should be in MailHog
 				assert.Len(t, emails, 1, "Only 1 email should be successfully sent")
 
 				// Verify it has attachment
@@ -94,8 +113,8 @@ func TestUploadPayersFile(t *testing.T) {
 			},
 		},
 		{
-			name:               "All emails fail to send (all invalid SMTP)",
-			emailsFile:         "testdata/emails/all_invalid_smtp.xlsx",
+			name:               "All emails fail to send (all rejected by SMTP server)",
+			emailsFile:         "testdata/emails/all_rejected_smtp.xlsx",
 			payersFile:         "testdata/payers/valid_payers.xlsm",
 			expectedHTTPStatus: http.StatusOK,
 			expectedPartial:    true,
@@ -154,8 +173,8 @@ func TestUploadPayersFile(t *testing.T) {
 
 		// ========== MIXED ERRORS ==========
 		{
-			name:               "Mixed: some missing emails + some invalid SMTP",
-			emailsFile:         "testdata/emails/mixed_partial_and_invalid.xlsx",
+			name:               "Mixed: some missing emails + some rejected by SMTP server",
+			emailsFile:         "testdata/emails/mixed_partial_and_rejected.xlsx",
 			payersFile:         "testdata/payers/five_payers.xlsm",
 			expectedHTTPStatus: http.StatusOK,
 			expectedPartial:    true,
@@ -0,0 +1,48 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"encoding/json"
+	"li-acc/internal/handler"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewPayersFile(t *testing.T) {
+	env, cleanup := SetupTestEnvironment(t)
+	defer cleanup()
+
+	t.Run("Some payers have no matching emails", func(t *testing.T) {
+		cleanupDB(t, env)
+		clearMailHog(t, env)
+		setupEmailsCustom(t, env, "testdata/emails/partial_emails.xlsx")
+
+		resp := previewPayersFile(t, env, "testdata/payers/five_payers.xlsm")
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var preview handler.PayersFilePreviewResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&preview))
+
+		assert.NotEmpty(t, preview.Organization.Name)
+		require.Len(t, preview.Payers, 5)
+		assert.Len(t, preview.UnmatchedPayers, 2, "2 payers should have no emails")
+		for _, p := range preview.Payers {
+			assert.Contains(t, p.QrData, "ST00012|")
+			assert.Contains(t, p.QrData, "PersonalAcc="+preview.Organization.PersonalAcc)
+		}
+
+		// dry run sends nothing
+		assert.Empty(t, getMailHogEmails(t, env))
+	})
+
+	t.Run("Invalid file extension - .txt", func(t *testing.T) {
+		resp := previewPayersFile(t, env, "testdata/payers/invalid_extension.txt")
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -11,6 +11,7 @@ import (
 	migrator "li-acc/internal/repository/db"
 	"li-acc/internal/service"
 	"li-acc/pkg/logger"
+	"net"
 	"net/http"
 	"strconv"
 	"testing"
@@ -55,12 +56,12 @@ func SetupTestEnvironment(t *testing.T) (*TestEnvironment, func()) {
 	// Setup MailHog container
 	mailHogContainer, mailHogAPIURL, smtpPort := setupMailHogContainer(t, ctx)
 
-	// Override SMTP config to use MailHog
+	// Override SMTP config to use MailHog through the proxy rejecting RejectedDomain recipients
 	mailHogHost, _ := mailHogContainer.Host(ctx)
-	cfg.SMTP.Host = mailHogHost
-	cfg.SMTP.Port = smtpPort
+	cfg.SMTP.Host = "127.0.0.1"
+	cfg.SMTP.Port = startRejectingSMTP(t, net.JoinHostPort(mailHogHost, strconv.Itoa(smtpPort)))
 
-	t.Logf("MailHog SMTP: %s:%d", mailHogHost, smtpPort)
+	t.Logf("MailHog SMTP: %s:%d, proxy: %s:%d", mailHogHost, smtpPort, cfg.SMTP.Host, cfg.SMTP.Port)
 	t.Logf("MailHog Web UI: %s", mailHogAPIURL)
 
 	// Start application
@@ -211,7 +212,7 @@ func startTestApp(t *testing.T, cfg *config.Config) *App {
 		Password: cfg.SMTP.Password,
 		UseTLS:   false,
 	}
-	serviceManager, err := service.NewManager(dsn, cfg.ConvertAPI.PublicKey, smtp)
+	serviceManager, err := service.NewManager(dsn, nil, smtp) // receipt templates are drawn natively
 	if err != nil {
 		t.Fatal("failed to create service manager", zap.Error(err))
 	}
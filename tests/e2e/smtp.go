@@ -0,0 +1,85 @@
+package e2e
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// RejectedDomain is the domain of the addresses the test SMTP server rejects. The addresses are valid,
+// so the emails files with them are accepted, and only sending the receipts to them fails.
+const RejectedDomain = "rejected.test"
+
+// startRejectingSMTP starts the SMTP proxy to the MailHog server [target], since MailHog accepts any recipient.
+// The proxy answers RCPT TO of the RejectedDomain addresses with 550 itself and relays everything else.
+// Returns the port of the proxy on 127.0.0.1.
+func startRejectingSMTP(t *testing.T, target string) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Failed to start SMTP proxy")
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			client, err := listener.Accept()
+			if err != nil {
+				return // listener closed
+			}
+			go relaySMTP(t, client, target)
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// relaySMTP relays one SMTP session of the [client] to the [target] server
+func relaySMTP(t *testing.T, client net.Conn, target string) {
+	defer client.Close()
+
+	server, err := net.Dial("tcp", target)
+	if err != nil {
+		t.Logf("SMTP proxy failed to connect to %s: %v", target, err)
+		return
+	}
+	defer server.Close()
+
+	// the replies of the server are copied as they are. The client waits for the reply to every command,
+	// so the rejection written below never interleaves with them.
+	go io.Copy(client, server)
+
+	lines := bufio.NewReader(client)
+	data := false // the message is being sent, its lines are not commands
+	for {
+		line, err := lines.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case data:
+			data = command != "."
+		case command == "DATA":
+			data = true
+		case strings.HasPrefix(command, "RCPT TO:") && isRejectedRecipient(command):
+			if _, err := io.WriteString(client, "550 5.1.1 Recipient address rejected\r\n"); err != nil {
+				return
+			}
+			continue
+		}
+		if _, err := io.WriteString(server, line); err != nil {
+			return
+		}
+	}
+}
+
+// isRejectedRecipient reports whether the upper-cased RCPT TO [command] has the RejectedDomain address
+func isRejectedRecipient(command string) bool {
+	start, end := strings.Index(command, "<"), strings.Index(command, ">")
+	if start < 0 || end < start {
+		return false
+	}
+	return strings.HasSuffix(command[start+1:end], "@"+strings.ToUpper(RejectedDomain))
+}